@@ -0,0 +1,154 @@
+package mysql
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditEvent describes a statement sent by a Connection, for compliance
+// logging through AuditFunc.
+type AuditEvent struct {
+	ConnectionID uint32
+	User         string
+	Schema       string // the default database when the statement was sent
+	SQL          string // with literals replaced by ? under AuditRedact
+	Start        time.Time
+	End          time.Time // when the server answered, before any row is read
+	Result       *Result   // the OK packet of a statement without result set
+	Err          error
+}
+
+// AuditFunc receives an AuditEvent for every statement of the application:
+// Query (and so the database/sql driver), Pipeline and ImportCSV. It is
+// called synchronously by the connection, use an AuditQueue to keep slow
+// logging out of the way of the queries.
+type AuditFunc func(event AuditEvent)
+
+// audit reports a statement to AuditFunc.
+func (c *Connection) audit(query string, start time.Time, result *Result, err error) {
+	if c.param.AuditFunc == nil {
+		return
+	}
+
+	if c.param.AuditRedact == true {
+		query = redactSQL(query, c.NoBackslashEscapes())
+	}
+
+	c.param.AuditFunc(AuditEvent{
+		ConnectionID: c.ConnectionID,
+		User:         c.param.Username,
+		Schema:       c.database,
+		SQL:          query,
+		Start:        start,
+		End:          time.Now(),
+		Result:       result,
+		Err:          err,
+	})
+}
+
+// redactSQL replaces the string and numeric literals of query with ?,
+// leaving identifiers, keywords and comments as they are.
+func redactSQL(query string, noBackslashEscapes bool) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; {
+		case ch == '\'' || ch == '"':
+			// A doubled quote closes the literal and opens the next one,
+			// both are replaced.
+			for i++; i < len(query) && query[i] != ch; i++ {
+				if query[i] == '\\' && noBackslashEscapes == false {
+					i++
+				}
+			}
+
+			if i+1 >= len(query) || query[i+1] != ch {
+				buf.WriteByte('?')
+			}
+		case ch == '`':
+			start := i
+
+			for i++; i < len(query) && query[i] != '`'; i++ {
+			}
+
+			if i >= len(query) {
+				i = len(query) - 1
+			}
+
+			buf.WriteString(query[start : i+1])
+		case ch >= '0' && ch <= '9' && (i == 0 || isIdentifierByte(query[i-1]) == false):
+			// Numbers, including 1.5e3 and 0x1f.
+			for i+1 < len(query) && (isIdentifierByte(query[i+1]) == true || query[i+1] == '.') {
+				i++
+			}
+
+			buf.WriteByte('?')
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+
+	return buf.String()
+}
+
+// isIdentifierByte reports whether b may be part of an unquoted
+// identifier.
+func isIdentifierByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '$' || b >= 0x80
+}
+
+// AuditQueue hands audit events to a slow AuditFunc, e.g. one writing to a
+// remote log, from a single goroutine so the connections do not wait for
+// it. When the queue is full the events are dropped and counted rather
+// than blocking the queries.
+type AuditQueue struct {
+	events  chan AuditEvent
+	done    chan struct{}
+	dropped uint64
+	once    sync.Once
+}
+
+// NewAuditQueue returns a queue of up to size events delivered to fn. Use
+// its Audit method as the AuditFunc of the connections.
+func NewAuditQueue(fn AuditFunc, size int) *AuditQueue {
+	q := &AuditQueue{
+		events: make(chan AuditEvent, size),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(q.done)
+
+		for event := range q.events {
+			fn(event)
+		}
+	}()
+
+	return q
+}
+
+// Audit queues event, or drops it when the queue is full. It must not be
+// called after Close.
+func (q *AuditQueue) Audit(event AuditEvent) {
+	select {
+	case q.events <- event:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (q *AuditQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// Close waits for the queued events to be delivered.
+func (q *AuditQueue) Close() {
+	q.once.Do(func() {
+		close(q.events)
+	})
+
+	<-q.done
+}
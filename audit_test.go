@@ -0,0 +1,96 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestRedactSQL(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM t WHERE id = 42", "SELECT * FROM t WHERE id = ?"},
+		{"UPDATE t2 SET pass = 'it''s \\' secret', n = -1.5e3 WHERE x IN (0x1f, 7)", "UPDATE t2 SET pass = ?, n = -? WHERE x IN (?, ?)"},
+		{"SELECT `col 'quoted' 1` FROM t WHERE a = \"b\"", "SELECT `col 'quoted' 1` FROM t WHERE a = ?"},
+		{"INSERT INTO t VALUES ('unterminated", "INSERT INTO t VALUES (?"},
+	}
+
+	for _, test := range tests {
+		if got := redactSQL(test.query, false); got != test.expected {
+			t.Errorf("redactSQL(%q) = %q", test.query, got)
+		}
+	}
+
+	// Without backslash escapes the literal ends at the quote after it.
+	if got := redactSQL("SELECT 'a\\', b", true); got != "SELECT ?, b" {
+		t.Errorf("unexpected redaction %q", got)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var events []AuditEvent
+
+	c, serverConn := newPipeConnection(ConnectionParameter{
+		Username:    "app",
+		AuditRedact: true,
+		AuditFunc: func(event AuditEvent) {
+			events = append(events, event)
+		},
+	})
+	c.ConnectionID = 7
+	c.database = "shop"
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'shop.x' doesn't exist"...))
+	}()
+
+	if rows, err := c.Query("DELETE FROM t WHERE card = '4111111111111111'"); err != nil {
+		t.Fatal(err)
+	} else {
+		rows.Close()
+	}
+
+	if _, err := c.Query("SELECT * FROM x"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	if e := events[0]; e.ConnectionID != 7 || e.User != "app" || e.Schema != "shop" || e.SQL != "DELETE FROM t WHERE card = ?" ||
+		e.Result == nil || e.Result.AffectedRows() != 1 || e.Err != nil || e.End.Before(e.Start) == true {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	if e := events[1]; e.Result != nil || e.Err.(*MySQLError).Number != 1146 {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestAuditQueue(t *testing.T) {
+	block := make(chan struct{})
+	var delivered []string
+
+	q := NewAuditQueue(func(event AuditEvent) {
+		<-block
+		delivered = append(delivered, event.SQL)
+	}, 2)
+
+	// The first event is held by the blocked goroutine or the queue, the
+	// queue fills up with the next ones.
+	for _, query := range []string{"a", "b", "c", "d", "e"} {
+		q.Audit(AuditEvent{SQL: query})
+	}
+
+	close(block)
+	q.Close()
+
+	if q.Dropped() < 2 || uint64(len(delivered))+q.Dropped() != 5 || delivered[0] != "a" {
+		t.Errorf("delivered %q, dropped %d", delivered, q.Dropped())
+	}
+}
@@ -0,0 +1,174 @@
+package mysql
+
+import (
+	"context"
+	"time"
+)
+
+// ExecuteBatch executes the statement once for every set of arguments of
+// paramSets, sending all the COM_STMT_EXECUTE back to back in a single
+// write before reading the responses in order, so the batch costs one
+// round trip instead of one per set. The arguments are those of Exec.
+//
+// The returned Result adds up the affected rows and warnings of every
+// execution; its last insert id is the first one generated, like for a
+// multiple-row INSERT. Rows returned by the statement are discarded.
+//
+// The server runs every execution even after one of them failed: the
+// error of the first one is returned as a *BatchError together with the
+// Result of the others. Run the batch in a transaction to roll it back.
+//
+// When ctx is done the response being read is cancelled like with
+// QueryContext and the following ones are discarded. The server does not
+// read further commands while it is blocked sending a response, so keep
+// the batch small enough for the commands to fit into the socket buffers.
+func (s *Stmt) ExecuteBatch(ctx context.Context, paramSets [][]interface{}) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := s.c
+	payloads := make([][]byte, len(paramSets))
+
+	// Check every set before writing any of them: the writer flushes by
+	// itself when its buffer is full.
+	for i, args := range paramSets {
+		if len(args) != len(s.params) {
+			return nil, ErrArgumentCount
+		}
+
+		arg, err := s.executePayload(args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payloads[i] = arg
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	if s.closed == true {
+		return nil, ErrStmtClosed
+	}
+
+	for i, arg := range payloads {
+		payload, err := c.commandPayload(COM_STMT_EXECUTE, arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payloads[i] = payload
+	}
+
+	c.watchContext(ctx)
+	defer c.unwatchContext()
+
+	start := time.Now()
+
+	err := c.writeCommands(payloads)
+
+	if err != nil {
+		if ctx.Err() != nil && isTimeout(err) == true {
+			return nil, ctx.Err()
+		}
+
+		return nil, err
+	}
+
+	total := new(Result)
+	var batchErr error
+
+	// The time of every execution but the first one is counted from the
+	// end of the previous response.
+	responseStart := start
+
+	for i := range payloads {
+		// Every response starts right after its one packet command.
+		c.sequence = 1
+		c.commandBytes = c.readBytes
+
+		result, err := s.readBatchResponse()
+
+		if err == errInterrupted {
+			err = c.abort(&Rows{c: c, binary: true, stmt: s}, true)
+			s.discardBatchResponses(len(payloads) - i - 1)
+		} else if err != nil && ctx.Err() != nil && isTimeout(err) == true {
+			err = ctx.Err()
+		}
+
+		c.audit(s.query, start, result, err)
+		c.recordStatement(s.query, responseStart, 0, err)
+		responseStart = time.Now()
+
+		if _, ok := err.(*MySQLError); ok == true && c.broken == false {
+			if batchErr == nil {
+				batchErr = &BatchError{Index: i, Err: err}
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		total.add(result)
+	}
+
+	return total, batchErr
+}
+
+// readBatchResponse reads the response to one execution of a batch and
+// returns its OK packet, nil if the statement returned rows, which are
+// discarded.
+func (s *Stmt) readBatchResponse() (*Result, error) {
+	// The batch holds the mutex, the rows must not release it.
+	rows := &Rows{c: s.c, binary: true, stmt: s, released: true}
+
+	var result *Result
+
+	for {
+		err := rows.readResultSetHeader()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if rows.result != nil {
+			result = rows.result
+		}
+
+		for rows.Next() == true {
+		}
+
+		if rows.err != nil {
+			return nil, rows.err
+		}
+
+		if s.c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			return result, nil
+		}
+	}
+}
+
+// discardBatchResponses discards the n responses following a cancelled
+// one, each for at most the grace period, and leaves the connection broken
+// otherwise.
+func (s *Stmt) discardBatchResponses(n int) {
+	c := s.c
+
+	for i := 0; i < n && c.broken == false; i++ {
+		header := true
+		c.sequence = 1
+
+		err := c.drain(&Rows{c: c, binary: true, stmt: s, released: true}, &header, c.cancelGracePeriod())
+
+		if err != nil {
+			c.broken = true
+		}
+	}
+}
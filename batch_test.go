@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStmtExecuteBatch(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(7, 0, 1))
+		server.writePacket(2, columnPacket("?", MYSQL_TYPE_VAR_STRING, 0, 63))
+		server.writePacket(3, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		// Every command is read before the first response is sent.
+		for _, name := range []string{"a", "b", "c"} {
+			expected := []byte{COM_STMT_EXECUTE, 7, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, byte(MYSQL_TYPE_VAR_STRING), 0, 1, name[0]}
+
+			if payload := server.readCommand(); bytes.Equal(payload, expected) == false {
+				t.Errorf("unexpected execute\n got % x\nwant % x", payload, expected)
+			}
+		}
+
+		server.writePacket(1, okPacket(1, 5, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x26, 0x04, '#', '2', '3', '0', '0', '0'}, "Duplicate entry 'b' for key 'PRIMARY'"...))
+		server.writePacket(1, okPacket(2, 6, SERVER_STATUS_AUTOCOMMIT, 1, ""))
+	}()
+
+	stmt, err := c.Prepare("INSERT INTO t (name) VALUES (?)")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stmt.ExecuteBatch(context.Background(), [][]interface{}{{"a"}, {}}); err != ErrArgumentCount {
+		t.Errorf("argument count: got %v", err)
+	}
+
+	result, err := stmt.ExecuteBatch(context.Background(), [][]interface{}{{"a"}, {"b"}, {"c"}})
+
+	if result == nil || result.AffectedRows() != 3 || result.LastInsertId() != 5 || result.Warnings() != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	var batchErr *BatchError
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &batchErr) == false || batchErr.Index != 1 || errors.As(err, &mysqlErr) == false || mysqlErr.Number != 1062 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
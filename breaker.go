@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Breaker stops the connection attempts to a server after Threshold
+// consecutive failures to dial, handshake or authenticate: during Cooldown
+// they fail at once with ErrBreakerOpen rather than adding to the load of
+// a recovering server. Past Cooldown a single attempt is let through; its
+// success closes the breaker again, its failure opens it for another
+// Cooldown. Share one Breaker between all the connections to a server.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a connection attempt may start, and whether it is
+// the attempt probing an open breaker.
+func (b *Breaker) allow() (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.Threshold <= 0 || b.failures < b.Threshold {
+		return false, nil
+	}
+
+	if b.probing == true || time.Now().Before(b.openUntil) == true {
+		return false, ErrBreakerOpen
+	}
+
+	b.probing = true
+
+	return true, nil
+}
+
+// record counts the outcome of a connection attempt. Attempts cancelled by
+// their context say nothing about the server and are not counted.
+func (b *Breaker) record(ctx context.Context, probe bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if probe == true {
+		b.probing = false
+	}
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.Threshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	refused := errors.New("connection refused")
+	breaker := &Breaker{Threshold: 2, Cooldown: 50 * time.Millisecond}
+	dials := 0
+	up := false
+
+	param := ConnectionParameter{
+		Network: "tcp",
+		Breaker: breaker,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			dials++
+
+			if up == false {
+				return nil, refused
+			}
+
+			client, serverConn := net.Pipe()
+			go newFakeServer(t, serverConn).handshake()
+
+			return client, nil
+		},
+	}
+
+	for i, expected := range []error{refused, refused, ErrBreakerOpen, ErrBreakerOpen} {
+		if err := NewConnection(param).Open(); err != expected {
+			t.Errorf("attempt %d: unexpected error %v", i, err)
+		}
+	}
+
+	if dials != 2 {
+		t.Errorf("%d dials while open", dials)
+	}
+
+	// A cancelled attempt is not counted.
+	time.Sleep(breaker.Cooldown)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := NewConnection(param).OpenContext(ctx); err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The failed probe opens the breaker again, the next one closes it.
+	if err := NewConnection(param).Open(); err != refused {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if err := NewConnection(param).Open(); err != ErrBreakerOpen {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	time.Sleep(breaker.Cooldown)
+	up = true
+
+	c := NewConnection(param)
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+
+	if breaker.failures != 0 {
+		t.Errorf("breaker still counting %d failures", breaker.failures)
+	}
+}
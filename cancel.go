@@ -0,0 +1,259 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// DEFAULT_CANCEL_GRACE_PERIOD is the time given to each step of the
+// cancellation of a statement when CancelGracePeriod is zero.
+const DEFAULT_CANCEL_GRACE_PERIOD = time.Second
+
+// errInterrupted is returned by readPacket when waiting for the first
+// packet of a response was interrupted: nothing was consumed, so the
+// response can still be read.
+var errInterrupted = errors.New("Read interrupted")
+
+// QueryContext is like Query, but when ctx is done while the connection
+// waits for the server, the statement is cancelled in three steps:
+//
+//  1. The wait is interrupted and the response is read and discarded for
+//     CancelGracePeriod, which is enough when the statement was about to
+//     end or its rows are already on the way.
+//  2. Otherwise the statement is stopped with KILL QUERY through a
+//     connection from KillHelper, if set, and the response (usually
+//     ER_QUERY_INTERRUPTED) is read for another CancelGracePeriod.
+//  3. Otherwise the connection is left broken and must be closed.
+//
+// In the first two cases the connection stays usable. The error of the
+// statement, or of Rows.Err, is then the one of ctx.
+func (c *Connection) QueryContext(ctx context.Context, query string) (*Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.unlockOnPanic()
+
+	c.watchContext(ctx)
+
+	start := time.Now()
+
+	rows, err := c.query(query)
+
+	if err == errInterrupted {
+		err = c.abort(&Rows{c: c}, true)
+	} else if err != nil && ctx.Err() != nil && isTimeout(err) == true {
+		// Interrupted while writing the command or in the middle of a
+		// packet.
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		c.audit(query, start, nil, err)
+		c.recordStatement(query, start, 0, err)
+		c.unwatchContext()
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	c.audit(query, start, rows.result, nil)
+
+	rows.query = query
+	rows.start = start
+
+	if rows.done == true && c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+		rows.release()
+	}
+
+	return rows, nil
+}
+
+// ExecContext is like Exec, with the cancellation of QueryContext.
+func (c *Connection) ExecContext(ctx context.Context, query string) (*Result, error) {
+	rows, err := c.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := rows.Result()
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// contextWatch interrupts the reads and writes of the connection once the
+// context of the statement is done.
+type contextWatch struct {
+	ctx    context.Context
+	stop   chan struct{}
+	exited chan struct{}
+}
+
+// watchContext starts interrupting the connection once ctx is done, until
+// unwatchContext is called.
+func (c *Connection) watchContext(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+
+	w := &contextWatch{
+		ctx:    ctx,
+		stop:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+
+	conn := c.conn
+
+	go func() {
+		defer close(w.exited)
+
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-w.stop:
+		}
+	}()
+
+	c.watch = w
+}
+
+// unwatchContext stops watching the context of the statement.
+func (c *Connection) unwatchContext() {
+	if c.watch == nil {
+		return
+	}
+
+	close(c.watch.stop)
+	<-c.watch.exited
+
+	c.watch = nil
+	c.conn.SetDeadline(time.Time{})
+}
+
+// abort cancels the statement whose context is done, following the steps
+// described at QueryContext. r holds the state of the response, header is
+// set when the next packet is the first of a result set.
+func (c *Connection) abort(r *Rows, header bool) error {
+	err := c.watch.ctx.Err()
+
+	c.unwatchContext()
+
+	grace := c.cancelGracePeriod()
+
+	// The rows are drained on a copy which keeps the connection locked.
+	drained := *r
+	drained.released = true
+
+	if c.drain(&drained, &header, grace) == nil {
+		return err
+	}
+
+	if c.broken == false && c.param.KillHelper != nil && c.kill(grace) == nil && c.drain(&drained, &header, grace) == nil {
+		return err
+	}
+
+	c.broken = true
+
+	return err
+}
+
+// cancelGracePeriod returns the time given to each step of a
+// cancellation.
+func (c *Connection) cancelGracePeriod() time.Duration {
+	if c.param.CancelGracePeriod <= 0 {
+		return DEFAULT_CANCEL_GRACE_PERIOD
+	}
+
+	return c.param.CancelGracePeriod
+}
+
+// drain reads and discards the rest of the response for at most grace.
+func (c *Connection) drain(r *Rows, header *bool, grace time.Duration) error {
+	if c.broken == true {
+		return ErrInvalidConn
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(grace))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	c.draining = true
+	defer func() { c.draining = false }()
+
+	for {
+		if *header == true {
+			err := r.readResultSetHeader()
+
+			if err == errInterrupted {
+				return err
+			}
+
+			*header = false
+
+			// The statement ended with an error, e.g. the KILL.
+			if _, ok := err.(*MySQLError); ok {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		r.err = nil
+
+		for r.Next() == true {
+		}
+
+		if _, ok := r.err.(*MySQLError); ok {
+			return nil
+		}
+
+		if r.err != nil {
+			return r.err
+		}
+
+		if c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			return nil
+		}
+
+		*header = true
+	}
+}
+
+// kill sends KILL QUERY for the statement of the connection through a
+// connection from KillHelper.
+func (c *Connection) kill(grace time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	helper, err := c.param.KillHelper(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer helper.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		helper.conn.SetDeadline(deadline)
+	}
+
+	return c.KillOwnQuery(ctx, helper)
+}
+
+// isTimeout reports whether err is a network timeout, which is how the
+// interrupted reads and writes fail.
+func isTimeout(err error) bool {
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout() == true
+}
@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryContextDrain(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{CancelGracePeriod: time.Second})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		// The statement ends shortly after its context.
+		server.readCommand()
+		time.Sleep(50 * time.Millisecond)
+		server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.QueryContext(ctx, "UPDATE t SET x = 1"); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	rows, err := c.QueryContext(context.Background(), "DO 1")
+
+	if err != nil || c.broken == true {
+		t.Fatalf("connection not usable: %v", err)
+	}
+
+	rows.Close()
+}
+
+func TestQueryContextKill(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{CancelGracePeriod: 50 * time.Millisecond})
+	c.ConnectionID = 7
+	server := newFakeServer(t, serverConn)
+	killed := make(chan struct{})
+
+	c.param.KillHelper = func(ctx context.Context) (*Connection, error) {
+		helper, helperConn := newPipeConnection(ConnectionParameter{})
+		helperServer := newFakeServer(t, helperConn)
+
+		go func() {
+			if payload := helperServer.readCommand(); string(payload[1:]) != "KILL QUERY 7" {
+				t.Errorf("unexpected query %q", payload)
+			}
+
+			helperServer.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+			close(killed)
+		}()
+
+		return helper, nil
+	}
+
+	go func() {
+		server.readCommand()
+		server.writeResultSet(1, [][]byte{
+			columnPacket("n", MYSQL_TYPE_LONGLONG, 0, 63),
+		}, nil, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	// The rows arrive, the statement is killed while reading them.
+	rows, err := c.QueryContext(context.Background(), "SELECT 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows.Close()
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("n", MYSQL_TYPE_LONGLONG, 0, 63))
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, textRowPacket("1"))
+
+		<-killed
+		server.writePacket(5, append([]byte{ERR_PACKET, 0x25, 0x05, '#', '7', '0', '1', '0', '0'}, "Query execution was interrupted"...))
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	rows, err = c.QueryContext(ctx, "SELECT SLEEP(1000) UNION SELECT 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false {
+		t.Fatal(rows.Err())
+	}
+
+	cancel()
+
+	if rows.Next() == true || rows.Err() != context.Canceled || rows.Close() != context.Canceled {
+		t.Fatalf("unexpected error %v", rows.Err())
+	}
+
+	if c.broken == true {
+		t.Error("connection broken after KILL")
+	}
+}
+
+func TestQueryContextBroken(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{CancelGracePeriod: 20 * time.Millisecond})
+	server := newFakeServer(t, serverConn)
+
+	go server.readCommand()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Without KillHelper the connection is given up.
+	if _, err := c.QueryContext(ctx, "SELECT SLEEP(1000)"); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if c.broken == false {
+		t.Error("connection still usable")
+	}
+}
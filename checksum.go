@@ -0,0 +1,288 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	DEFAULT_CHECKSUM_CHUNK_ROWS = 1000
+)
+
+var (
+	ErrNoChecksumKey = errors.New("Table without a primary key to checksum by")
+)
+
+// ChecksumOptions selects how ChecksumTable splits and checksums a table.
+type ChecksumOptions struct {
+	Key       []string // optional, defaults to the primary key
+	Columns   []string // optional, defaults to all columns
+	ChunkRows int      // DEFAULT_CHECKSUM_CHUNK_ROWS if zero
+}
+
+// ChecksumChunk is the checksum of the rows of a table whose key is
+// greater than After and at most Upper, given as SQL literals.
+type ChecksumChunk struct {
+	After []string // nil from the start of the table
+	Upper []string // nil to the end of the table
+
+	Rows     int64
+	Checksum uint32 // BIT_XOR of the CRC32 of the rows
+}
+
+// ChecksumTable checksums table by chunks of consecutive keys like
+// pt-table-checksum, so that running ChecksumChunks with the chunks on a
+// replica and comparing them with DiffChecksums finds the ranges which
+// drifted. The last chunk is open ended and catches rows beyond the end of
+// the table on the replica.
+//
+// Unlike pt-table-checksum, which replicates its checksum statements, the
+// servers are read separately: rows changing or not yet replicated in the
+// meantime differ too. Compare a replica which caught up, e.g. with
+// ReplicaLag, and check the differing chunks again.
+func ChecksumTable(ctx context.Context, c *Connection, table string, opts ChecksumOptions) ([]ChecksumChunk, error) {
+	plan, err := c.planChecksum(ctx, table, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []ChecksumChunk
+	var after []string
+
+	for {
+		upper, err := c.checksumBoundary(ctx, plan, after)
+
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := ChecksumChunk{After: after, Upper: upper}
+
+		err = c.checksumChunk(ctx, plan, &chunk)
+
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, chunk)
+
+		if upper == nil {
+			return chunks, nil
+		}
+
+		after = upper
+	}
+}
+
+// ChecksumChunks checksums the chunks ChecksumTable returned for table on
+// another server, e.g. a replica, with the same options.
+func ChecksumChunks(ctx context.Context, c *Connection, table string, opts ChecksumOptions, chunks []ChecksumChunk) ([]ChecksumChunk, error) {
+	plan, err := c.planChecksum(ctx, table, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make([]ChecksumChunk, len(chunks))
+
+	for i := range chunks {
+		checksums[i] = ChecksumChunk{After: chunks[i].After, Upper: chunks[i].Upper}
+
+		err = c.checksumChunk(ctx, plan, &checksums[i])
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return checksums, nil
+}
+
+// DiffChecksums returns the chunks of source whose rows or checksum differ
+// in replica.
+func DiffChecksums(source []ChecksumChunk, replica []ChecksumChunk) []ChecksumChunk {
+	var diff []ChecksumChunk
+
+	for i := range source {
+		if i >= len(replica) || source[i].Rows != replica[i].Rows || source[i].Checksum != replica[i].Checksum {
+			diff = append(diff, source[i])
+		}
+	}
+
+	return diff
+}
+
+// checksumPlan is what the statements of a checksum need of the table.
+type checksumPlan struct {
+	table     string
+	key       []string
+	columns   []string
+	chunkRows int
+}
+
+// planChecksum completes opts with the primary key and the columns of
+// table.
+func (c *Connection) planChecksum(ctx context.Context, table string, opts ChecksumOptions) (*checksumPlan, error) {
+	plan := &checksumPlan{
+		table:     QuoteIdentifier(table),
+		key:       opts.Key,
+		columns:   opts.Columns,
+		chunkRows: opts.ChunkRows,
+	}
+
+	if plan.chunkRows <= 0 {
+		plan.chunkRows = DEFAULT_CHECKSUM_CHUNK_ROWS
+	}
+
+	if len(plan.key) == 0 {
+		rows, err := c.QueryContext(ctx, "SHOW KEYS FROM "+plan.table+" WHERE Key_name = 'PRIMARY'")
+
+		if err != nil {
+			return nil, err
+		}
+
+		column := -1
+
+		for i, col := range rows.Columns() {
+			if col.Name == "Column_name" {
+				column = i
+			}
+		}
+
+		// In Seq_in_index order.
+		for rows.Next() == true {
+			if column >= 0 && column < len(rows.Values()) {
+				plan.key = append(plan.key, string(rows.Values()[column]))
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(plan.key) == 0 {
+			return nil, ErrNoChecksumKey
+		}
+	}
+
+	if len(plan.columns) == 0 {
+		rows, err := c.QueryContext(ctx, "SELECT * FROM "+plan.table+" LIMIT 0")
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, col := range rows.Columns() {
+			plan.columns = append(plan.columns, col.Name)
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// checksumBoundary returns the key of the last row of the chunk after
+// after, nil when fewer than chunkRows rows are left.
+func (c *Connection) checksumBoundary(ctx context.Context, plan *checksumPlan, after []string) ([]string, error) {
+	key := quoteIdentifiers(plan.key)
+
+	query := "SELECT " + key + " FROM " + plan.table
+
+	if after != nil {
+		query += " WHERE (" + key + ") > (" + strings.Join(after, ", ") + ")"
+	}
+
+	query += " ORDER BY " + key + " LIMIT 1 OFFSET " + strconv.Itoa(plan.chunkRows-1)
+
+	rows, err := c.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var upper []string
+
+	for rows.Next() == true {
+		upper = make([]string, len(rows.Values()))
+
+		for i, value := range rows.Values() {
+			switch {
+			case value == nil:
+				upper[i] = "NULL"
+			case rows.Columns()[i].Type.IsNumeric() == true:
+				upper[i] = string(value)
+			default:
+				upper[i] = c.QuoteString(string(value))
+			}
+		}
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return upper, nil
+}
+
+// checksumChunk counts and checksums the rows of chunk. NULL is told apart
+// from the empty string by the ISNULL flags concatenated to the values.
+func (c *Connection) checksumChunk(ctx context.Context, plan *checksumPlan, chunk *ChecksumChunk) error {
+	columns := quoteIdentifiers(plan.columns)
+	key := quoteIdentifiers(plan.key)
+
+	nulls := make([]string, len(plan.columns))
+
+	for i, column := range plan.columns {
+		nulls[i] = "ISNULL(" + QuoteIdentifier(column) + ")"
+	}
+
+	query := "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', " + columns + ", CONCAT(" + strings.Join(nulls, ", ") + ")))), 0) FROM " + plan.table
+
+	var conditions []string
+
+	if chunk.After != nil {
+		conditions = append(conditions, "("+key+") > ("+strings.Join(chunk.After, ", ")+")")
+	}
+
+	if chunk.Upper != nil {
+		conditions = append(conditions, "("+key+") <= ("+strings.Join(chunk.Upper, ", ")+")")
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := c.QueryContext(ctx, query)
+
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() == true {
+		values := rows.Values()
+
+		if len(values) < 2 {
+			rows.Close()
+			return ErrMalformedPacket
+		}
+
+		chunk.Rows, _ = strconv.ParseInt(string(values[0]), 10, 64)
+
+		checksum, _ := strconv.ParseUint(string(values[1]), 10, 32)
+		chunk.Checksum = uint32(checksum)
+	}
+
+	return rows.Close()
+}
@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// checksumExchange is a statement of a checksum and the rows answering it.
+type checksumExchange struct {
+	query   string
+	columns []string
+	rows    [][]interface{}
+}
+
+func serveChecksum(t *testing.T, server *fakeServer, exchanges []checksumExchange) {
+	for _, exchange := range exchanges {
+		payload := server.readCommand()
+
+		if string(payload[1:]) != exchange.query {
+			t.Errorf("query:\n got %s\nwant %s", payload[1:], exchange.query)
+		}
+
+		var columns [][]byte
+		var rows [][]byte
+
+		for _, name := range exchange.columns {
+			columns = append(columns, columnPacket(name, MYSQL_TYPE_LONGLONG, 0, 63))
+		}
+
+		for _, row := range exchange.rows {
+			rows = append(rows, textRowPacket(row...))
+		}
+
+		server.writeResultSet(1, columns, rows, 0)
+	}
+}
+
+func TestChecksumTable(t *testing.T) {
+	sum := "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', `id`, `name`, CONCAT(ISNULL(`id`), ISNULL(`name`))))), 0) FROM `city`"
+
+	source, sourceConn := newPipeConnection(ConnectionParameter{})
+
+	go serveChecksum(t, newFakeServer(t, sourceConn), []checksumExchange{
+		{"SHOW KEYS FROM `city` WHERE Key_name = 'PRIMARY'", []string{"Table", "Non_unique", "Key_name", "Seq_in_index", "Column_name"}, [][]interface{}{{"city", "0", "PRIMARY", "1", "id"}}},
+		{"SELECT * FROM `city` LIMIT 0", []string{"id", "name"}, nil},
+		{"SELECT `id` FROM `city` ORDER BY `id` LIMIT 1 OFFSET 1", []string{"id"}, [][]interface{}{{"2"}}},
+		{sum + " WHERE (`id`) <= (2)", []string{"count", "crc"}, [][]interface{}{{"2", "3735928559"}}},
+		{"SELECT `id` FROM `city` WHERE (`id`) > (2) ORDER BY `id` LIMIT 1 OFFSET 1", []string{"id"}, nil},
+		{sum + " WHERE (`id`) > (2)", []string{"count", "crc"}, [][]interface{}{{"1", "42"}}},
+	})
+
+	opts := ChecksumOptions{ChunkRows: 2}
+
+	chunks, err := ChecksumTable(context.Background(), source, "city", opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ChecksumChunk{
+		{After: nil, Upper: []string{"2"}, Rows: 2, Checksum: 3735928559},
+		{After: []string{"2"}, Upper: nil, Rows: 1, Checksum: 42},
+	}
+
+	if reflect.DeepEqual(chunks, expected) == false {
+		t.Fatalf("chunks:\n got %+v\nwant %+v", chunks, expected)
+	}
+
+	replica, replicaConn := newPipeConnection(ConnectionParameter{})
+
+	go serveChecksum(t, newFakeServer(t, replicaConn), []checksumExchange{
+		{sum + " WHERE (`id`) <= (2)", []string{"count", "crc"}, [][]interface{}{{"2", "3735928559"}}},
+		{sum + " WHERE (`id`) > (2)", []string{"count", "crc"}, [][]interface{}{{"2", "7"}}},
+	})
+
+	opts.Key = []string{"id"}
+	opts.Columns = []string{"id", "name"}
+
+	checksums, err := ChecksumChunks(context.Background(), replica, "city", opts, chunks)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := DiffChecksums(chunks, checksums); len(diff) != 1 || reflect.DeepEqual(diff[0], chunks[1]) == false {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+}
+
+func TestChecksumTableWithoutKey(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+
+	go serveChecksum(t, newFakeServer(t, serverConn), []checksumExchange{
+		{"SHOW KEYS FROM `log` WHERE Key_name = 'PRIMARY'", []string{"Column_name"}, nil},
+	})
+
+	if _, err := ChecksumTable(context.Background(), c, "log", ChecksumOptions{}); err != ErrNoChecksumKey {
+		t.Errorf("unexpected error %v", err)
+	}
+}
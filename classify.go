@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// IsServerShutdown reports whether err says the server is shutting down.
+func IsServerShutdown(err error) bool {
+	var mysqlErr *MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == ER_SERVER_SHUTDOWN
+}
+
+// IsTransientNetwork reports whether err is a network failure, such as a
+// timeout, a reset or refused connection or a connection closed by the
+// server, rather than an error reported by the server.
+func IsTransientNetwork(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, ErrHandshakeTimeout) ||
+		errors.Is(err, ErrConnectTimeout) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var netErr net.Error
+
+	if errors.As(err, &netErr) && netErr.Timeout() == true {
+		return true
+	}
+
+	// Forwarded by proxies in ERR packets.
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case CR_SERVER_GONE_ERROR, CR_SERVER_LOST:
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRetryable reports whether running the statement (or the transaction it
+// was part of) again may succeed: deadlocks, lock wait timeouts, too many
+// connections, a server shutting down, killing the connection or turned
+// read-only by a failover, and transient network failures.
+//
+// A network failure may happen after the server executed the statement, so
+// only retry statements that are safe to run twice, or whole transactions
+// that were not committed.
+func IsRetryable(err error) bool {
+	if IsTransientNetwork(err) == true {
+		return true
+	}
+
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &mysqlErr) == false {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT, ER_CON_COUNT_ERROR,
+		ER_SERVER_SHUTDOWN, ER_CONNECTION_KILLED, ER_CLIENT_INTERACTION_TIMEOUT,
+		ER_OPTION_PREVENTS_STATEMENT:
+		return true
+	}
+
+	return false
+}
+
+// SQLStateClass returns the class of the SQLSTATE of a server error, its
+// first two characters (e.g. "23" for ER_DUP_ENTRY's 23000), or "" when
+// err is not a server error or carries no SQLSTATE. MySQL reports most
+// errors without a standard state as HY000.
+// Reference:
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func SQLStateClass(err error) string {
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &mysqlErr) == false || len(mysqlErr.SQLState) != 5 {
+		return ""
+	}
+
+	return mysqlErr.SQLState[:2]
+}
+
+// IsConnectionException reports whether err has an SQLSTATE of class 08,
+// e.g. ER_CON_COUNT_ERROR or a handshake failure.
+func IsConnectionException(err error) bool {
+	return SQLStateClass(err) == "08"
+}
+
+// IsDataException reports whether err has an SQLSTATE of class 22, e.g. a
+// value out of range or too long for its column.
+func IsDataException(err error) bool {
+	return SQLStateClass(err) == "22"
+}
+
+// IsIntegrityConstraintViolation reports whether err has an SQLSTATE of
+// class 23: a duplicate key, a foreign key or a NOT NULL violation.
+func IsIntegrityConstraintViolation(err error) bool {
+	return SQLStateClass(err) == "23"
+}
+
+// IsTransactionRollback reports whether err has an SQLSTATE of class 40:
+// the transaction was rolled back, e.g. by ER_LOCK_DEADLOCK.
+func IsTransactionRollback(err error) bool {
+	return SQLStateClass(err) == "40"
+}
+
+// IsSyntaxError reports whether err has an SQLSTATE of class 42, syntax
+// error or access rule violation. Besides ER_PARSE_ERROR the class holds
+// unknown tables and columns and missing privileges.
+func IsSyntaxError(err error) bool {
+	return SQLStateClass(err) == "42"
+}
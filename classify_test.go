@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: &timeoutError{}}
+
+	tests := []struct {
+		err              error
+		retryable        bool
+		transientNetwork bool
+		serverShutdown   bool
+	}{
+		{nil, false, false, false},
+		{io.EOF, true, true, false},
+		{fmt.Errorf("query: %w", syscall.ECONNRESET), true, true, false},
+		{timeout, true, true, false},
+		{&MySQLError{Number: ER_LOCK_DEADLOCK}, true, false, false},
+		{&MySQLError{Number: ER_SERVER_SHUTDOWN}, true, false, true},
+		{&MySQLError{Number: CR_SERVER_LOST}, true, true, false},
+		{&MySQLError{Number: 1062}, false, false, false},
+		{&MySQLError{Number: 1045}, false, false, false},
+		{errors.New("something else"), false, false, false},
+	}
+
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.retryable {
+			t.Errorf("IsRetryable(%v) = %v", test.err, got)
+		}
+
+		if got := IsTransientNetwork(test.err); got != test.transientNetwork {
+			t.Errorf("IsTransientNetwork(%v) = %v", test.err, got)
+		}
+
+		if got := IsServerShutdown(test.err); got != test.serverShutdown {
+			t.Errorf("IsServerShutdown(%v) = %v", test.err, got)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestSQLStateClass(t *testing.T) {
+	duplicate := fmt.Errorf("insert: %w", &MySQLError{Number: 1062, SQLState: "23000", Message: "Duplicate entry"})
+
+	if SQLStateClass(duplicate) != "23" || IsIntegrityConstraintViolation(duplicate) == false || IsSyntaxError(duplicate) == true {
+		t.Errorf("unexpected class of %v", duplicate)
+	}
+
+	if IsSyntaxError(&MySQLError{Number: ER_PARSE_ERROR, SQLState: "42000"}) == false {
+		t.Errorf("ER_PARSE_ERROR is a syntax error")
+	}
+
+	if IsConnectionException(&MySQLError{Number: ER_CON_COUNT_ERROR, SQLState: "08004"}) == false {
+		t.Errorf("ER_CON_COUNT_ERROR is a connection exception")
+	}
+
+	if IsTransactionRollback(&MySQLError{Number: ER_LOCK_DEADLOCK, SQLState: "40001"}) == false {
+		t.Errorf("ER_LOCK_DEADLOCK is a transaction rollback")
+	}
+
+	if IsDataException(&MySQLError{Number: 1406, SQLState: "22001"}) == false {
+		t.Errorf("ER_DATA_TOO_LONG is a data exception")
+	}
+
+	if SQLStateClass(&MySQLError{Number: 1045}) != "" || SQLStateClass(io.EOF) != "" {
+		t.Errorf("class without SQLSTATE")
+	}
+}
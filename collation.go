@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"strings"
+)
+
+// Collation is a collation of the server, as listed by SHOW COLLATION.
+type Collation struct {
+	ID      uint16
+	Name    string
+	Charset string
+	Default bool // the default collation of Charset, see DefaultCollationForCharset
+}
+
+// collations are the collations compiled into MySQL, MariaDB uses the same
+// ids. MySQL 8.0.30 names utf8 utf8mb3 and its collations utf8mb3_*, both
+// spellings are accepted by the lookups.
+// Reference:
+// https://dev.mysql.com/doc/refman/8.0/en/charset-mysql.html
+var collations = []Collation{
+	{1, "big5_chinese_ci", "big5", true},
+	{2, "latin2_czech_cs", "latin2", false},
+	{3, "dec8_swedish_ci", "dec8", true},
+	{4, "cp850_general_ci", "cp850", true},
+	{5, "latin1_german1_ci", "latin1", false},
+	{6, "hp8_english_ci", "hp8", true},
+	{7, "koi8r_general_ci", "koi8r", true},
+	{8, "latin1_swedish_ci", "latin1", true},
+	{9, "latin2_general_ci", "latin2", true},
+	{10, "swe7_swedish_ci", "swe7", true},
+	{11, "ascii_general_ci", "ascii", true},
+	{12, "ujis_japanese_ci", "ujis", true},
+	{13, "sjis_japanese_ci", "sjis", true},
+	{14, "cp1251_bulgarian_ci", "cp1251", false},
+	{15, "latin1_danish_ci", "latin1", false},
+	{16, "hebrew_general_ci", "hebrew", true},
+	{18, "tis620_thai_ci", "tis620", true},
+	{19, "euckr_korean_ci", "euckr", true},
+	{20, "latin7_estonian_cs", "latin7", false},
+	{21, "latin2_hungarian_ci", "latin2", false},
+	{22, "koi8u_general_ci", "koi8u", true},
+	{23, "cp1251_ukrainian_ci", "cp1251", false},
+	{24, "gb2312_chinese_ci", "gb2312", true},
+	{25, "greek_general_ci", "greek", true},
+	{26, "cp1250_general_ci", "cp1250", true},
+	{27, "latin2_croatian_ci", "latin2", false},
+	{28, "gbk_chinese_ci", "gbk", true},
+	{29, "cp1257_lithuanian_ci", "cp1257", false},
+	{30, "latin5_turkish_ci", "latin5", true},
+	{31, "latin1_german2_ci", "latin1", false},
+	{32, "armscii8_general_ci", "armscii8", true},
+	{33, "utf8_general_ci", "utf8", true},
+	{34, "cp1250_czech_cs", "cp1250", false},
+	{35, "ucs2_general_ci", "ucs2", true},
+	{36, "cp866_general_ci", "cp866", true},
+	{37, "keybcs2_general_ci", "keybcs2", true},
+	{38, "macce_general_ci", "macce", true},
+	{39, "macroman_general_ci", "macroman", true},
+	{40, "cp852_general_ci", "cp852", true},
+	{41, "latin7_general_ci", "latin7", true},
+	{42, "latin7_general_cs", "latin7", false},
+	{43, "macce_bin", "macce", false},
+	{44, "cp1250_croatian_ci", "cp1250", false},
+	{45, "utf8mb4_general_ci", "utf8mb4", true},
+	{46, "utf8mb4_bin", "utf8mb4", false},
+	{47, "latin1_bin", "latin1", false},
+	{48, "latin1_general_ci", "latin1", false},
+	{49, "latin1_general_cs", "latin1", false},
+	{50, "cp1251_bin", "cp1251", false},
+	{51, "cp1251_general_ci", "cp1251", true},
+	{52, "cp1251_general_cs", "cp1251", false},
+	{53, "macroman_bin", "macroman", false},
+	{54, "utf16_general_ci", "utf16", true},
+	{55, "utf16_bin", "utf16", false},
+	{56, "utf16le_general_ci", "utf16le", true},
+	{57, "cp1256_general_ci", "cp1256", true},
+	{58, "cp1257_bin", "cp1257", false},
+	{59, "cp1257_general_ci", "cp1257", true},
+	{60, "utf32_general_ci", "utf32", true},
+	{61, "utf32_bin", "utf32", false},
+	{62, "utf16le_bin", "utf16le", false},
+	{63, "binary", "binary", true},
+	{64, "armscii8_bin", "armscii8", false},
+	{65, "ascii_bin", "ascii", false},
+	{66, "cp1250_bin", "cp1250", false},
+	{67, "cp1256_bin", "cp1256", false},
+	{68, "cp866_bin", "cp866", false},
+	{69, "dec8_bin", "dec8", false},
+	{70, "greek_bin", "greek", false},
+	{71, "hebrew_bin", "hebrew", false},
+	{72, "hp8_bin", "hp8", false},
+	{73, "keybcs2_bin", "keybcs2", false},
+	{74, "koi8r_bin", "koi8r", false},
+	{75, "koi8u_bin", "koi8u", false},
+	{77, "latin2_bin", "latin2", false},
+	{78, "latin5_bin", "latin5", false},
+	{79, "latin7_bin", "latin7", false},
+	{80, "cp850_bin", "cp850", false},
+	{81, "cp852_bin", "cp852", false},
+	{82, "swe7_bin", "swe7", false},
+	{83, "utf8_bin", "utf8", false},
+	{84, "big5_bin", "big5", false},
+	{85, "euckr_bin", "euckr", false},
+	{86, "gb2312_bin", "gb2312", false},
+	{87, "gbk_bin", "gbk", false},
+	{88, "sjis_bin", "sjis", false},
+	{89, "tis620_bin", "tis620", false},
+	{90, "ucs2_bin", "ucs2", false},
+	{91, "ujis_bin", "ujis", false},
+	{92, "geostd8_general_ci", "geostd8", true},
+	{93, "geostd8_bin", "geostd8", false},
+	{94, "latin1_spanish_ci", "latin1", false},
+	{95, "cp932_japanese_ci", "cp932", true},
+	{96, "cp932_bin", "cp932", false},
+	{97, "eucjpms_japanese_ci", "eucjpms", true},
+	{98, "eucjpms_bin", "eucjpms", false},
+	{99, "cp1250_polish_ci", "cp1250", false},
+	{101, "utf16_unicode_ci", "utf16", false},
+	{128, "ucs2_unicode_ci", "ucs2", false},
+	{160, "utf32_unicode_ci", "utf32", false},
+	{192, "utf8_unicode_ci", "utf8", false},
+	{224, "utf8mb4_unicode_ci", "utf8mb4", false},
+	{246, "utf8mb4_unicode_520_ci", "utf8mb4", false},
+	{248, "gb18030_chinese_ci", "gb18030", true},
+	{249, "gb18030_bin", "gb18030", false},
+	{255, "utf8mb4_0900_ai_ci", "utf8mb4", false},
+	{278, "utf8mb4_0900_as_ci", "utf8mb4", false},
+	{305, "utf8mb4_0900_as_cs", "utf8mb4", false},
+	{309, "utf8mb4_0900_bin", "utf8mb4", false},
+}
+
+// CollationByID returns the collation with the given id, e.g. the
+// CharacterSet of a Column or the ServerDefaultCollation of a Connection.
+func CollationByID(id uint16) (Collation, bool) {
+	for _, collation := range collations {
+		if collation.ID == id {
+			return collation, true
+		}
+	}
+
+	return Collation{}, false
+}
+
+// CollationByName returns the collation with the given name, e.g.
+// "utf8mb4_unicode_ci". Names are case insensitive.
+func CollationByName(name string) (Collation, bool) {
+	name = strings.ToLower(name)
+
+	if strings.HasPrefix(name, "utf8mb3_") {
+		name = "utf8_" + name[len("utf8mb3_"):]
+	}
+
+	for _, collation := range collations {
+		if collation.Name == name {
+			return collation, true
+		}
+	}
+
+	return Collation{}, false
+}
+
+// DefaultCollationForCharset returns the collation a server of the given
+// version uses for charset when none is named: utf8mb4 defaults to
+// utf8mb4_0900_ai_ci since MySQL 8.0 and to utf8mb4_general_ci before and
+// on MariaDB.
+func DefaultCollationForCharset(charset string, serverVersion string) (Collation, bool) {
+	charset = strings.ToLower(charset)
+
+	if charset == "utf8mb3" {
+		charset = "utf8"
+	}
+
+	if charset == "utf8mb4" && strings.Contains(serverVersion, "MariaDB") == false && compareVersions(serverVersion, "8.0") >= 0 {
+		return CollationByName("utf8mb4_0900_ai_ci")
+	}
+
+	for _, collation := range collations {
+		if collation.Charset == charset && collation.Default == true {
+			return collation, true
+		}
+	}
+
+	return Collation{}, false
+}
@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestCollationLookup(t *testing.T) {
+	collation, ok := CollationByID(UTF8MB4_GENERAL_CI)
+
+	if ok == false || collation.Name != "utf8mb4_general_ci" || collation.Charset != "utf8mb4" {
+		t.Errorf("unexpected collation %+v", collation)
+	}
+
+	collation, ok = CollationByName("UTF8MB3_UNICODE_CI")
+
+	if ok == false || collation.ID != 192 || collation.Charset != "utf8" {
+		t.Errorf("unexpected collation %+v", collation)
+	}
+
+	if _, ok = CollationByName("klingon_ci"); ok == true {
+		t.Errorf("unknown collation found")
+	}
+
+	tests := []struct {
+		charset string
+		version string
+		id      uint16
+	}{
+		{"utf8mb4", "8.0.36", 255},
+		{"utf8mb4", "5.7.44-log", 45},
+		{"utf8mb4", "5.5.5-10.11.6-MariaDB", 45},
+		{"latin1", "8.0.36", 8},
+		{"binary", "8.0.36", BINARY_COLLATION},
+		{"utf8mb3", "8.0.36", 33},
+	}
+
+	for _, test := range tests {
+		if collation, ok = DefaultCollationForCharset(test.charset, test.version); ok == false || collation.ID != test.id {
+			t.Errorf("%s %s: unexpected collation %+v", test.charset, test.version, collation)
+		}
+	}
+}
+
+func TestCollationsUnique(t *testing.T) {
+	ids := map[uint16]bool{}
+	defaults := map[string]bool{}
+
+	for _, collation := range collations {
+		if ids[collation.ID] == true || (collation.Default == true && defaults[collation.Charset] == true) {
+			t.Errorf("duplicate collation %+v", collation)
+		}
+
+		ids[collation.ID] = true
+		defaults[collation.Charset] = defaults[collation.Charset] || collation.Default
+	}
+}
@@ -0,0 +1,155 @@
+package mysql
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// VectorKind tells which slice of a Vector holds the values of a column.
+type VectorKind uint8
+
+const (
+	BYTES_VECTOR   VectorKind = iota // Data and Offsets, e.g. strings, DECIMAL, dates
+	INT64_VECTOR                     // Int64s, signed integers and YEAR
+	UINT64_VECTOR                    // Uint64s, unsigned integers
+	FLOAT64_VECTOR                   // Float64s, FLOAT and DOUBLE
+)
+
+// Vector holds the values of one column of a ColumnarBatch. Only the
+// slice matching its Kind is set. Byte values are packed into Data, value i
+// being Data[Offsets[i]:Offsets[i+1]], as in Apache Arrow.
+type Vector struct {
+	Kind     VectorKind
+	Int64s   []int64
+	Uint64s  []uint64
+	Float64s []float64
+	Data     []byte
+	Offsets  []int
+
+	// Nulls tells which values are NULL; nil when none is. A NULL value
+	// is zero, or empty in Data.
+	Nulls []bool
+}
+
+// IsNull reports whether value i is NULL.
+func (v *Vector) IsNull(i int) bool {
+	return v.Nulls != nil && v.Nulls[i] == true
+}
+
+// Bytes returns value i of a BYTES_VECTOR.
+func (v *Vector) Bytes(i int) []byte {
+	return v.Data[v.Offsets[i]:v.Offsets[i+1]]
+}
+
+// ColumnarBatch holds up to a given number of rows of a result set column
+// by column, for analytics code which works on whole columns.
+type ColumnarBatch struct {
+	Columns []Column
+	Vectors []Vector
+	Len     int // number of rows
+}
+
+// vectorKind returns the kind of vector decoding the values of col.
+func vectorKind(col *Column) VectorKind {
+	switch col.Type {
+	case MYSQL_TYPE_TINY, MYSQL_TYPE_SHORT, MYSQL_TYPE_INT24, MYSQL_TYPE_LONG, MYSQL_TYPE_LONGLONG:
+		if col.Flags&UNSIGNED_FLAG != 0 {
+			return UINT64_VECTOR
+		}
+
+		return INT64_VECTOR
+	case MYSQL_TYPE_YEAR:
+		return INT64_VECTOR
+	case MYSQL_TYPE_FLOAT, MYSQL_TYPE_DOUBLE:
+		return FLOAT64_VECTOR
+	}
+
+	return BYTES_VECTOR
+}
+
+// ReadBatch reads up to maxRows rows (all of them if maxRows is zero or
+// less) into a ColumnarBatch, decoding the numbers of every column into a
+// typed slice instead of boxing every value. It returns io.EOF once the
+// result set has no more rows.
+func (r *Rows) ReadBatch(maxRows int) (*ColumnarBatch, error) {
+	batch := &ColumnarBatch{
+		Columns: r.columns,
+		Vectors: make([]Vector, len(r.columns)),
+	}
+
+	for i := range batch.Vectors {
+		batch.Vectors[i].Kind = vectorKind(&r.columns[i])
+
+		if batch.Vectors[i].Kind == BYTES_VECTOR {
+			batch.Vectors[i].Offsets = []int{0}
+		}
+	}
+
+	for (maxRows <= 0 || batch.Len < maxRows) && r.Next() == true {
+		for i, value := range r.values {
+			err := batch.Vectors[i].append(value, batch.Len)
+
+			if err != nil {
+				return nil, fmt.Errorf("Decode column %s: %w", r.columns[i].Name, err)
+			}
+		}
+
+		batch.Len++
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if batch.Len == 0 {
+		return nil, io.EOF
+	}
+
+	return batch, nil
+}
+
+// append adds the value of row n to the vector.
+func (v *Vector) append(value []byte, n int) error {
+	var err error
+
+	if value == nil && v.Nulls == nil {
+		v.Nulls = make([]bool, n, n+1)
+	}
+
+	if v.Nulls != nil {
+		v.Nulls = append(v.Nulls, value == nil)
+	}
+
+	switch v.Kind {
+	case INT64_VECTOR:
+		var i int64
+
+		if value != nil {
+			i, err = strconv.ParseInt(string(value), 10, 64)
+		}
+
+		v.Int64s = append(v.Int64s, i)
+	case UINT64_VECTOR:
+		var u uint64
+
+		if value != nil {
+			u, err = strconv.ParseUint(string(value), 10, 64)
+		}
+
+		v.Uint64s = append(v.Uint64s, u)
+	case FLOAT64_VECTOR:
+		var f float64
+
+		if value != nil {
+			f, err = strconv.ParseFloat(string(value), 64)
+		}
+
+		v.Float64s = append(v.Float64s, f)
+	default:
+		v.Data = append(v.Data, value...)
+		v.Offsets = append(v.Offsets, len(v.Data))
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadBatch(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writeResultSet(1, [][]byte{
+			columnPacket("id", MYSQL_TYPE_LONGLONG, UNSIGNED_FLAG, 63),
+			columnPacket("delta", MYSQL_TYPE_LONG, 0, 63),
+			columnPacket("price", MYSQL_TYPE_DOUBLE, 0, 63),
+			columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 33),
+		}, [][]byte{
+			textRowPacket("1", "-5", "1.5", "apple"),
+			textRowPacket("2", nil, "2.25", ""),
+			textRowPacket("18446744073709551615", "7", nil, nil),
+		}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	rows, err := c.Query("SELECT id, delta, price, name FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := rows.ReadBatch(2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch.Len != 2 || reflect.DeepEqual(batch.Vectors[0].Uint64s, []uint64{1, 2}) == false ||
+		reflect.DeepEqual(batch.Vectors[1].Int64s, []int64{-5, 0}) == false || batch.Vectors[1].IsNull(1) == false ||
+		reflect.DeepEqual(batch.Vectors[2].Float64s, []float64{1.5, 2.25}) == false || batch.Vectors[2].Nulls != nil ||
+		string(batch.Vectors[3].Bytes(0)) != "apple" || string(batch.Vectors[3].Bytes(1)) != "" || batch.Vectors[3].IsNull(1) == true {
+		t.Errorf("unexpected batch %+v", batch)
+	}
+
+	batch, err = rows.ReadBatch(0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch.Len != 1 || batch.Vectors[0].Uint64s[0] != 1<<64-1 || batch.Vectors[2].IsNull(0) == false ||
+		batch.Vectors[3].IsNull(0) == false || batch.Vectors[3].Kind != BYTES_VECTOR {
+		t.Errorf("unexpected batch %+v", batch)
+	}
+
+	if _, err = rows.ReadBatch(0); err != io.EOF {
+		t.Errorf("unexpected error %v", err)
+	}
+}
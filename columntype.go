@@ -0,0 +1,210 @@
+package mysql
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+const (
+	// BINARY_COLLATION is the collation (and character set) id of binary
+	// strings, telling BLOB from TEXT and BINARY from CHAR.
+	BINARY_COLLATION = 63
+
+	// NOT_FIXED_DEC is the Decimals value of floats without a fixed scale.
+	NOT_FIXED_DEC = 31
+)
+
+// DatabaseTypeName returns the type name as used in column definitions,
+// e.g. "INT", "UNSIGNED BIGINT", "VARCHAR" or "BLOB". The MariaDB types
+// known from the extended metadata are returned as such, e.g. "JSON",
+// "UUID", "INET6" or "POINT".
+func (col *Column) DatabaseTypeName() string {
+	if col.ExtendedFormat == "json" {
+		return "JSON"
+	}
+
+	if col.ExtendedType != "" {
+		return strings.ToUpper(col.ExtendedType)
+	}
+
+	name := ""
+	binary := col.CharacterSet == BINARY_COLLATION
+
+	switch col.Type {
+	case MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL:
+		name = "DECIMAL"
+	case MYSQL_TYPE_TINY:
+		name = "TINYINT"
+	case MYSQL_TYPE_SHORT:
+		name = "SMALLINT"
+	case MYSQL_TYPE_LONG:
+		name = "INT"
+	case MYSQL_TYPE_INT24:
+		name = "MEDIUMINT"
+	case MYSQL_TYPE_LONGLONG:
+		name = "BIGINT"
+	case MYSQL_TYPE_FLOAT:
+		name = "FLOAT"
+	case MYSQL_TYPE_DOUBLE:
+		name = "DOUBLE"
+	case MYSQL_TYPE_NULL:
+		return "NULL"
+	case MYSQL_TYPE_TIMESTAMP:
+		return "TIMESTAMP"
+	case MYSQL_TYPE_DATE, MYSQL_TYPE_NEWDATE:
+		return "DATE"
+	case MYSQL_TYPE_TIME:
+		return "TIME"
+	case MYSQL_TYPE_DATETIME:
+		return "DATETIME"
+	case MYSQL_TYPE_YEAR:
+		return "YEAR"
+	case MYSQL_TYPE_BIT:
+		return "BIT"
+	case MYSQL_TYPE_ENUM:
+		return "ENUM"
+	case MYSQL_TYPE_SET:
+		return "SET"
+	case MYSQL_TYPE_GEOMETRY:
+		return "GEOMETRY"
+	case MYSQL_TYPE_JSON:
+		return "JSON"
+	case MYSQL_TYPE_VARCHAR, MYSQL_TYPE_VAR_STRING:
+		if binary == true {
+			return "VARBINARY"
+		}
+
+		return "VARCHAR"
+	case MYSQL_TYPE_STRING:
+		// ENUM and SET columns are sent as strings with a flag.
+		switch {
+		case col.Flags&ENUM_FLAG != 0:
+			return "ENUM"
+		case col.Flags&SET_FLAG != 0:
+			return "SET"
+		case binary == true:
+			return "BINARY"
+		}
+
+		return "CHAR"
+	case MYSQL_TYPE_TINY_BLOB:
+		if binary == true {
+			return "TINYBLOB"
+		}
+
+		return "TINYTEXT"
+	case MYSQL_TYPE_BLOB:
+		if binary == true {
+			return "BLOB"
+		}
+
+		return "TEXT"
+	case MYSQL_TYPE_MEDIUM_BLOB:
+		if binary == true {
+			return "MEDIUMBLOB"
+		}
+
+		return "MEDIUMTEXT"
+	case MYSQL_TYPE_LONG_BLOB:
+		if binary == true {
+			return "LONGBLOB"
+		}
+
+		return "LONGTEXT"
+	default:
+		return ""
+	}
+
+	if col.Unsigned() == true {
+		return "UNSIGNED " + name
+	}
+
+	return name
+}
+
+// Nullable reports whether the column may contain NULL.
+func (col *Column) Nullable() bool {
+	return col.Flags&NOT_NULL_FLAG == 0
+}
+
+// Unsigned reports whether a numeric column is UNSIGNED.
+func (col *Column) Unsigned() bool {
+	return col.Flags&UNSIGNED_FLAG != 0
+}
+
+// Length returns the maximum length in bytes of variable length string and
+// binary columns. The length of a string column is its character length
+// times the maximum bytes per character of its character set.
+func (col *Column) Length() (int64, bool) {
+	switch col.Type {
+	case MYSQL_TYPE_VARCHAR, MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING,
+		MYSQL_TYPE_TINY_BLOB, MYSQL_TYPE_BLOB, MYSQL_TYPE_MEDIUM_BLOB,
+		MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BIT:
+		return int64(col.ColumnLength), true
+	}
+
+	return 0, false
+}
+
+// PrecisionScale returns the precision and scale of DECIMAL columns and of
+// FLOAT and DOUBLE columns declared with a fixed scale.
+func (col *Column) PrecisionScale() (int64, int64, bool) {
+	switch col.Type {
+	case MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL:
+		// The display length includes the sign and the decimal point.
+		precision := int64(col.ColumnLength)
+
+		if col.Unsigned() == false {
+			precision--
+		}
+
+		if col.Decimals > 0 {
+			precision--
+		}
+
+		return precision, int64(col.Decimals), true
+	case MYSQL_TYPE_FLOAT, MYSQL_TYPE_DOUBLE:
+		if col.Decimals == NOT_FIXED_DEC {
+			return 0, 0, false
+		}
+
+		return int64(col.ColumnLength), int64(col.Decimals), true
+	}
+
+	return 0, 0, false
+}
+
+var (
+	scanTypeInt64       = reflect.TypeOf(int64(0))
+	scanTypeUint64      = reflect.TypeOf(uint64(0))
+	scanTypeFloat64     = reflect.TypeOf(float64(0))
+	scanTypeNullInt64   = reflect.TypeOf(sql.NullInt64{})
+	scanTypeNullFloat64 = reflect.TypeOf(sql.NullFloat64{})
+	scanTypeRawBytes    = reflect.TypeOf(sql.RawBytes{})
+)
+
+// ScanType returns a Go type suitable for scanning the column into.
+func (col *Column) ScanType() reflect.Type {
+	switch col.Type {
+	case MYSQL_TYPE_TINY, MYSQL_TYPE_SHORT, MYSQL_TYPE_LONG, MYSQL_TYPE_INT24,
+		MYSQL_TYPE_LONGLONG, MYSQL_TYPE_YEAR:
+		if col.Nullable() == true {
+			return scanTypeNullInt64
+		}
+
+		if col.Unsigned() == true {
+			return scanTypeUint64
+		}
+
+		return scanTypeInt64
+	case MYSQL_TYPE_FLOAT, MYSQL_TYPE_DOUBLE:
+		if col.Nullable() == true {
+			return scanTypeNullFloat64
+		}
+
+		return scanTypeFloat64
+	}
+
+	return scanTypeRawBytes
+}
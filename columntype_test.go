@@ -0,0 +1,45 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		column   Column
+		typeName string
+		nullable bool
+	}{
+		{Column{Type: MYSQL_TYPE_LONG, Flags: NOT_NULL_FLAG}, "INT", false},
+		{Column{Type: MYSQL_TYPE_LONGLONG, Flags: UNSIGNED_FLAG}, "UNSIGNED BIGINT", true},
+		{Column{Type: MYSQL_TYPE_VAR_STRING, CharacterSet: 45}, "VARCHAR", true},
+		{Column{Type: MYSQL_TYPE_VAR_STRING, CharacterSet: BINARY_COLLATION}, "VARBINARY", true},
+		{Column{Type: MYSQL_TYPE_BLOB, CharacterSet: 45}, "TEXT", true},
+		{Column{Type: MYSQL_TYPE_BLOB, CharacterSet: BINARY_COLLATION}, "BLOB", true},
+		{Column{Type: MYSQL_TYPE_STRING, Flags: ENUM_FLAG}, "ENUM", true},
+		{Column{Type: MYSQL_TYPE_BLOB, CharacterSet: 45, ExtendedFormat: "json"}, "JSON", true},
+		{Column{Type: MYSQL_TYPE_STRING, CharacterSet: 8, ExtendedType: "inet6"}, "INET6", true},
+		{Column{Type: MYSQL_TYPE_GEOMETRY, ExtendedType: "point"}, "POINT", true},
+	}
+
+	for _, test := range tests {
+		if name := test.column.DatabaseTypeName(); name != test.typeName {
+			t.Errorf("type %d: got %s want %s", test.column.Type, name, test.typeName)
+		}
+
+		if test.column.Nullable() != test.nullable {
+			t.Errorf("%s: nullable %v", test.typeName, test.column.Nullable())
+		}
+	}
+
+	// DECIMAL(10,2): 10 digits, the sign and the point.
+	decimal := Column{Type: MYSQL_TYPE_NEWDECIMAL, ColumnLength: 12, Decimals: 2}
+
+	if precision, scale, ok := decimal.PrecisionScale(); !ok || precision != 10 || scale != 2 {
+		t.Errorf("DECIMAL(10,2): got %d,%d", precision, scale)
+	}
+
+	if _, _, ok := (&Column{Type: MYSQL_TYPE_DOUBLE, Decimals: NOT_FIXED_DEC}).PrecisionScale(); ok {
+		t.Errorf("DOUBLE without scale must not report one")
+	}
+}
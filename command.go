@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+// simpleCommand sends a command without result set and reads its OK packet.
+func (c *Connection) simpleCommand(command byte, arg []byte) (*Result, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	err := c.writeCommand(command, arg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.readOKPacket()
+}
+
+// Ping checks that the server is alive with a COM_PING.
+func (c *Connection) Ping() error {
+	_, err := c.simpleCommand(COM_PING, nil)
+	return err
+}
+
+// ResetConnection resets the session state (user variables, temporary
+// tables, prepared statements, ...) without re-authenticating by sending a
+// COM_RESET_CONNECTION. It requires MySQL 5.7.3 or MariaDB 10.2.4.
+func (c *Connection) ResetConnection() error {
+	_, err := c.simpleCommand(COM_RESET_CONNECTION, nil)
+	return err
+}
+
+// FieldList returns the columns of table whose name matches the LIKE
+// pattern wildcard (all of them if empty) with a COM_FIELD_LIST, along
+// with their default values. The command is deprecated since MySQL 5.7.11,
+// it is there for the tools and servers which still rely on it; prefer
+// SHOW COLUMNS or information_schema.
+func (c *Connection) FieldList(table string, wildcard string) ([]Column, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	// table [null terminated string]
+	// wildcard [string<EOF>]
+	err := c.writeCommand(COM_FIELD_LIST, append(append([]byte(table), 0), wildcard...))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []Column
+
+	for {
+		payload, err := c.readPacket()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(payload) > 0 && payload[0] == ERR_PACKET {
+			return nil, parseErrPacket(payload)
+		}
+
+		if isEOFPacket(payload) == true {
+			_, err = c.parseEOFPacket(payload)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return columns, nil
+		}
+
+		var column Column
+
+		err = column.parse(payload, c.columnOptions()&^protocol.COLUMN_LITE|protocol.COLUMN_DEFAULT)
+
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+}
+
+// UseDatabase changes the default database with a COM_INIT_DB.
+func (c *Connection) UseDatabase(name string) error {
+	_, err := c.simpleCommand(COM_INIT_DB, []byte(name))
+
+	if err != nil {
+		return err
+	}
+
+	c.database = name
+
+	return nil
+}
+
+// CurrentDatabase returns the default database of the connection, empty if
+// none. It follows the database given to Open and UseDatabase and, when the
+// server supports session tracking (MySQL 5.7, MariaDB 10.2), USE
+// statements.
+func (c *Connection) CurrentDatabase() string {
+	return c.database
+}
+
+// ID returns the connection id the server assigned in the handshake, the
+// Id of SHOW PROCESSLIST and the result of CONNECTION_ID().
+func (c *Connection) ID() uint32 {
+	return c.ConnectionID
+}
+
+// KillOwnQuery stops the statement running on c by sending KILL QUERY
+// through helper, another connection to the same server, since c itself
+// is busy waiting for the response. The connection c stays usable: its
+// statement fails with ER_QUERY_INTERRUPTED. ctx is checked before the
+// statement is sent.
+func (c *Connection) KillOwnQuery(ctx context.Context, helper *Connection) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rows, err := helper.Query("KILL QUERY " + strconv.FormatUint(uint64(c.ConnectionID), 10))
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKillOwnQuery(t *testing.T) {
+	c := NewConnection(ConnectionParameter{})
+	c.ConnectionID = 4242
+
+	helper, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		if payload := server.readCommand(); string(payload[1:]) != "KILL QUERY 4242" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	if c.ID() != 4242 {
+		t.Errorf("unexpected id %d", c.ID())
+	}
+
+	if err := c.KillOwnQuery(context.Background(), helper); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFieldList(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		if payload := server.readCommand(); string(payload) != "\x04city\x00co%" {
+			t.Errorf("unexpected command %q", payload)
+		}
+
+		server.writePacket(1, append(columnPacket("code", MYSQL_TYPE_VAR_STRING, NOT_NULL_FLAG, 45), lenEncString("TW")...))
+		server.writePacket(2, append(columnPacket("country", MYSQL_TYPE_VAR_STRING, 0, 45), 0xfb))
+		server.writePacket(3, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.x' doesn't exist"...))
+	}()
+
+	columns, err := c.FieldList("city", "co%")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(columns) != 2 || columns[0].Name != "code" || string(columns[0].Default) != "TW" ||
+		columns[1].Name != "country" || columns[1].Default != nil {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	if _, err = c.FieldList("x", ""); err == nil || err.(*MySQLError).Number != 1146 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
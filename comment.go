@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AppendSQLComment appends tags to query as a comment in the sqlcommenter
+// format, e.g. /*action='list',traceparent='00-...'*/, which shows up in
+// the slow and general logs of the server and can be joined with traces.
+// Keys are sorted, keys and values are URL encoded and the values quoted.
+// A query which already holds a comment is left as it is, as required by
+// the specification.
+// Reference:
+// https://google.github.io/sqlcommenter/spec/
+func AppendSQLComment(query string, tags map[string]string) string {
+	if len(tags) == 0 || strings.Contains(query, "/*") == true {
+		return query
+	}
+
+	keys := make([]string, 0, len(tags))
+
+	for key := range tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var buf strings.Builder
+
+	// The comment goes before a final semicolon.
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	end := strings.TrimSuffix(trimmed, ";")
+
+	buf.WriteString(end)
+	buf.WriteString(" /*")
+
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(commentEscape(key))
+		buf.WriteString("='")
+		buf.WriteString(commentEscape(tags[key]))
+		buf.WriteByte('\'')
+	}
+
+	buf.WriteString("*/")
+	buf.WriteString(trimmed[len(end):])
+
+	return buf.String()
+}
+
+// commentEscape URL encodes a key or value of a comment, which also
+// encodes the quotes and keeps */ from ending the comment.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAppendSQLComment(t *testing.T) {
+	tags := map[string]string{
+		"route":       "/param*d",
+		"action":      "run's",
+		"traceparent": "00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01",
+		"evil":        "*/ DROP TABLE t; /*",
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM t", "SELECT * FROM t /*action='run%27s',evil='%2A%2F%20DROP%20TABLE%20t%3B%20%2F%2A',route='%2Fparam%2Ad',traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/"},
+		{"SELECT 1;\n", "SELECT 1 /*action='run%27s',evil='%2A%2F%20DROP%20TABLE%20t%3B%20%2F%2A',route='%2Fparam%2Ad',traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01'*/;"},
+		{"SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1", "SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1"},
+	}
+
+	for _, test := range tests {
+		if got := AppendSQLComment(test.query, tags); got != test.expected {
+			t.Errorf("got  %s\nwant %s", got, test.expected)
+		}
+	}
+
+	if got := AppendSQLComment("SELECT 1", nil); got != "SELECT 1" {
+		t.Errorf("unexpected query %s", got)
+	}
+}
+
+type routeKey struct{}
+
+func TestDriverSQLComment(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{
+		SQLComment: func(ctx context.Context) map[string]string {
+			route, _ := ctx.Value(routeKey{}).(string)
+			return map[string]string{"route": route}
+		},
+	})
+	server := newFakeServer(t, serverConn)
+	dc := &driverConn{c: c}
+
+	go func() {
+		if payload := server.readCommand(); string(payload[1:]) != "DELETE FROM t WHERE id = 1 /*route='%2Fusers'*/" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	ctx := context.WithValue(context.Background(), routeKey{}, "/users")
+
+	if _, err := dc.ExecContext(ctx, "DELETE FROM t WHERE id = 1", nil); err != nil {
+		t.Fatal(err)
+	}
+}
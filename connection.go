@@ -3,17 +3,29 @@ package mysql
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/binary"
-	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/junhsieh/go-mysql-pure/protocol"
 )
 
 const (
 	MAX_PACKET_SIZE = (1 << 24)
+
+	// UTF8MB4_GENERAL_CI is the collation requested in the handshake. A
+	// fixed ASCII-safe charset keeps the backslash escaping of string
+	// literals sound: with big5, gbk or sjis a 0x5c byte can be the second
+	// half of a multibyte character and swallow the escape.
+	UTF8MB4_GENERAL_CI = 45
 )
 
 type ClientFlags uint32
@@ -23,56 +35,140 @@ type ClientFlags uint32
 // https://github.com/google/mysql/blob/master/include/mysql_com.h
 // http://boytnt.blog.51cto.com/966121/1279318
 const (
-	CLIENT_LONG_PASSWORD     ClientFlags = 1       /* new more secure passwords */
-	CLIENT_FOUND_ROWS                    = 2       /* Found instead of affected rows */
-	CLIENT_LONG_FLAG                     = 4       /* Get all column flags */
-	CLIENT_CONNECT_WITH_DB               = 8       /* One can specify db on connect */
-	CLIENT_NO_SCHEMA                     = 16      /* Don't allow database.table.column */
-	CLIENT_COMPRESS                      = 32      /* Can use compression protocol */
-	CLIENT_ODBC                          = 64      /* Odbc client */
-	CLIENT_LOCAL_FILES                   = 128     /* Can use LOAD DATA LOCAL */
-	CLIENT_IGNORE_SPACE                  = 256     /* Ignore spaces before '(' */
-	CLIENT_PROTOCOL_41                   = 512     /* New 4.1 protocol */
-	CLIENT_INTERACTIVE                   = 1024    /* This is an interactive client */
-	CLIENT_SSL                           = 2048    /* Switch to SSL after handshake */
-	CLIENT_IGNORE_SIGPIPE                = 4096    /* IGNORE sigpipes */
-	CLIENT_TRANSACTIONS                  = 8192    /* Client knows about transactions */
-	CLIENT_RESERVED                      = 16384   /* Old flag for 4.1 protocol  */
-	CLIENT_SECURE_CONNECTION             = 32768   /* New 4.1 authentication */
-	CLIENT_MULTI_STATEMENTS              = 65536   /* Enable/disable multi-stmt support */
-	CLIENT_MULTI_RESULTS                 = 131072  /* Enable/disable multi-results */
-	CLIENT_PS_MULTI_RESULTS              = 1 << 18 /* Multi-results in PS-protocol */
-	CLIENT_PLUGIN_AUTH                   = 1 << 19 /* Client supports plugin authentication */
+	CLIENT_LONG_PASSWORD                ClientFlags = 1       /* new more secure passwords */
+	CLIENT_FOUND_ROWS                               = 2       /* Found instead of affected rows */
+	CLIENT_LONG_FLAG                                = 4       /* Get all column flags */
+	CLIENT_CONNECT_WITH_DB                          = 8       /* One can specify db on connect */
+	CLIENT_NO_SCHEMA                                = 16      /* Don't allow database.table.column */
+	CLIENT_COMPRESS                                 = 32      /* Can use compression protocol */
+	CLIENT_ODBC                                     = 64      /* Odbc client */
+	CLIENT_LOCAL_FILES                              = 128     /* Can use LOAD DATA LOCAL */
+	CLIENT_IGNORE_SPACE                             = 256     /* Ignore spaces before '(' */
+	CLIENT_PROTOCOL_41                              = 512     /* New 4.1 protocol */
+	CLIENT_INTERACTIVE                              = 1024    /* This is an interactive client */
+	CLIENT_SSL                                      = 2048    /* Switch to SSL after handshake */
+	CLIENT_IGNORE_SIGPIPE                           = 4096    /* IGNORE sigpipes */
+	CLIENT_TRANSACTIONS                             = 8192    /* Client knows about transactions */
+	CLIENT_RESERVED                                 = 16384   /* Old flag for 4.1 protocol  */
+	CLIENT_SECURE_CONNECTION                        = 32768   /* New 4.1 authentication */
+	CLIENT_MULTI_STATEMENTS                         = 65536   /* Enable/disable multi-stmt support */
+	CLIENT_MULTI_RESULTS                            = 131072  /* Enable/disable multi-results */
+	CLIENT_PS_MULTI_RESULTS                         = 1 << 18 /* Multi-results in PS-protocol */
+	CLIENT_PLUGIN_AUTH                              = 1 << 19 /* Client supports plugin authentication */
+	CLIENT_CONNECT_ATTRS                            = 1 << 20 /* Client supports connection attributes */
+	CLIENT_PLUGIN_AUTH_LENENC_DATA                  = 1 << 21 /* Length encoded auth response */
+	CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS             = 1 << 22 /* Don't close the connection for an expired password */
+	CLIENT_SESSION_TRACK                            = 1 << 23 /* OK packets carry session state changes */
+	CLIENT_DEPRECATE_EOF                            = 1 << 24 /* OK packets replace EOF packets */
+	CLIENT_OPTIONAL_RESULTSET_METADATA              = 1 << 25 /* Result set metadata may be skipped */
+	CLIENT_ZSTD_COMPRESSION_ALGORITHM               = 1 << 26 /* Can use zstd compression */
+	CLIENT_QUERY_ATTRIBUTES                         = 1 << 27 /* COM_QUERY carries query attributes */
+)
+
+// Session state change types of CLIENT_SESSION_TRACK.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_ok_packet.html
+const (
+	SESSION_TRACK_SYSTEM_VARIABLES = iota
+	SESSION_TRACK_SCHEMA
+	SESSION_TRACK_STATE_CHANGE
+	SESSION_TRACK_GTIDS
+	SESSION_TRACK_TRANSACTION_CHARACTERISTICS
+	SESSION_TRACK_TRANSACTION_STATE
+)
+
+// MariaDB extended capabilities, exchanged in the last 4 reserved bytes of
+// the handshake packets when the server does not set CLIENT_LONG_PASSWORD
+// (CLIENT_MYSQL for MariaDB).
+// Reference:
+// https://mariadb.com/kb/en/connection/#capabilities
+const (
+	MARIADB_CLIENT_PROGRESS             ClientFlags = 1 << 0 /* Client supports progress indicator */
+	MARIADB_CLIENT_COM_MULTI                        = 1 << 1 /* Client supports COM_MULTI */
+	MARIADB_CLIENT_STMT_BULK_OPERATIONS             = 1 << 2 /* Client supports bulk operations */
+	MARIADB_CLIENT_EXTENDED_METADATA                = 1 << 3 /* Column definitions carry extended type info */
+	MARIADB_CLIENT_CACHE_METADATA                   = 1 << 4 /* COM_STMT_EXECUTE may skip unchanged metadata */
+)
+
+// MARIADB_CLIENT_CAPABILITIES are the extended capabilities the client
+// requests. With MARIADB_CLIENT_CACHE_METADATA the columns of a Stmt are
+// only sent again when they change.
+const MARIADB_CLIENT_CAPABILITIES = MARIADB_CLIENT_EXTENDED_METADATA | MARIADB_CLIENT_CACHE_METADATA
+
+// Reference:
+// https://dev.mysql.com/doc/internals/en/status-flags.html
+const (
+	SERVER_STATUS_IN_TRANS             uint16 = 1
+	SERVER_STATUS_AUTOCOMMIT                  = 2
+	SERVER_MORE_RESULTS_EXISTS                = 8
+	SERVER_STATUS_NO_GOOD_INDEX_USED          = 16
+	SERVER_STATUS_NO_INDEX_USED               = 32
+	SERVER_STATUS_CURSOR_EXISTS               = 64
+	SERVER_STATUS_LAST_ROW_SENT               = 128
+	SERVER_STATUS_DB_DROPPED                  = 256
+	SERVER_STATUS_NO_BACKSLASH_ESCAPES        = 512
+	SERVER_STATUS_METADATA_CHANGED            = 1024
+	SERVER_QUERY_WAS_SLOW                     = 2048
+	SERVER_PS_OUT_PARAMS                      = 4096
+	SERVER_STATUS_IN_TRANS_READONLY           = 8192
+	SERVER_SESSION_STATE_CHANGED              = 16384
+)
+
+// Reference:
+// https://mariadb.com/kb/en/mariadb/resultset/#field-detail-flag
+const (
+	NOT_NULL_FLAG         uint16 = 1
+	PRI_KEY_FLAG                 = 2
+	UNIQUE_KEY_FLAG              = 4
+	MULTIPLE_KEY_FLAG            = 8
+	BLOB_FLAG                    = 16
+	UNSIGNED_FLAG                = 32
+	ZEROFILL_FLAG                = 64
+	BINARY_FLAG                  = 128
+	ENUM_FLAG                    = 256
+	AUTO_INCREMENT_FLAG          = 512
+	TIMESTAMP_FLAG               = 1024
+	SET_FLAG                     = 2048
+	NO_DEFAULT_VALUE_FLAG        = 4096
+	ON_UPDATE_NOW_FLAG           = 8192
+	NUM_FLAG                     = 32768
 )
 
+// Reference:
+// https://mariadb.com/kb/en/mariadb/text-protocol/
 const (
-	MYSQL_TYPE_DECIMAL uint8 = iota
-	MYSQL_TYPE_TINY
-	MYSQL_TYPE_SHORT
-	MYSQL_TYPE_LONG
-	MYSQL_TYPE_FLOAT
-	MYSQL_TYPE_DOUBLE
-	MYSQL_TYPE_NULL
-	MYSQL_TYPE_TIMESTAMP
-	MYSQL_TYPE_LONGLONG
-	MYSQL_TYPE_INT24
-	MYSQL_TYPE_DATE
-	MYSQL_TYPE_TIME
-	MYSQL_TYPE_DATETIME
-	MYSQL_TYPE_YEAR
-	MYSQL_TYPE_NEWDATE
-	MYSQL_TYPE_VARCHAR
-	MYSQL_TYPE_BIT
-	MYSQL_TYPE_NEWDECIMAL  = 246
-	MYSQL_TYPE_ENUM        = 247
-	MYSQL_TYPE_SET         = 248
-	MYSQL_TYPE_TINY_BLOB   = 249
-	MYSQL_TYPE_MEDIUM_BLOB = 250
-	MYSQL_TYPE_LONG_BLOB   = 251
-	MYSQL_TYPE_BLOB        = 252
-	MYSQL_TYPE_VAR_STRING  = 253
-	MYSQL_TYPE_STRING      = 254
-	MYSQL_TYPE_GEOMETRY    = 255
+	COM_SLEEP byte = iota
+	COM_QUIT
+	COM_INIT_DB
+	COM_QUERY
+	COM_FIELD_LIST
+	COM_CREATE_DB
+	COM_DROP_DB
+	COM_REFRESH
+	COM_SHUTDOWN
+	COM_STATISTICS
+	COM_PROCESS_INFO
+	COM_CONNECT
+	COM_PROCESS_KILL
+	COM_DEBUG
+	COM_PING
+	COM_TIME
+	COM_DELAYED_INSERT
+	COM_CHANGE_USER
+	COM_BINLOG_DUMP
+	COM_TABLE_DUMP
+	COM_CONNECT_OUT
+	COM_REGISTER_SLAVE
+	COM_STMT_PREPARE
+	COM_STMT_EXECUTE
+	COM_STMT_SEND_LONG_DATA
+	COM_STMT_CLOSE
+	COM_STMT_RESET
+	COM_SET_OPTION
+	COM_STMT_FETCH
+	COM_DAEMON
+	COM_BINLOG_DUMP_GTID
+	COM_RESET_CONNECTION
 )
 
 type Connection struct {
@@ -84,7 +180,42 @@ type Connection struct {
 
 	mutex *sync.Mutex
 
-	debugBuf *bytes.Buffer
+	debugBuf  *bytes.Buffer
+	traceConn uint64 // number of the connection in TraceRecord
+
+	header        [4]byte // header of the packet being read
+	readBuf       []byte  // payload returned by readPacket, reused for the next one
+	readBufMemory int64   // size of readBuf accounted in the MemoryBudget
+
+	writeHeader  [4]byte   // header of the packet written by writeDirect
+	writeBuffers [2][]byte // header and payload written by writeDirect
+	writev       bool      // DirectIO writes bypass the write buffer
+
+	readBytes    int64 // bytes of the packets read so far
+	commandBytes int64 // readBytes when the last command was sent
+
+	sequence uint8  // sequence number of the next packet
+	status   uint16 // status flags of the last OK/EOF packet
+
+	broken bool // a network or protocol error left the stream out of sync
+	unsent bool // the last command failed before the server could read it
+
+	readTimeout time.Duration // rolling per packet read deadline, 0 if none
+
+	watch         *contextWatch // context of the statement, see QueryContext
+	draining      bool          // a cancelled statement is being drained
+	interruptible bool          // the next packet is the first of a response
+
+	maxAllowedPacket int  // max_allowed_packet of the server, 0 if unknown
+	ansiQuotes       bool // sql_mode contains ANSI_QUOTES
+
+	waitTimeout        time.Duration
+	interactiveTimeout time.Duration
+
+	clientFlags         ClientFlags // capabilities sent in the handshake response
+	mariaDBCapabilities ClientFlags // negotiated MariaDB extended capabilities
+
+	database string // current default database, empty if none
 
 	ProtocolVersion          uint8
 	ServerVersion            string
@@ -97,20 +228,194 @@ type Connection struct {
 	LenOfScramblePart2       uint8
 	ScramblePart2            []byte
 	AuthenticationPluginName string
+
+	// ServerMariaDBCapabilities are the MariaDB extended capabilities of
+	// the server, zero for MySQL.
+	ServerMariaDBCapabilities ClientFlags
 }
 
 type ConnectionParameter struct {
+	// Network is "tcp", "unix" with the path of the socket in Host, or on
+	// Windows "pipe" with the name of the named pipe in Host, MySQL if
+	// empty. Shared memory is not supported.
 	Network  string
 	Host     string
 	Port     string
 	DBName   string
 	Username string
-	Password string
+	Password Secret
+
+	// AllowLocalInfile announces CLIENT_LOCAL_FILES so LOAD DATA LOCAL
+	// INFILE can be used, e.g. by ImportCSV.
+	AllowLocalInfile bool
+
+	// LocalInfileFS, with AllowLocalInfile, serves the files requested by
+	// the LOAD DATA LOCAL INFILE statements run with Query, e.g. an
+	// embed.FS or an os.DirFS. The server, which chooses the file name,
+	// cannot read anything outside of it. Requests are refused without it
+	// and the statement returns ErrLocalInfileRefused.
+	LocalInfileFS fs.FS
+
+	// LocalInfileProgress, when set, is called after every chunk of a
+	// file served from LocalInfileFS with the name requested by the
+	// server and the number of chunks and bytes sent so far. It runs
+	// before the next chunk is read, so sleeping in it slows the upload
+	// down, e.g. to enforce a rate limit.
+	LocalInfileProgress func(name string, chunks int, bytes int64)
+
+	// MaxAllowedPacket caps the size of outgoing commands. When zero the
+	// max_allowed_packet of the server is used; a negative value disables
+	// the check.
+	MaxAllowedPacket int
+
+	// Interactive announces CLIENT_INTERACTIVE, making the server close the
+	// idle connection after interactive_timeout instead of wait_timeout.
+	Interactive bool
+
+	// ConnectTimeout bounds the whole of Open: dial, TLS, handshake,
+	// authentication and the session variables query, including the
+	// attempts and delays of ConnectRetry. Open then fails with an error
+	// matching ErrConnectTimeout with errors.Is, which tells the step cut
+	// short. Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// HandshakeTimeout bounds the time from the established connection
+	// to the end of authentication, independently of the deadline of the
+	// context given to OpenContext. Zero means no limit.
+	HandshakeTimeout time.Duration
+
+	// ReadTimeout, once connected, bounds the wait for every single packet
+	// rather than a whole command, so a server dying silently in the middle
+	// of a long result set is noticed without limiting how long streaming
+	// may take. Zero means no limit.
+	ReadTimeout time.Duration
+
+	// LiteColumnMetadata only decodes the name, type, flags, length and
+	// decimals of result set columns, leaving Catalog, Schema, Table,
+	// OrgTable and OrgName empty. It saves allocations on hot paths such
+	// as point lookups where the metadata is not needed.
+	LiteColumnMetadata bool
+
+	// MaxPayloadSize and MaxColumnSize limit the size of a packet payload
+	// (joined when split over several packets) and of a column value the
+	// server may send, guarding against a malicious server or proxy making
+	// the client allocate unbounded memory. Zero means no limit.
+	MaxPayloadSize int
+	MaxColumnSize  int
+
+	// TLSConfig, when set, switches the connection to TLS before
+	// authenticating. An empty ServerName is taken from Host.
+	TLSConfig *tls.Config
+
+	// StrictSecurity refuses with a *SecurityError: connections without
+	// TLS (unix sockets and named pipes excepted) or with InsecureSkipVerify,
+	// AllowLocalInfile, and servers asking for the mysql_old_password or
+	// mysql_clear_password plugins.
+	StrictSecurity bool
+
+	// MinServerVersion, e.g. "8.0.28" or "10.6", refuses older servers
+	// with a *SecurityError.
+	MinServerVersion string
+
+	// AuditFunc, when set, receives every statement of the application
+	// with the user and default database, for compliance logging.
+	// AuditRedact replaces the literals of the statements with ? first.
+	AuditFunc   AuditFunc
+	AuditRedact bool
+
+	// KillHelper, when set, returns a connection to the same server, which
+	// QueryContext uses to KILL QUERY a statement whose context is done.
+	// The connection is closed afterwards. CancelGracePeriod bounds each
+	// step of the cancellation, DEFAULT_CANCEL_GRACE_PERIOD if zero.
+	KillHelper        func(ctx context.Context) (*Connection, error)
+	CancelGracePeriod time.Duration
+
+	// ReadThrottle and WriteThrottle, when set, limit the bandwidth the
+	// connection uses to receive and send, e.g. for a backfill which must
+	// not saturate a replica. Connections sharing a Throttle, such as the
+	// connections of a Connector, share its rate.
+	ReadThrottle  *Throttle
+	WriteThrottle *Throttle
+
+	// MemoryBudget, when set, bounds the memory held in packet buffers
+	// and buffered rows together with the other connections sharing it.
+	MemoryBudget *MemoryBudget
+
+	// ProgressFunc, when set, receives the progress reports MariaDB sends
+	// every progress_report_time seconds while running ALTER TABLE, LOAD
+	// DATA and other long statements.
+	ProgressFunc ProgressFunc
+
+	// KillOnClose makes Rows.Close stop a statement whose rows were not
+	// read to the end with KILL QUERY through KillHelper before discarding
+	// what the server already sent, rather than discarding the whole rest
+	// of the result set. Draining is cheaper for a few rows, killing for
+	// a long result set or one still being computed.
+	KillOnClose bool
+
+	// SQLComment, when set, returns the tags, e.g. the trace id or the
+	// route, which the database/sql driver appends to every statement run
+	// with ctx; see AppendSQLComment.
+	SQLComment func(ctx context.Context) map[string]string
+
+	// StatementStats, when set, aggregates the statements sent with
+	// Query, QueryContext, Pipeline and ImportCSV by fingerprint.
+	StatementStats *StatementStats
+
+	// Firewall, when set, checks every statement the database/sql driver
+	// is given before it is sent; see Firewall.Check for the others.
+	Firewall *Firewall
+
+	// OnFailover, when set, is called once when a connection of a
+	// Connector sees an error for which IsFailover holds, e.g. to look up
+	// the new primary in a topology service before the next connections
+	// dial it. The connections opened before are discarded by database/sql
+	// as they are checked out, so that new statements go to the server the
+	// address, whose host name is resolved again by every dial, now leads
+	// to.
+	OnFailover func(err error)
+
+	// ConnectRetry retries Open after network failures, too many
+	// connections and a server shutting down, but not after an
+	// authentication failure.
+	ConnectRetry RetryPolicy
+
+	// Breaker, when set, fails Open fast while the server keeps refusing
+	// connections.
+	Breaker *Breaker
+
+	// Dial, when set, replaces net.Dialer to establish the connection,
+	// e.g. to go through an SSH tunnel or a proxy.
+	Dial func(ctx context.Context, network string, address string) (net.Conn, error)
+
+	// BeforeHandshake, when set, receives the established connection
+	// before the server is read from and returns the one to use instead,
+	// e.g. the connection wrapped by a cloud connector in its own TLS
+	// tunnel. The dialed connection is closed when it returns an error.
+	BeforeHandshake func(conn net.Conn) (net.Conn, error)
+
+	// DirectIO trades throughput for latency on connections running small
+	// point queries: packets are written with a single writev call rather
+	// than copied into the write buffer first, and the read buffer is only
+	// DIRECT_IO_READ_BUFFER_SIZE bytes, so larger payloads are read
+	// straight into the packet buffer. Writes are only direct on TCP and
+	// Unix socket connections without TLS.
+	DirectIO bool
+
+	// TraceWriter, when set, receives every packet read and written as a
+	// TraceRecord on a line of JSON, which ReplayTrace plays back. Like
+	// IsDebugPacket the trace includes the auth response. A TraceWriter
+	// shared by several connections must be safe for concurrent use.
+	TraceWriter io.Writer
 
 	IsDebugPacket bool
 }
 
 func NewConnection(param ConnectionParameter) *Connection {
+	// The password of the connection is zeroed after the handshake, not
+	// the one of the caller.
+	param.Password = param.Password.clone()
+
 	return &Connection{
 		param:    param,
 		mutex:    new(sync.Mutex),
@@ -119,57 +424,460 @@ func NewConnection(param ConnectionParameter) *Connection {
 }
 
 func (c *Connection) Open() error {
+	return c.OpenContext(context.Background())
+}
+
+// OpenContext is like Open but ctx bounds the whole connection setup: dial,
+// handshake, authentication and the session variables query.
+func (c *Connection) OpenContext(ctx context.Context) error {
+	err := c.checkParameters()
+
+	if err != nil {
+		return err
+	}
+
+	if c.param.ConnectTimeout <= 0 {
+		return c.connect(ctx)
+	}
+
+	deadline := time.Now().Add(c.param.ConnectTimeout)
+
+	// The deadline of ctx comes first anyway.
+	if ctxDeadline, ok := ctx.Deadline(); ok == true && ctxDeadline.Before(deadline) {
+		return c.connect(ctx)
+	}
+
+	connectCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	err = c.connect(connectCtx)
+
+	// Whichever step was cut short. The clock is checked rather than
+	// connectCtx, whose expiry may lag behind the socket deadline.
+	if err != nil && ctx.Err() == nil && time.Now().Before(deadline) == false {
+		return fmt.Errorf("%w: %v", ErrConnectTimeout, err)
+	}
+
+	return err
+}
+
+// connect opens the connection, retrying as set by ConnectRetry.
+func (c *Connection) connect(ctx context.Context) error {
 	var err error
 
-	c.conn, err = net.Dial(c.param.Network, c.param.Host+":"+c.param.Port)
+	retry := c.param.ConnectRetry
+
+	if retry.Attempts <= 1 {
+		return c.attempt(ctx)
+	}
+
+	// The password is zeroed by every handshake.
+	password := c.param.Password.clone()
+	defer password.zero()
+
+	for attempt := 1; ; attempt++ {
+		err = c.attempt(ctx)
+
+		if err == nil || attempt >= retry.Attempts || isRetryableConnectError(err) == false {
+			return err
+		}
+
+		if c.conn != nil {
+			c.conn.Close()
+			c.conn = nil
+		}
+
+		c.param.Password = password.clone()
+
+		timer := time.NewTimer(retry.delay(attempt))
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// attempt opens the connection once, through the breaker if any.
+func (c *Connection) attempt(ctx context.Context) error {
+	if c.param.Breaker == nil {
+		return c.open(ctx)
+	}
+
+	probe, err := c.param.Breaker.allow()
 
 	if err != nil {
 		return err
 	}
 
-	if c.param.IsDebugPacket == true {
-		c.reader = bufio.NewReader(io.TeeReader(c.conn, c.debugBuf))
-		c.writer = bufio.NewWriter(io.MultiWriter(c.conn, c.debugBuf))
+	err = c.open(ctx)
+
+	c.param.Breaker.record(ctx, probe, err)
+
+	return err
+}
+
+// open dials the server and sets up the connection.
+func (c *Connection) open(ctx context.Context) error {
+	var err error
+
+	address := c.param.Host
+
+	// Unix domain sockets and named pipes are addressed by their path or
+	// name only.
+	if c.param.Network != "unix" && c.param.Network != "pipe" {
+		address = net.JoinHostPort(c.param.Host, c.param.Port)
+	}
+
+	if c.param.Dial != nil {
+		c.conn, err = c.param.Dial(ctx, c.param.Network, address)
+	} else if c.param.Network == "pipe" {
+		c.conn, err = dialNamedPipe(ctx, address)
 	} else {
-		c.reader = bufio.NewReader(c.conn)
-		c.writer = bufio.NewWriter(c.conn)
+		c.conn, err = new(net.Dialer).DialContext(ctx, c.param.Network, address)
 	}
 
+	if err != nil {
+		return err
+	}
+
+	if c.param.BeforeHandshake != nil {
+		conn, err := c.param.BeforeHandshake(c.conn)
+
+		if err != nil {
+			c.conn.Close()
+			return err
+		}
+
+		c.conn = conn
+	}
+
+	if c.param.ReadThrottle != nil || c.param.WriteThrottle != nil {
+		c.conn = &throttledConn{Conn: c.conn, read: c.param.ReadThrottle, write: c.param.WriteThrottle}
+	}
+
+	// Past the dial ctx is enforced through the deadline of the socket,
+	// which is cleared again once the connection is set up.
+	ctxDeadline, _ := ctx.Deadline()
+
+	// The handshake gets its own budget so a server (or load balancer)
+	// which accepts the connection but never speaks cannot stall Open.
+	handshakeDeadline := ctxDeadline
+
+	if c.param.HandshakeTimeout > 0 {
+		deadline := time.Now().Add(c.param.HandshakeTimeout)
+
+		if handshakeDeadline.IsZero() == true || deadline.Before(handshakeDeadline) {
+			handshakeDeadline = deadline
+		}
+	}
+
+	// c.conn changes once TLS is started, the deadlines are set on the
+	// underlying connection.
+	netConn := c.conn
+
+	netConn.SetDeadline(handshakeDeadline)
+
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		exited := make(chan struct{})
+
+		go func() {
+			defer close(exited)
+
+			select {
+			case <-ctx.Done():
+				// Unblock any pending read or write.
+				netConn.SetDeadline(time.Unix(1, 0))
+			case <-stop:
+			}
+		}()
+
+		defer func() {
+			close(stop)
+			<-exited
+			netConn.SetDeadline(time.Time{})
+		}()
+	}
+
+	c.setBuffers()
+
 	//
 	err = c.readInitPacket()
 
 	if err != nil {
-		return err
+		return c.handshakeError(ctx, err)
 	}
 
-	spew.Dump(c.debugBuf.Bytes())
+	c.status = c.StatusFlags
+	c.sequence = 1
+
+	c.debugDump(c.debugBuf.Bytes())
 	c.debugBuf.Reset()
 
+	err = c.checkServer()
+
+	if err != nil {
+		return err
+	}
+
+	if c.param.TLSConfig != nil {
+		err = c.startTLS(ctx)
+
+		if err != nil {
+			return c.handshakeError(ctx, err)
+		}
+	}
+
 	//
 	err = c.sendAuth()
 
 	if err != nil {
-		return err
+		return c.handshakeError(ctx, err)
 	}
 
-	spew.Dump(c.debugBuf.Bytes())
+	c.debugDump(c.debugBuf.Bytes())
 	c.debugBuf.Reset()
 
+	// Also reported by session tracking in the OK packet below.
+	c.database = c.param.DBName
+
 	//
 	err = c.readResult()
 
 	if err != nil {
-		return err
+		return c.handshakeError(ctx, err)
 	}
 
-	spew.Dump(c.debugBuf.Bytes())
+	c.debugDump(c.debugBuf.Bytes())
 	c.debugBuf.Reset()
 
+	// Back to the deadline of ctx. Checking ctx after the swap makes sure
+	// a cancellation in between is not lost.
+	c.conn.SetDeadline(ctxDeadline)
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	//
+	err = c.readSessionVariables()
+
+	if err != nil {
+		return err
+	}
+
+	// The rolling read deadline starts once ctx no longer applies.
+	c.readTimeout = c.param.ReadTimeout
+
 	//
+	return ctx.Err()
+}
+
+// handshakeError reports a timeout of the handshake as ErrHandshakeTimeout
+// unless it was ctx that ran out.
+func (c *Connection) handshakeError(ctx context.Context, err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() == true && c.param.HandshakeTimeout > 0 && ctx.Err() == nil {
+		// The socket deadline may be the one of ctx, expired a moment
+		// before ctx reports it.
+		if deadline, ok := ctx.Deadline(); ok == true && time.Now().Before(deadline) == false {
+			return err
+		}
+
+		return ErrHandshakeTimeout
+	}
+
+	return err
+}
+
+// debugPrintf prints handshake details when IsDebugPacket is set.
+func (c *Connection) debugPrintf(format string, a ...interface{}) {
+	if c.param.IsDebugPacket == true {
+		spew.Printf(format, a...)
+	}
+}
+
+// debugDump dumps handshake details when IsDebugPacket is set. The dumps
+// include the scramble and the auth response.
+func (c *Connection) debugDump(a ...interface{}) {
+	if c.param.IsDebugPacket == true {
+		spew.Dump(a...)
+	}
+}
+
+// SESSION_VARIABLES_QUERY reads the session variables the client adapts to
+// after connecting and after a session reset.
+const SESSION_VARIABLES_QUERY = "SELECT @@max_allowed_packet, @@sql_mode, @@wait_timeout, @@interactive_timeout"
+
+// readSessionVariables queries the session variables the client adapts to.
+func (c *Connection) readSessionVariables() error {
+	rows, err := c.Query(SESSION_VARIABLES_QUERY)
+
+	if err == nil {
+		err = c.parseSessionVariables(rows)
+	}
+
+	return c.sessionVariablesRead(err)
+}
+
+// resetSession sends a COM_RESET_CONNECTION followed by
+// SESSION_VARIABLES_QUERY in a single write, so that reading back the
+// variables restored by the reset, such as the global sql_mode the escaping
+// depends on, costs no extra round trip. The error of the reset comes
+// first: the query is answered whether or not the server knows the reset.
+func (c *Connection) resetSession() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	reset, err := c.commandPayload(COM_RESET_CONNECTION, nil)
+
+	if err != nil {
+		return err
+	}
+
+	query, err := c.commandPayload(COM_QUERY, []byte(SESSION_VARIABLES_QUERY))
+
+	if err != nil {
+		return err
+	}
+
+	err = c.writeCommands([][]byte{reset, query})
+
+	if err != nil {
+		return err
+	}
+
+	// Every response starts right after its one packet command.
+	c.sequence = 1
+	c.commandBytes = c.readBytes
+
+	_, resetErr := c.readOKPacket()
+
+	if c.broken == true {
+		return resetErr
+	}
+
+	c.sequence = 1
+	c.commandBytes = c.readBytes
+
+	start := time.Now()
+
+	// The reset holds the mutex, the rows must not release it.
+	rows := &Rows{c: c, released: true}
+
+	err = rows.readResultSetHeader()
+
+	if err == nil {
+		err = c.parseSessionVariables(rows)
+
+		// Close does not read the rest of released rows.
+		for rows.Next() == true {
+		}
+
+		if err == nil {
+			err = rows.err
+		}
+	}
+
+	c.audit(SESSION_VARIABLES_QUERY, start, nil, err)
+	c.recordStatement(SESSION_VARIABLES_QUERY, start, 0, err)
+
+	err = c.sessionVariablesRead(err)
+
+	if resetErr != nil {
+		return resetErr
+	}
+
+	return err
+}
+
+// sessionVariablesRead completes the reading of SESSION_VARIABLES_QUERY
+// which ended with err.
+func (c *Connection) sessionVariablesRead(err error) error {
+	if _, ok := err.(*MySQLError); ok == true && c.IsVitess() == true {
+		// vtgate sends the SELECT to a tablet and fails when there is
+		// no keyspace to route it to, the defaults then apply.
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if c.param.MaxAllowedPacket > 0 {
+		c.maxAllowedPacket = c.param.MaxAllowedPacket
+	} else if c.param.MaxAllowedPacket < 0 {
+		c.maxAllowedPacket = 0
+	}
+
 	return nil
 }
 
+// parseSessionVariables reads the row of SESSION_VARIABLES_QUERY. Proxies
+// such as vtgate answer NULL for the variables they do not know, which
+// leaves the defaults.
+func (c *Connection) parseSessionVariables(rows *Rows) error {
+	var err error
+
+	if rows.Next() {
+		values := rows.Values()
+
+		if values[0] != nil {
+			c.maxAllowedPacket, err = strconv.Atoi(string(values[0]))
+		}
+
+		if err == nil {
+			c.setSQLMode(string(values[1]))
+		}
+
+		if err == nil && values[2] != nil {
+			c.waitTimeout, err = parseSeconds(values[2])
+		}
+
+		if err == nil && values[3] != nil {
+			c.interactiveTimeout, err = parseSeconds(values[3])
+		}
+
+		if err != nil {
+			rows.Close()
+			return err
+		}
+	}
+
+	return rows.Close()
+}
+
+func parseSeconds(value []byte) (time.Duration, error) {
+	seconds, err := strconv.Atoi(string(value))
+	return time.Duration(seconds) * time.Second, err
+}
+
+// WaitTimeout returns the session wait_timeout: the server closes the
+// connection after being idle that long. With Interactive it has been set
+// from interactive_timeout by the server. Pools should retire idle
+// connections before, e.g. with sql.DB.SetConnMaxIdleTime.
+func (c *Connection) WaitTimeout() time.Duration {
+	return c.waitTimeout
+}
+
+// InteractiveTimeout returns the interactive_timeout of the session, which
+// the server uses as wait_timeout for clients connecting with Interactive.
+func (c *Connection) InteractiveTimeout() time.Duration {
+	return c.interactiveTimeout
+}
+
+// MaxAllowedPacket returns the largest command the server accepts, or zero
+// when unknown.
+func (c *Connection) MaxAllowedPacket() int {
+	return c.maxAllowedPacket
+}
+
 func (c *Connection) Close() error {
+	c.dropReadBuf()
+
 	return c.conn.Close()
 }
 
@@ -188,145 +896,226 @@ func (c *Connection) readInitPacket() error {
 
 	if packetHeader.Seq != 0 {
 		// The sequence number of the initial packet must be a zero.
-		return errors.New("Unexpected Sequence Number")
+		return ErrUnexpectedSequence
 	}
 
-	spew.Printf("=== packetHeader\n")
-	spew.Dump(packetHeader)
+	c.debugPrintf("=== packetHeader\n")
+	c.debugDump(packetHeader)
+
+	// The whole packet is read first so that no field can run past the
+	// length announced in the header.
+	payload := make([]byte, packetHeader.Len)
 
-	// ProtocolVersion [1 byte]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.ProtocolVersion)
+	err = ReadPacket(c.reader, payload)
 
 	if err != nil {
 		return err
 	}
 
-	spew.Printf("=== ProtocolVersion\n")
-	spew.Dump(c.ProtocolVersion)
+	// A server refusing the connection, e.g. "Too many connections" or
+	// "Host is not allowed to connect", sends an ERR packet instead.
+	if len(payload) > 0 && payload[0] == ERR_PACKET {
+		return parseErrPacket(payload)
+	}
 
-	// ServerVersion [null terminated string]
-	c.ServerVersion, err = c.reader.ReadString('\x00')
+	err = c.parseInitPacket(payload)
 
 	if err != nil {
 		return err
 	}
 
-	spew.Printf("=== ServerVersion\n")
-	spew.Dump(c.ServerVersion)
+	c.debugPrintf("=== ProtocolVersion ServerVersion ConnectionID\n")
+	c.debugDump(c.ProtocolVersion, c.ServerVersion, c.ConnectionID)
+	c.debugPrintf("=== ScramblePart1 ScramblePart2 AuthenticationPluginName\n")
+	c.debugDump(c.ScramblePart1, c.ScramblePart2, c.AuthenticationPluginName)
+
+	return nil
+}
 
-	// ConnectionID [4 bytes]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.ConnectionID)
+// parseInitPacket decodes the initial handshake packet, see
+// protocol.ParseHandshake, into the fields of the connection.
+// Reference:
+// https://dev.mysql.com/doc/internals/en/connection-phase-packets.html#packet-Protocol::HandshakeV10
+func (c *Connection) parseInitPacket(payload []byte) error {
+	h, err := protocol.ParseHandshake(payload)
+
+	if versionErr, ok := err.(*protocol.VersionError); ok == true {
+		c.ProtocolVersion = versionErr.Version
+		return &ProtocolVersionError{Version: versionErr.Version}
+	}
 
 	if err != nil {
 		return err
 	}
 
-	spew.Printf("=== ConnectionID\n")
-	spew.Dump(c.ConnectionID)
+	capabilities := ClientFlags(h.Capabilities)
+
+	c.ProtocolVersion = h.ProtocolVersion
+	c.ServerVersion = h.ServerVersion
+	c.ConnectionID = h.ConnectionID
+	c.ScramblePart1 = h.AuthPluginData[:8:8]
+	c.ServerCapabilitiesPart1 = uint16(capabilities)
+	c.ServerDefaultCollation = h.CharacterSet
+	c.StatusFlags = h.StatusFlags
+	c.ServerCapabilitiesPart2 = uint16(capabilities >> 16)
+	c.LenOfScramblePart2 = h.AuthPluginDataLength
+	c.ScramblePart2 = nil
+	c.AuthenticationPluginName = h.AuthPluginName
+	c.ServerMariaDBCapabilities = ClientFlags(h.MariaDBCapabilities)
+
+	if capabilities&CLIENT_SECURE_CONNECTION != 0 {
+		// mysql_native_password needs a 20 byte scramble.
+		if len(h.AuthPluginData) < 8+12 {
+			return ErrMalformedPacket
+		}
+
+		c.ScramblePart2 = h.AuthPluginData[8:]
+	}
 
-	// ScramblePart1 [8 bytes]
-	c.ScramblePart1 = make([]byte, 8)
+	// sendAuth answers with a 4.1 handshake response.
+	if capabilities&CLIENT_PROTOCOL_41 == 0 || capabilities&CLIENT_SECURE_CONNECTION == 0 {
+		return ErrUnsupportedServer
+	}
 
-	err = ReadPacket(c.reader, c.ScramblePart1[0:8])
+	return nil
+}
 
-	if err != nil {
-		return err
+// setBuffers sets up the buffered reader and writer of the connection.
+func (c *Connection) setBuffers() {
+	if c.param.TraceWriter != nil {
+		read, write := c.traceStreams()
+
+		if c.param.IsDebugPacket == true {
+			read = io.MultiWriter(read, c.debugBuf)
+			write = io.MultiWriter(write, c.debugBuf)
+		}
+
+		c.reader = bufio.NewReader(io.TeeReader(c.conn, read))
+		c.writer = bufio.NewWriter(io.MultiWriter(c.conn, write))
+	} else if c.param.IsDebugPacket == true {
+		c.reader = bufio.NewReader(io.TeeReader(c.conn, c.debugBuf))
+		c.writer = bufio.NewWriter(io.MultiWriter(c.conn, c.debugBuf))
+	} else if c.param.DirectIO == true {
+		c.reader = bufio.NewReaderSize(c.conn, DIRECT_IO_READ_BUFFER_SIZE)
+		c.writer = bufio.NewWriter(c.conn)
+	} else {
+		c.reader = bufio.NewReader(c.conn)
+		c.writer = bufio.NewWriter(c.conn)
 	}
 
-	spew.Printf("=== ScramblePart1\n")
-	spew.Dump(c.ScramblePart1)
+	// Only the connections of the net package turn net.Buffers into a
+	// single writev call, TLS would send the header in a record of its
+	// own.
+	c.writev = false
 
-	// Reserved byte [1 byte]
-	IgnoreBytes(c.reader, 1)
+	if c.param.DirectIO == true && c.param.IsDebugPacket == false && c.param.TraceWriter == nil {
+		switch c.conn.(type) {
+		case *net.TCPConn, *net.UnixConn:
+			c.writev = true
+		}
+	}
+}
 
-	if err != nil {
-		return err
+// startTLS sends the SSL request, a handshake response cut short after the
+// reserved bytes, and switches the connection to TLS.
+// Reference:
+// https://dev.mysql.com/doc/internals/en/ssl-handshake.html
+func (c *Connection) startTLS(ctx context.Context) error {
+	if c.ServerCapabilitiesPart1&uint16(CLIENT_SSL) == 0 {
+		return ErrNoTLS
 	}
 
-	// ServerCapabilitiesPart1 (lower 2 bytes) [2 bytes]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.ServerCapabilitiesPart1)
+	// client capabilities [4 bytes]
+	// max packet size [4 bytes]
+	// client character collation [1 byte]
+	// reserved [23 bytes]
+	payload := make([]byte, 4+4+1+23)
 
-	if err != nil {
-		return err
-	}
+	binary.LittleEndian.PutUint32(payload[0:4], uint32(c.clientCapabilities()))
+	binary.LittleEndian.PutUint32(payload[4:8], uint32(MAX_PACKET_SIZE))
+	payload[8] = UTF8MB4_GENERAL_CI
+	binary.LittleEndian.PutUint32(payload[28:32], uint32(c.mariaDBCapabilities))
 
-	// ServerDefaultCollation [1 byte]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.ServerDefaultCollation)
+	err := c.writePacket(payload)
 
 	if err != nil {
 		return err
 	}
 
-	// StatusFlags [2 bytes]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.StatusFlags)
+	config := c.param.TLSConfig
 
-	if err != nil {
-		return err
+	if config.ServerName == "" && config.InsecureSkipVerify == false {
+		config = config.Clone()
+		config.ServerName = c.param.Host
 	}
 
-	// ServerCapabilitiesPart2 (upper 2 bytes) [2 bytes]
-	err = binary.Read(c.reader, binary.LittleEndian, &c.ServerCapabilitiesPart2)
+	// A server may answer the SSL request with an ERR packet, which the TLS
+	// handshake alone would only report as a malformed record.
+	recorder := &recordingConn{Conn: c.conn, recording: true}
+	tlsConn := tls.Client(recorder, config)
+
+	err = tlsConn.HandshakeContext(ctx)
 
 	if err != nil {
+		if serverErr := c.tlsRefusal(recorder); serverErr != nil {
+			return serverErr
+		}
+
 		return err
 	}
 
-	// LenOfScramblePart2 [1 byte]
-	//err = binary.Read(c.reader, binary.LittleEndian, &c.LenOfScramblePart2)
+	recorder.recording = false
+	recorder.received = nil
 
-	//if err != nil {
-	//	return err
-	//}
+	c.conn = tlsConn
+	c.setBuffers()
 
-	//spew.Dump(c.LenOfScramblePart2)
+	return nil
+}
 
-	// PLUGIN_AUTH [1 byte]
-	// Filler [6 bytes]
-	// Filler [4 bytes]
-	IgnoreBytes(c.reader, 1+6+4)
+// recordingConn records what is read from a connection while recording
+// is set.
+type recordingConn struct {
+	net.Conn
+	recording bool
+	received  []byte
+}
 
-	if err != nil {
-		return err
+func (conn *recordingConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+
+	if conn.recording == true {
+		conn.received = append(conn.received, b[:n]...)
 	}
 
-	// ScramblePart2 [12 bytes]
-	// The web documentation is ambiguous about the length.
-	// Reference:
-	// https://github.com/go-sql-driver/mysql/blob/master/packets.go
-	c.ScramblePart2 = make([]byte, 12)
+	return n, err
+}
 
-	err = ReadPacket(c.reader, c.ScramblePart2[0:12])
+// tlsRefusal returns the server error when the server sent an ERR packet
+// instead of the TLS handshake, nil otherwise.
+func (c *Connection) tlsRefusal(conn *recordingConn) error {
+	received := conn.received
 
-	if err != nil {
-		return err
+	if len(received) < 5 || received[3] != c.sequence || received[4] != ERR_PACKET {
+		return nil
 	}
 
-	spew.Printf("=== ScramblePart2\n")
-	spew.Dump(c.ScramblePart2)
-
-	// ScramblePart2 0x00
-	IgnoreBytes(c.reader, 1)
+	payload := make([]byte, int(received[0])|int(received[1])<<8|int(received[2])<<16)
+	n := copy(payload, received[4:])
 
-	// AuthenticationPluginName [null terminated string]
-	c.AuthenticationPluginName, err = c.reader.ReadString('\x00')
+	// The TLS handshake may have stopped reading within the packet.
+	_, err := io.ReadFull(conn.Conn, payload[n:])
 
 	if err != nil {
-		return err
+		return nil
 	}
 
-	spew.Printf("=== AuthenticationPluginName\n")
-	spew.Dump(c.AuthenticationPluginName)
-	spew.Dump([]byte(c.AuthenticationPluginName))
-
-	//
-	return nil
+	return parseErrPacket(payload)
 }
 
-// sendAuth sends the handshake response packet.
-func (c *Connection) sendAuth() error {
-	var err error
-
-	//
+// clientCapabilities returns the capabilities sent in the SSL request and
+// the handshake response.
+func (c *Connection) clientCapabilities() ClientFlags {
 	var clientFlags ClientFlags
 
 	clientFlags = CLIENT_LONG_PASSWORD
@@ -335,6 +1124,49 @@ func (c *Connection) sendAuth() error {
 	clientFlags += CLIENT_MULTI_STATEMENTS
 	clientFlags += CLIENT_MULTI_RESULTS
 
+	if c.param.AllowLocalInfile == true {
+		clientFlags += CLIENT_LOCAL_FILES
+	}
+
+	if c.param.Interactive == true {
+		clientFlags += CLIENT_INTERACTIVE
+	}
+
+	if c.param.TLSConfig != nil {
+		clientFlags += CLIENT_SSL
+	}
+
+	if len(c.param.DBName) > 0 {
+		clientFlags += CLIENT_CONNECT_WITH_DB
+	}
+
+	// Schema changes are then reported in OK packets, see CurrentDatabase.
+	if c.ServerCapabilitiesPart2&uint16(CLIENT_SESSION_TRACK>>16) != 0 {
+		clientFlags += CLIENT_SESSION_TRACK
+	}
+
+	// A MariaDB server reads the extended capabilities of a client which
+	// does not claim to be MySQL.
+	c.mariaDBCapabilities = 0
+
+	if c.ServerCapabilitiesPart1&uint16(CLIENT_LONG_PASSWORD) == 0 {
+		clientFlags -= CLIENT_LONG_PASSWORD
+		c.mariaDBCapabilities = c.ServerMariaDBCapabilities & MARIADB_CLIENT_CAPABILITIES
+
+		if c.param.ProgressFunc != nil {
+			c.mariaDBCapabilities |= c.ServerMariaDBCapabilities & MARIADB_CLIENT_PROGRESS
+		}
+	}
+
+	return clientFlags
+}
+
+// sendAuth sends the handshake response packet.
+func (c *Connection) sendAuth() error {
+	var err error
+
+	clientFlags := c.clientCapabilities()
+
 	// client capabilities [4 bytes]
 	// max packet size [4 bytes]
 	// client character collation [1 byte]
@@ -346,13 +1178,22 @@ func (c *Connection) sendAuth() error {
 	cipher := c.ScramblePart1
 	cipher = append(cipher, c.ScramblePart2...)
 
-	password := scramblePassword(cipher, []byte(c.param.Password))
+	// Servers announcing a longer scramble for other plugins.
+	if len(cipher) > 20 {
+		cipher = cipher[:20]
+	}
+
+	password := scramblePassword(cipher, c.param.Password.b)
+
+	// Neither the password nor the response outlive the handshake in
+	// buffers of the client.
+	c.param.Password.zero()
+	defer zeroBytes(password)
 
 	byteLen := 4 + 4 + 1 + 19 + 4 + (len(c.param.Username) + 1) + (1 + len(password))
 
 	// database name [null terminated string]
 	if n := len(c.param.DBName); n > 0 {
-		clientFlags += CLIENT_CONNECT_WITH_DB
 		byteLen += (n + 1)
 	}
 
@@ -367,10 +1208,11 @@ func (c *Connection) sendAuth() error {
 	byteArr[0] = byte(byteLen)
 	byteArr[1] = byte(byteLen >> 8)
 	byteArr[2] = byte(byteLen >> 16)
-	byteArr[3] = 1 // sequence number
+	byteArr[3] = c.sequence // 1, or 2 after the SSL request
 	pos += 4
 
 	// client capabilities [4 bytes]
+	c.clientFlags = clientFlags
 	binary.LittleEndian.PutUint32(byteArr[pos:pos+4], uint32(clientFlags))
 	pos += 4
 
@@ -379,13 +1221,14 @@ func (c *Connection) sendAuth() error {
 	pos += 4
 
 	// client character collation [1 byte]
-	byteArr[pos] = c.ServerDefaultCollation
+	byteArr[pos] = UTF8MB4_GENERAL_CI
 	pos += 1
 
 	// reserved [19 bytes]
 	pos += 19
 
-	// reserved [4 bytes]
+	// reserved, MariaDB extended capabilities [4 bytes]
+	binary.LittleEndian.PutUint32(byteArr[pos:pos+4], uint32(c.mariaDBCapabilities))
 	pos += 4
 
 	// username [null terminated string]
@@ -405,9 +1248,12 @@ func (c *Connection) sendAuth() error {
 	pos += copy(byteArr[pos:], password)
 
 	// database name [null terminated string]
-	pos += copy(byteArr[pos:], c.param.DBName)
-	byteArr[pos] = 0x00
-	pos += 1
+	// Only present with CLIENT_CONNECT_WITH_DB.
+	if len(c.param.DBName) > 0 {
+		pos += copy(byteArr[pos:], c.param.DBName)
+		byteArr[pos] = 0x00
+		pos += 1
+	}
 
 	// Assume native client during response [null terminated string]
 	pos += copy(byteArr[pos:], "mysql_native_password")
@@ -415,6 +1261,8 @@ func (c *Connection) sendAuth() error {
 	pos += 1
 
 	//
+	defer zeroBytes(byteArr)
+
 	_, err = c.writer.Write(byteArr[0:pos])
 
 	if err != nil {
@@ -428,29 +1276,16 @@ func (c *Connection) sendAuth() error {
 		return err
 	}
 
+	c.sequence++
+
 	return nil
 }
 
+// readResult reads the server's answer to the handshake response, returning
+// the server error, e.g. "Access denied", when authentication failed.
 func (c *Connection) readResult() error {
-	var packetHeader *PacketHeader
-	var err error
-
-	//
-	packetHeader, err = ReadPacketHeader(c.reader)
+	// c.sequence follows the handshake response.
+	_, err := c.readOKPacket()
 
-	if err != nil {
-		return err
-	}
-
-	spew.Dump(packetHeader)
-
-	//
-	test := make([]byte, 1)
-	err = ReadPacket(c.reader, test)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return err
 }
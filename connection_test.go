@@ -1,9 +1,553 @@
 package mysql
 
 import (
-	"fmt"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestNotYet(t *testing.T) {
 }
+
+// newPipeConnection returns a connection past the handshake whose peer is
+// the returned fake server end.
+func newPipeConnection(param ConnectionParameter) (*Connection, net.Conn) {
+	client, server := net.Pipe()
+
+	c := &Connection{
+		param:    param,
+		conn:     client,
+		reader:   bufio.NewReader(client),
+		writer:   bufio.NewWriter(client),
+		mutex:    new(sync.Mutex),
+		debugBuf: new(bytes.Buffer),
+	}
+
+	return c, server
+}
+
+// fakeServer reads and writes raw packets on the server end of a pipe.
+type fakeServer struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+
+	clientFlags ClientFlags // sent in the handshake response
+}
+
+func newFakeServer(t *testing.T, conn net.Conn) *fakeServer {
+	return &fakeServer{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (s *fakeServer) readPacket() (uint8, []byte) {
+	packetHeader, err := ReadPacketHeader(s.reader)
+
+	if err != nil {
+		s.t.Errorf("server read header: %v", err)
+		return 0, nil
+	}
+
+	payload := make([]byte, packetHeader.Len)
+
+	err = ReadPacket(s.reader, payload)
+
+	if err != nil {
+		s.t.Errorf("server read payload: %v", err)
+	}
+
+	return packetHeader.Seq, payload
+}
+
+// readCommand reads the next command, returning nil once the client has
+// closed the connection.
+func (s *fakeServer) readCommand() []byte {
+	packetHeader, err := ReadPacketHeader(s.reader)
+
+	if err != nil {
+		return nil
+	}
+
+	payload := make([]byte, packetHeader.Len)
+
+	if ReadPacket(s.reader, payload) != nil {
+		return nil
+	}
+
+	return payload
+}
+
+func (s *fakeServer) writePacket(seq uint8, payload []byte) {
+	n := len(payload)
+	packet := append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+
+	_, err := s.conn.Write(packet)
+
+	if err != nil {
+		s.t.Errorf("server write: %v", err)
+	}
+}
+
+// okPacket builds an OK packet payload.
+func okPacket(affectedRows uint8, lastInsertID uint8, status uint16, warnings uint16, info string) []byte {
+	payload := []byte{OK_PACKET, affectedRows, lastInsertID, byte(status), byte(status >> 8), byte(warnings), byte(warnings >> 8)}
+	return append(payload, info...)
+}
+
+func TestWriteCommandMaxAllowedPacket(t *testing.T) {
+	c, _ := newPipeConnection(ConnectionParameter{})
+	c.maxAllowedPacket = 16
+
+	err := c.writeCommand(COM_QUERY, []byte("SELECT 'too long for the server'"))
+
+	if e, ok := err.(*PacketTooLargeError); !ok || e.Size != 33 || e.MaxAllowedPacket != 16 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// lenEncString encodes a string shorter than 251 bytes.
+func lenEncString(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+// columnPacket builds a ColumnDefinition41 payload.
+func columnPacket(name string, fieldType FieldType, flags uint16, charset uint16) []byte {
+	var payload []byte
+
+	for _, s := range []string{"def", "test", "t", "t", name, name} {
+		payload = append(payload, lenEncString(s)...)
+	}
+
+	payload = append(payload, 0x0c, byte(charset), byte(charset>>8), 11, 0, 0, 0, byte(fieldType), byte(flags), byte(flags>>8), 0, 0, 0)
+
+	return payload
+}
+
+// textRowPacket builds a text protocol row, nil values being NULL.
+func textRowPacket(values ...interface{}) []byte {
+	var payload []byte
+
+	for _, value := range values {
+		if value == nil {
+			payload = append(payload, 0xfb)
+		} else {
+			payload = append(payload, lenEncString(value.(string))...)
+		}
+	}
+
+	return payload
+}
+
+func eofPacket(status uint16) []byte {
+	return []byte{EOF_PACKET, 0, 0, byte(status), byte(status >> 8)}
+}
+
+// writeResultSet writes a column count, the column definitions, the rows
+// and the closing EOF packet starting at seq, and returns the next seq.
+func (s *fakeServer) writeResultSet(seq uint8, columns [][]byte, rows [][]byte, status uint16) uint8 {
+	s.writePacket(seq, []byte{byte(len(columns))})
+	seq++
+
+	for _, column := range columns {
+		s.writePacket(seq, column)
+		seq++
+	}
+
+	s.writePacket(seq, eofPacket(status))
+	seq++
+
+	for _, row := range rows {
+		s.writePacket(seq, row)
+		seq++
+	}
+
+	s.writePacket(seq, eofPacket(status))
+	seq++
+
+	return seq
+}
+
+func TestReadResultAccessDenied(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.writePacket(2, append([]byte{ERR_PACKET, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied for user 'root'@'localhost'"...))
+	}()
+
+	c.sequence = 2
+
+	err := c.readResult()
+
+	if mysqlErr, ok := err.(*MySQLError); ok == false || mysqlErr.Number != 1045 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOpenRefused(t *testing.T) {
+	param, server := dialPipe(t, ConnectionParameter{})
+
+	// Sent instead of the init packet, without an SQL state.
+	go server.writePacket(0, append([]byte{ERR_PACKET, 0x6a, 0x04}, "Host '10.0.0.7' is not allowed to connect to this MySQL server"...))
+
+	err := NewConnection(param).Open()
+
+	if mysqlErr, ok := err.(*MySQLError); ok == false || mysqlErr.Number != 1130 || mysqlErr.Message != "Host '10.0.0.7' is not allowed to connect to this MySQL server" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOpenContextDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// The server accepts the connection but never sends the init packet.
+	c := NewConnection(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := c.OpenContext(ctx)
+
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("expected a timeout, got %v after %v", err, time.Since(start))
+	}
+}
+
+// initPacket is the initial handshake packet of the fake server.
+func initPacket() []byte {
+	var payload []byte
+
+	payload = append(payload, 10)                    // protocol version
+	payload = append(payload, "5.7.99-fake\x00"...)  // server version
+	payload = append(payload, 7, 0, 0, 0)            // connection id
+	payload = append(payload, "scramble"...)         // scramble part 1
+	payload = append(payload, 0)                     // filler
+	payload = append(payload, 0xff, 0xf7)            // capabilities (lower)
+	payload = append(payload, 8)                     // collation
+	payload = append(payload, 2, 0)                  // status
+	payload = append(payload, 0x7f, 0x81)            // capabilities (upper), no session tracking
+	payload = append(payload, 21)                    // scramble length
+	payload = append(payload, make([]byte, 10)...)   // reserved
+	payload = append(payload, "part2scrambl\x00"...) // scramble part 2
+	payload = append(payload, "mysql_native_password\x00"...)
+
+	return payload
+}
+
+func TestParseInitPacket(t *testing.T) {
+	c := NewConnection(ConnectionParameter{})
+
+	err := c.parseInitPacket(initPacket())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ProtocolVersion != 10 || c.ServerVersion != "5.7.99-fake" || c.ConnectionID != 7 ||
+		string(c.ScramblePart1) != "scramble" || string(c.ScramblePart2) != "part2scrambl" ||
+		c.AuthenticationPluginName != "mysql_native_password" {
+		t.Errorf("unexpected handshake %+v", c)
+	}
+
+	// Every truncation before the end of the scramble is rejected without
+	// panicking; the trailing zero bytes and the plugin name may be cut.
+	scrambleEnd := len(initPacket()) - len("\x00mysql_native_password\x00")
+
+	for n := 0; n < len(initPacket()); n++ {
+		err := c.parseInitPacket(initPacket()[:n])
+
+		if n < scrambleEnd && err != ErrMalformedPacket || n >= scrambleEnd && err != nil {
+			t.Errorf("truncated at %d: got %v", n, err)
+		}
+	}
+
+	// The plugin name of MySQL 5.5.7 is not zero terminated.
+	if c.parseInitPacket(initPacket()[:len(initPacket())-1]) != nil || c.AuthenticationPluginName != "mysql_native_password" {
+		t.Errorf("unterminated plugin name %q", c.AuthenticationPluginName)
+	}
+
+	// A server without CLIENT_PLUGIN_AUTH and a zero scramble length.
+	payload := initPacket()[:scrambleEnd+1]
+	payload[len("\x0a5.7.99-fake\x00")+4+8+1+2+1+2+1] &^= byte(CLIENT_PLUGIN_AUTH >> 24)
+	payload[len("\x0a5.7.99-fake\x00")+4+8+1+2+1+2+2] = 0
+
+	if c.parseInitPacket(payload) != nil || string(c.ScramblePart2) != "part2scrambl" || c.AuthenticationPluginName != "" {
+		t.Errorf("unexpected handshake %+v", c)
+	}
+
+	// A 4.0 server ending the packet after its capabilities.
+	payload = initPacket()[:len("\x0a5.7.99-fake\x00")+4+8+1]
+	payload = append(payload, 0x2c, 0x02)
+
+	if c.parseInitPacket(payload) != ErrUnsupportedServer {
+		t.Error("accepted a 4.0 server")
+	}
+
+	// A HandshakeV9 of MySQL 3.20.
+	payload = append([]byte{9}, "3.20.32a\x00"...)
+	payload = append(payload, 1, 0, 0, 0)
+	payload = append(payload, "scramble\x00"...)
+
+	err = c.parseInitPacket(payload)
+
+	if versionErr, ok := err.(*ProtocolVersionError); ok == false || versionErr.Version != 9 || errors.Is(err, ErrUnsupportedServer) == false {
+		t.Errorf("protocol version 9: got %v", err)
+	}
+}
+
+// handshake plays the server side of Open: init packet, OK to the auth
+// response and the session variables query.
+func (s *fakeServer) handshake() {
+	s.writePacket(0, initPacket())
+	s.authenticate(1)
+}
+
+// authenticate plays the rest of the handshake from the auth response,
+// expected with sequence seq.
+func (s *fakeServer) authenticate(seq uint8) {
+	responseSeq, payload := s.readPacket()
+
+	if responseSeq != seq || len(payload) < 32 {
+		s.t.Errorf("unexpected handshake response %d %q", responseSeq, payload)
+		return
+	}
+
+	s.clientFlags = ClientFlags(UnpackNumber(payload, 4))
+
+	s.writePacket(seq+1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+	_, payload = s.readPacket()
+
+	if string(payload[1:]) != SESSION_VARIABLES_QUERY {
+		s.t.Errorf("unexpected session query %q", payload)
+	}
+
+	s.writeResultSet(1, [][]byte{
+		columnPacket("@@max_allowed_packet", MYSQL_TYPE_LONGLONG, 0, 63),
+		columnPacket("@@sql_mode", MYSQL_TYPE_VAR_STRING, 0, 45),
+		columnPacket("@@wait_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+		columnPacket("@@interactive_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+	}, [][]byte{
+		textRowPacket("4194304", "STRICT_TRANS_TABLES", "28800", "28800"),
+	}, SERVER_STATUS_AUTOCOMMIT)
+}
+
+func TestOpenHandshakeTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := NewConnection(ConnectionParameter{
+		Network:          "tcp",
+		HandshakeTimeout: 50 * time.Millisecond,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	// No deadline on the context: only HandshakeTimeout can end the wait.
+	err := c.Open()
+
+	if err != ErrHandshakeTimeout {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOpenConnectTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	c := NewConnection(ConnectionParameter{
+		Network:          "tcp",
+		ConnectTimeout:   50 * time.Millisecond,
+		HandshakeTimeout: 10 * time.Second,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	start := time.Now()
+
+	err := c.Open()
+
+	if errors.Is(err, ErrConnectTimeout) == false || time.Since(start) > 5*time.Second {
+		t.Errorf("unexpected error %v after %v", err, time.Since(start))
+	}
+
+	// The budget also covers the delays between attempts.
+	c = NewConnection(ConnectionParameter{
+		Network:        "tcp",
+		ConnectTimeout: 50 * time.Millisecond,
+		ConnectRetry:   RetryPolicy{Attempts: 10, BaseDelay: time.Second},
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return nil, syscall.ECONNREFUSED
+		},
+	})
+
+	start = time.Now()
+
+	err = c.Open()
+
+	// The error of the last attempt is kept.
+	if errors.Is(err, ErrConnectTimeout) == false || strings.Contains(err.Error(), syscall.ECONNREFUSED.Error()) == false || time.Since(start) > 5*time.Second {
+		t.Errorf("retry: unexpected error %v after %v", err, time.Since(start))
+	}
+}
+
+func TestOpenInteractive(t *testing.T) {
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+
+	c := NewConnection(ConnectionParameter{
+		Network:     "tcp",
+		Interactive: true,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	go server.handshake()
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.clientFlags&CLIENT_INTERACTIVE == 0 {
+		t.Errorf("CLIENT_INTERACTIVE not set")
+	}
+
+	if c.WaitTimeout() != 8*time.Hour || c.InteractiveTimeout() != 8*time.Hour {
+		t.Errorf("unexpected timeouts %v %v", c.WaitTimeout(), c.InteractiveTimeout())
+	}
+}
+
+// countingConn counts the bytes read through it.
+type countingConn struct {
+	net.Conn
+	read int
+}
+
+func (conn *countingConn) Read(p []byte) (int, error) {
+	n, err := conn.Conn.Read(p)
+	conn.read += n
+
+	return n, err
+}
+
+func TestOpenBeforeHandshake(t *testing.T) {
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+	var wrapped *countingConn
+
+	c := NewConnection(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+		BeforeHandshake: func(conn net.Conn) (net.Conn, error) {
+			wrapped = &countingConn{Conn: conn}
+			return wrapped, nil
+		},
+	})
+
+	go server.handshake()
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if wrapped == nil || wrapped.read == 0 {
+		t.Error("the handshake did not go through the wrapped connection")
+	}
+
+	// A failing hook closes the dialed connection.
+	client, serverConn = net.Pipe()
+	refused := errors.New("refused")
+
+	c = NewConnection(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+		BeforeHandshake: func(conn net.Conn) (net.Conn, error) {
+			return nil, refused
+		},
+	})
+
+	if err := c.Open(); err != refused {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := serverConn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("the connection was not closed: %v", err)
+	}
+}
+
+func TestMariaDBCapabilities(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{Username: "u"})
+	server := newFakeServer(t, serverConn)
+
+	// MariaDB clears CLIENT_MYSQL and sends its extended capabilities in
+	// the last 4 reserved bytes.
+	payload := initPacket()
+	capabilities := len("\x0a5.7.99-fake\x00") + 4 + 8 + 1
+	payload[capabilities] &^= byte(CLIENT_LONG_PASSWORD)
+	payload[capabilities+2+1+2+2+1+6] = byte(MARIADB_CLIENT_PROGRESS | MARIADB_CLIENT_EXTENDED_METADATA | MARIADB_CLIENT_CACHE_METADATA)
+
+	if err := c.parseInitPacket(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ServerMariaDBCapabilities != MARIADB_CLIENT_PROGRESS|MARIADB_CLIENT_EXTENDED_METADATA|MARIADB_CLIENT_CACHE_METADATA {
+		t.Errorf("unexpected server capabilities %x", c.ServerMariaDBCapabilities)
+	}
+
+	go func() {
+		_, payload := server.readPacket()
+
+		if ClientFlags(UnpackNumber(payload, 4))&CLIENT_LONG_PASSWORD != 0 ||
+			ClientFlags(UnpackNumber(payload[4+4+1+19:], 4)) != MARIADB_CLIENT_EXTENDED_METADATA|MARIADB_CLIENT_CACHE_METADATA {
+			t.Errorf("unexpected handshake response %q", payload)
+		}
+
+		server.readCommand()
+		server.writePacket(1, []byte{1})
+
+		// The extended type info follows org_name: format "json".
+		column := columnPacket("doc", MYSQL_TYPE_BLOB, BLOB_FLAG, 45)
+		column = append(append(column[:len(column)-13:len(column)-13], 0x06, 1, 4, 'j', 's', 'o', 'n'), column[len(column)-13:]...)
+
+		server.writePacket(2, column)
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, eofPacket(0))
+	}()
+
+	if err := c.sendAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := c.Query("SELECT doc FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if columns := rows.Columns(); columns[0].Name != "doc" || columns[0].ExtendedFormat != "json" || columns[0].DatabaseTypeName() != "JSON" {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	rows.Close()
+}
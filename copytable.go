@@ -0,0 +1,291 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_COPY_CHUNK_ROWS = 1000
+)
+
+var (
+	ErrCopySameConnection = errors.New("Table copied from a connection to itself")
+)
+
+// CopyOptions selects what CopyTable copies and how.
+type CopyOptions struct {
+	Columns  []string // optional, defaults to all columns in table order
+	Where    string   // optional condition on the rows of the source table
+	DstTable string   // defaults to the source table
+
+	// ChunkRows is the number of rows per INSERT or LOAD DATA; an INSERT
+	// is also sent before it exceeds the max_allowed_packet of the
+	// destination.
+	ChunkRows int
+
+	// LoadData sends the chunks with LOAD DATA LOCAL INFILE, which is
+	// faster than INSERT. The destination must be opened with
+	// AllowLocalInfile and have local_infile enabled. The values are sent
+	// as utf8mb4, which binary columns holding invalid UTF-8 may not
+	// survive in strict mode.
+	LoadData bool
+
+	// Pause is waited between chunks, e.g. to let the replicas of the
+	// destination keep up. Set ReadThrottle or WriteThrottle on the
+	// connections to limit their bandwidth instead.
+	Pause time.Duration
+
+	// Progress is called after every chunk with the number of rows copied
+	// so far.
+	Progress func(rows int64)
+}
+
+// CopyTable streams the rows of table from src into the same table, or
+// opts.DstTable, on dst by chunks, and returns the number of rows copied.
+// The tables are those of the current database of each connection, and the
+// destination table must exist.
+//
+// Under autocommit every chunk is committed as it is sent, so an error
+// leaves the rows already copied in the destination; the unread rows of the
+// source are then discarded with Rows.Close, see KillOnClose.
+func CopyTable(ctx context.Context, src *Connection, dst *Connection, table string, opts CopyOptions) (int64, error) {
+	if src == dst {
+		return 0, ErrCopySameConnection
+	}
+
+	if opts.ChunkRows <= 0 {
+		opts.ChunkRows = DEFAULT_COPY_CHUNK_ROWS
+	}
+
+	if opts.DstTable == "" {
+		opts.DstTable = table
+	}
+
+	columns := "*"
+
+	if len(opts.Columns) > 0 {
+		columns = quoteIdentifiers(opts.Columns)
+	}
+
+	query := "SELECT " + columns + " FROM " + QuoteIdentifier(table)
+
+	if opts.Where != "" {
+		query += " WHERE " + opts.Where
+	}
+
+	rows, err := src.QueryContext(ctx, query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := &copyChunk{dst: dst, table: opts.DstTable, loadData: opts.LoadData, columns: rows.Columns()}
+	copied := int64(0)
+
+	for rows.Next() == true {
+		if chunk.rows > 0 && (chunk.rows == opts.ChunkRows || chunk.full() == true) {
+			err = chunk.flush(ctx)
+
+			if err == nil {
+				copied += int64(chunk.flushed)
+				err = copyProgress(ctx, &opts, copied)
+			}
+
+			if err != nil {
+				rows.Close()
+				return copied, err
+			}
+		}
+
+		chunk.add(rows.Values())
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return copied, err
+	}
+
+	if chunk.rows > 0 {
+		err = chunk.flush(ctx)
+
+		if err != nil {
+			return copied, err
+		}
+
+		copied += int64(chunk.flushed)
+
+		if opts.Progress != nil {
+			opts.Progress(copied)
+		}
+	}
+
+	return copied, nil
+}
+
+// copyProgress reports the rows copied and pauses before the next chunk.
+func copyProgress(ctx context.Context, opts *CopyOptions, copied int64) error {
+	if opts.Progress != nil {
+		opts.Progress(copied)
+	}
+
+	if opts.Pause <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(opts.Pause)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// copyChunk buffers the rows of the next INSERT or LOAD DATA of CopyTable.
+// The values are copied out of the read buffer of the source as they are
+// added.
+type copyChunk struct {
+	dst      *Connection
+	table    string
+	loadData bool
+	columns  []Column
+
+	buf     bytes.Buffer
+	rows    int
+	flushed int
+}
+
+// full reports whether another row might take the INSERT beyond the
+// max_allowed_packet of the destination.
+func (ch *copyChunk) full() bool {
+	return ch.loadData == false && ch.dst.MaxAllowedPacket() > 0 && ch.buf.Len() > ch.dst.MaxAllowedPacket()/2
+}
+
+func (ch *copyChunk) add(values [][]byte) {
+	if ch.loadData == true {
+		writeCSVRow(&ch.buf, values)
+	} else {
+		if ch.rows > 0 {
+			ch.buf.WriteString(", ")
+		}
+
+		ch.buf.WriteByte('(')
+
+		for i, value := range values {
+			if i > 0 {
+				ch.buf.WriteString(", ")
+			}
+
+			ch.buf.WriteString(ch.dst.copyLiteral(&ch.columns[i], value))
+		}
+
+		ch.buf.WriteByte(')')
+	}
+
+	ch.rows++
+}
+
+// flush sends the rows buffered to the destination.
+func (ch *copyChunk) flush(ctx context.Context) error {
+	var err error
+
+	names := make([]string, len(ch.columns))
+
+	for i := range ch.columns {
+		names[i] = ch.columns[i].Name
+	}
+
+	if ch.loadData == true {
+		if err = ctx.Err(); err == nil {
+			_, err = ch.dst.ImportCSV(bytes.NewReader(ch.buf.Bytes()), CSVImport{
+				Table:        ch.table,
+				Columns:      names,
+				CharacterSet: "utf8mb4",
+			})
+		}
+	} else {
+		var rows *Rows
+
+		statement := "INSERT INTO " + QuoteIdentifier(ch.table) + " (" + quoteIdentifiers(names) + ") VALUES " + ch.buf.String()
+
+		rows, err = ch.dst.QueryContext(ctx, statement)
+
+		if err == nil {
+			err = rows.Close()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ch.flushed = ch.rows
+	ch.rows = 0
+	ch.buf.Reset()
+
+	return nil
+}
+
+// copyLiteral formats a text protocol value of column as an SQL literal for
+// the connection.
+func (c *Connection) copyLiteral(column *Column, value []byte) string {
+	switch {
+	case value == nil:
+		return "NULL"
+	case column.Type.IsNumeric() == true || column.Type == MYSQL_TYPE_YEAR:
+		return string(value)
+	case len(value) > 0 && (column.Type == MYSQL_TYPE_BIT || column.CharacterSet == BINARY_COLLATION && (column.Type.IsString() == true || column.Type == MYSQL_TYPE_GEOMETRY)):
+		return "0x" + hex.EncodeToString(value)
+	}
+
+	return c.QuoteString(string(value))
+}
+
+// writeCSVRow writes a row the way ImportCSV reads it back. Every value is
+// enclosed so that a string "NULL" stays a string, while NULL itself is an
+// unquoted NULL.
+func writeCSVRow(buf *bytes.Buffer, values [][]byte) {
+	for i, value := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if value == nil {
+			buf.WriteString("NULL")
+			continue
+		}
+
+		buf.WriteByte('"')
+
+		for _, b := range value {
+			if b == '"' {
+				buf.WriteByte('"')
+			}
+
+			buf.WriteByte(b)
+		}
+
+		buf.WriteByte('"')
+	}
+
+	buf.WriteByte('\n')
+}
+
+// quoteIdentifiers quotes names as a comma separated list of columns.
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+
+	for i, name := range names {
+		quoted[i] = QuoteIdentifier(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// serveCopySource answers the SELECT of CopyTable with three rows.
+func serveCopySource(t *testing.T, server *fakeServer, query string) {
+	payload := server.readCommand()
+
+	if string(payload[1:]) != query {
+		t.Errorf("unexpected query %q", payload[1:])
+	}
+
+	server.writeResultSet(1, [][]byte{
+		columnPacket("id", MYSQL_TYPE_LONG, 0, 63),
+		columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+		columnPacket("data", MYSQL_TYPE_BLOB, 0, BINARY_COLLATION),
+	}, [][]byte{
+		textRowPacket("1", "Taipei", "\x00\xff"),
+		textRowPacket("2", `"Tai'nan"`, nil),
+		textRowPacket("3", "NULL", ""),
+	}, 0)
+}
+
+func TestCopyTable(t *testing.T) {
+	src, srcConn := newPipeConnection(ConnectionParameter{})
+	dst, dstConn := newPipeConnection(ConnectionParameter{})
+	srcServer := newFakeServer(t, srcConn)
+	dstServer := newFakeServer(t, dstConn)
+
+	go serveCopySource(t, srcServer, "SELECT `id`, `name`, `data` FROM `city` WHERE id > 0")
+
+	expected := []string{
+		"INSERT INTO `city_copy` (`id`, `name`, `data`) VALUES (1, 'Taipei', 0x00ff), (2, '\\\"Tai\\'nan\\\"', NULL)",
+		"INSERT INTO `city_copy` (`id`, `name`, `data`) VALUES (3, 'NULL', '')",
+	}
+
+	go func() {
+		for _, statement := range expected {
+			payload := dstServer.readCommand()
+
+			if string(payload[1:]) != statement {
+				t.Errorf("statement:\n got %s\nwant %s", payload[1:], statement)
+			}
+
+			dstServer.writePacket(1, okPacket(2, 0, 0, 0, ""))
+		}
+	}()
+
+	var progress []int64
+
+	copied, err := CopyTable(context.Background(), src, dst, "city", CopyOptions{
+		Columns:   []string{"id", "name", "data"},
+		Where:     "id > 0",
+		DstTable:  "city_copy",
+		ChunkRows: 2,
+		Progress: func(rows int64) {
+			progress = append(progress, rows)
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if copied != 3 || len(progress) != 2 || progress[1] != 3 {
+		t.Errorf("copied %d rows, progress %v", copied, progress)
+	}
+}
+
+func TestCopyTableLoadData(t *testing.T) {
+	src, srcConn := newPipeConnection(ConnectionParameter{})
+	dst, dstConn := newPipeConnection(ConnectionParameter{AllowLocalInfile: true})
+	srcServer := newFakeServer(t, srcConn)
+	dstServer := newFakeServer(t, dstConn)
+
+	go serveCopySource(t, srcServer, "SELECT * FROM `city`")
+
+	received := new(bytes.Buffer)
+
+	go func() {
+		payload := dstServer.readCommand()
+
+		statement := "LOAD DATA LOCAL INFILE 'csv::city' INTO TABLE `city` CHARACTER SET utf8mb4" +
+			" FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '\\\"' ESCAPED BY ''" +
+			" LINES TERMINATED BY '\\n' (`id`, `name`, `data`)"
+
+		if string(payload[1:]) != statement {
+			t.Errorf("statement:\n got %s\nwant %s", payload[1:], statement)
+		}
+
+		dstServer.writePacket(1, append([]byte{LOCAL_INFILE_PACKET}, "csv::city"...))
+
+		seq := uint8(2)
+
+		for ; ; seq++ {
+			_, payload := dstServer.readPacket()
+
+			if len(payload) == 0 {
+				break
+			}
+
+			received.Write(payload)
+		}
+
+		dstServer.writePacket(seq+1, okPacket(3, 0, 0, 0, ""))
+	}()
+
+	copied, err := CopyTable(context.Background(), src, dst, "city", CopyOptions{LoadData: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "\"1\",\"Taipei\",\"\x00\xff\"\n\"2\",\"\"\"Tai'nan\"\"\",NULL\n\"3\",\"NULL\",\"\"\n"
+
+	if copied != 3 || received.String() != csv {
+		t.Errorf("copied %d rows:\n got %q\nwant %q", copied, received, csv)
+	}
+}
+
+func TestCopyTableSameConnection(t *testing.T) {
+	c, _ := newPipeConnection(ConnectionParameter{})
+
+	if _, err := CopyTable(context.Background(), c, c, "city", CopyOptions{}); err != ErrCopySameConnection {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,593 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrArgumentCount = errors.New("Wrong number of arguments")
+	ErrArgumentType  = errors.New("Unsupported argument type")
+	ErrNamedArgument = errors.New("Named arguments are not supported")
+)
+
+func init() {
+	sql.Register("mysql-pure", &Driver{})
+}
+
+// Driver implements database/sql/driver.Driver and DriverContext for DSNs
+// understood by ParseDSN.
+type Driver struct{}
+
+// Open opens a new connection for the DSN.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(context.Background())
+}
+
+// OpenConnector parses the DSN once for all the connections of a sql.DB.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	param, err := ParseDSN(dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnector(param), nil
+}
+
+// Connector implements driver.Connector so that sql.OpenDB can be used with
+// a ConnectionParameter, including its custom Dial, without any DSN.
+type Connector struct {
+	// generation counts the failovers seen by the connections, see
+	// IsFailover. First for the alignment of atomic operations.
+	generation uint64
+
+	param ConnectionParameter
+}
+
+// NewConnector returns a connector for sql.OpenDB:
+//
+//	db := sql.OpenDB(mysql.NewConnector(param))
+func NewConnector(param ConnectionParameter) *Connector {
+	return &Connector{param: param}
+}
+
+// Connect opens a new connection.
+func (ct *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	c := NewConnection(ct.param)
+
+	err := c.OpenContext(ctx)
+
+	if err != nil {
+		if c.conn != nil {
+			c.Close()
+		}
+
+		return nil, err
+	}
+
+	return &driverConn{c: c, connector: ct, generation: atomic.LoadUint64(&ct.generation)}, nil
+}
+
+// Driver returns the driver of the connector.
+func (ct *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+// driverConn adapts a Connection to driver.Conn. Statements are prepared on
+// the client side by interpolating the arguments.
+type driverConn struct {
+	c *Connection
+
+	// connector opened the connection in its failover generation.
+	connector  *Connector
+	generation uint64
+
+	// bad is set once a network or protocol error left the connection in
+	// an unknown state. Server errors (ERR packets) keep it usable.
+	bad bool
+
+	// noResetConnection is set when the server rejected
+	// COM_RESET_CONNECTION, so sessions are only pinged from then on.
+	noResetConnection bool
+}
+
+// check records whether err left the connection unusable. A command that
+// failed on a bad connection before reaching the server is reported as
+// driver.ErrBadConn so database/sql retries it on another connection;
+// otherwise it might have run and the error is returned as is.
+func (dc *driverConn) check(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err.(type) {
+	case *MySQLError:
+		if isFatalServerError(err) == true {
+			dc.bad = true
+		}
+	case *PacketTooLargeError:
+	default:
+		// A cancelled statement leaves the connection usable unless it
+		// is broken, see QueryContext.
+		if err != ErrArgumentCount && err != ErrArgumentType && err != ErrNamedArgument &&
+			err != context.Canceled && err != context.DeadlineExceeded {
+			dc.bad = true
+		}
+	}
+
+	if dc.c.broken == true {
+		dc.bad = true
+	}
+
+	if IsFailover(err) == true {
+		dc.bad = true
+
+		if dc.connector != nil {
+			dc.connector.failover(dc.generation, err)
+		}
+	}
+
+	if dc.bad == true && dc.c.unsent == true {
+		return driver.ErrBadConn
+	}
+
+	return err
+}
+
+// IsValid implements driver.Validator so database/sql discards connections
+// which broke while in use instead of putting them back into the pool,
+// including those poisoned by a panic which skipped check and those opened
+// before a failover.
+func (dc *driverConn) IsValid() bool {
+	return dc.bad == false && dc.c.broken == false && dc.stale() == false
+}
+
+// ResetSession implements driver.SessionResetter. It clears the session
+// state left by the previous user with COM_RESET_CONNECTION, falling back
+// to a COM_PING on servers without it. The session variables restored by
+// the reset are read back in the same round trip.
+//
+// Behind vtgate the reset also gives back the reserved connection a
+// session holds once it changed system variables, created temporary
+// tables or took locks. A vtgate without COM_RESET_CONNECTION cannot
+// release it: the connection is discarded instead of handing that state
+// to the next user.
+//
+// Connections opened before a failover are discarded as well.
+func (dc *driverConn) ResetSession(ctx context.Context) error {
+	if dc.bad == true || dc.stale() == true {
+		return driver.ErrBadConn
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if dc.noResetConnection == false {
+		err := dc.c.resetSession()
+
+		if mysqlErr, ok := err.(*MySQLError); ok && mysqlErr.Number == ER_UNKNOWN_COM_ERROR && dc.c.IsVitess() == true {
+			dc.bad = true
+			return driver.ErrBadConn
+		} else if ok && mysqlErr.Number == ER_UNKNOWN_COM_ERROR {
+			dc.noResetConnection = true
+		} else if dc.check(err) != nil {
+			return driver.ErrBadConn
+		} else {
+			return nil
+		}
+	}
+
+	if dc.check(dc.c.Ping()) != nil {
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+// Ping implements driver.Pinger.
+func (dc *driverConn) Ping(ctx context.Context) error {
+	if dc.bad == true {
+		return driver.ErrBadConn
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return dc.check(dc.c.Ping())
+}
+
+// CheckNamedValue implements driver.NamedValueChecker. Only positional
+// arguments are supported. Values are converted by the default converter,
+// except for unsigned integers which are kept to allow values beyond the
+// int64 range.
+func (dc *driverConn) CheckNamedValue(nv *driver.NamedValue) error {
+	var err error
+
+	if nv.Name != "" {
+		return ErrNamedArgument
+	}
+
+	switch v := nv.Value.(type) {
+	case uint64:
+		return nil
+	case uint:
+		nv.Value = uint64(v)
+		return nil
+	}
+
+	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+
+	return err
+}
+
+func (dc *driverConn) Prepare(query string) (driver.Stmt, error) {
+	return &driverStmt{dc: dc, query: query, numInput: len(findPlaceholders(query, dc.c.NoBackslashEscapes()))}, nil
+}
+
+func (dc *driverConn) Close() error {
+	return dc.c.Close()
+}
+
+func (dc *driverConn) Begin() (driver.Tx, error) {
+	_, err := dc.exec(context.Background(), "START TRANSACTION")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &driverTx{dc: dc}, nil
+}
+
+func (dc *driverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if dc.c.param.Firewall != nil {
+		if err := dc.c.param.Firewall.Check(query); err != nil {
+			return nil, err
+		}
+	}
+
+	query, err := dc.interpolate(query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dc.exec(ctx, dc.comment(ctx, query))
+}
+
+func (dc *driverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if dc.c.param.Firewall != nil {
+		if err := dc.c.param.Firewall.Check(query); err != nil {
+			return nil, err
+		}
+	}
+
+	query, err := dc.interpolate(query, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := dc.c.QueryContext(ctx, dc.comment(ctx, query))
+
+	if err != nil {
+		return nil, dc.check(err)
+	}
+
+	abandon, _ := ctx.Value(abandonOnCloseKey{}).(bool)
+
+	return &driverRows{dc: dc, rows: rows, abandon: abandon}, nil
+}
+
+// exec runs a statement and returns the result of its OK packet.
+func (dc *driverConn) exec(ctx context.Context, query string) (driver.Result, error) {
+	result, err := dc.c.ExecContext(ctx, query)
+
+	if err != nil {
+		return nil, dc.check(err)
+	}
+
+	if result == nil {
+		return driver.ResultNoRows, nil
+	}
+
+	return &driverResult{result: result}, nil
+}
+
+// comment appends the SQLComment tags of ctx to query.
+func (dc *driverConn) comment(ctx context.Context, query string) string {
+	if dc.c.param.SQLComment == nil {
+		return query
+	}
+
+	return AppendSQLComment(query, dc.c.param.SQLComment(ctx))
+}
+
+// interpolate replaces every '?' placeholder with the literal of the
+// corresponding argument, quoted for the sql_mode of the connection.
+func (dc *driverConn) interpolate(query string, args []driver.NamedValue) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	placeholders := findPlaceholders(query, dc.c.NoBackslashEscapes())
+
+	if len(placeholders) != len(args) {
+		return "", ErrArgumentCount
+	}
+
+	var buf strings.Builder
+	pos := 0
+
+	for i, placeholder := range placeholders {
+		literal, err := dc.literal(args[i].Value)
+
+		if err != nil {
+			return "", err
+		}
+
+		buf.WriteString(query[pos:placeholder])
+		buf.WriteString(literal)
+		pos = placeholder + 1
+	}
+
+	buf.WriteString(query[pos:])
+
+	return buf.String(), nil
+}
+
+// findPlaceholders returns the offsets of the '?' placeholders in query,
+// skipping those inside string literals, quoted identifiers and comments.
+// Executable comments (/*! ... */) are scanned like the rest of the query.
+func findPlaceholders(query string, noBackslashEscapes bool) []int {
+	var placeholders []int
+
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; ch {
+		case '?':
+			placeholders = append(placeholders, i)
+		case '\'', '"', '`':
+			// A doubled quote closes the quoted part and opens the next.
+			for i++; i < len(query) && query[i] != ch; i++ {
+				if query[i] == '\\' && ch != '`' && noBackslashEscapes == false {
+					i++
+				}
+			}
+		case '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case '-':
+			// "-- " only starts a comment when followed by a space or a
+			// control character.
+			if i+1 < len(query) && query[i+1] == '-' && (i+2 == len(query) || query[i+2] <= ' ') {
+				for i < len(query) && query[i] != '\n' {
+					i++
+				}
+			}
+		case '/':
+			if i+2 < len(query) && query[i+1] == '*' && query[i+2] != '!' {
+				end := strings.Index(query[i+2:], "*/")
+
+				if end < 0 {
+					return placeholders
+				}
+
+				i += 2 + end + 1
+			}
+		}
+	}
+
+	return placeholders
+}
+
+// literal formats a driver.Value as an SQL literal.
+func (dc *driverConn) literal(value driver.Value) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		if v == true {
+			return "1", nil
+		}
+
+		return "0", nil
+	case []byte:
+		if v == nil {
+			return "NULL", nil
+		}
+
+		// Hex literals do not depend on the escaping rules of sql_mode.
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case string:
+		return dc.c.QuoteString(v), nil
+	case time.Time:
+		if v.IsZero() == true {
+			return "'0000-00-00'", nil
+		}
+
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'", nil
+	}
+
+	return "", ErrArgumentType
+}
+
+// driverStmt is a client side prepared statement.
+type driverStmt struct {
+	dc       *driverConn
+	query    string
+	numInput int // placeholders counted when preparing
+}
+
+func (ds *driverStmt) Close() error {
+	return nil
+}
+
+// NumInput returns the number of placeholders, so that database/sql checks
+// the number of arguments before anything is sent. A client side prepared
+// statement has no parameter types to report.
+func (ds *driverStmt) NumInput() int {
+	return ds.numInput
+}
+
+func (ds *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return ds.dc.ExecContext(context.Background(), ds.query, namedValues(args))
+}
+
+func (ds *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return ds.dc.QueryContext(context.Background(), ds.query, namedValues(args))
+}
+
+func (ds *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return ds.dc.ExecContext(ctx, ds.query, args)
+}
+
+func (ds *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return ds.dc.QueryContext(ctx, ds.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	return named
+}
+
+type driverTx struct {
+	dc *driverConn
+}
+
+func (dt *driverTx) Commit() error {
+	_, err := dt.dc.exec(context.Background(), "COMMIT")
+	return err
+}
+
+func (dt *driverTx) Rollback() error {
+	_, err := dt.dc.exec(context.Background(), "ROLLBACK")
+	return err
+}
+
+type driverResult struct {
+	result *Result
+}
+
+func (dr *driverResult) LastInsertId() (int64, error) {
+	return int64(dr.result.LastInsertId()), nil
+}
+
+func (dr *driverResult) RowsAffected() (int64, error) {
+	return int64(dr.result.AffectedRows()), nil
+}
+
+// driverRows adapts Rows to driver.Rows. Values are returned as []byte,
+// which database/sql converts when scanning.
+type driverRows struct {
+	dc      *driverConn
+	rows    *Rows
+	abandon bool // see AbandonOnClose
+}
+
+type abandonOnCloseKey struct{}
+
+// AbandonOnClose returns a context whose queries, when their sql.Rows are
+// closed before the end, abandon the rest of the rows (see Rows.Abandon)
+// instead of draining them. database/sql then closes the connection and
+// opens another one when needed.
+func AbandonOnClose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, abandonOnCloseKey{}, true)
+}
+
+func (dr *driverRows) Columns() []string {
+	columns := dr.rows.Columns()
+	names := make([]string, len(columns))
+
+	for i := range columns {
+		names[i] = columns[i].Name
+	}
+
+	return names
+}
+
+func (dr *driverRows) Close() error {
+	if dr.abandon == true && dr.rows.released == false {
+		dr.rows.Abandon()
+		dr.dc.bad = true
+		return nil
+	}
+
+	return dr.dc.check(dr.rows.Close())
+}
+
+func (dr *driverRows) Next(dest []driver.Value) error {
+	if dr.rows.Next() == false {
+		if err := dr.rows.Err(); err != nil {
+			return dr.dc.check(err)
+		}
+
+		return io.EOF
+	}
+
+	for i, value := range dr.rows.Values() {
+		if value == nil {
+			dest[i] = nil
+		} else {
+			dest[i] = value
+		}
+	}
+
+	return nil
+}
+
+func (dr *driverRows) ColumnTypeDatabaseTypeName(index int) string {
+	return dr.rows.Columns()[index].DatabaseTypeName()
+}
+
+func (dr *driverRows) ColumnTypeNullable(index int) (bool, bool) {
+	return dr.rows.Columns()[index].Nullable(), true
+}
+
+func (dr *driverRows) ColumnTypeLength(index int) (int64, bool) {
+	return dr.rows.Columns()[index].Length()
+}
+
+func (dr *driverRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	return dr.rows.Columns()[index].PrecisionScale()
+}
+
+func (dr *driverRows) ColumnTypeScanType(index int) reflect.Type {
+	return dr.rows.Columns()[index].ScanType()
+}
@@ -0,0 +1,345 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	dc := &driverConn{c: new(Connection)}
+
+	query, err := dc.interpolate("INSERT INTO t VALUES (?, ?, ?, ?, ?, ?, ?)", namedValues([]driver.Value{
+		int64(-1), uint64(math.MaxUint64), 1.5, true, []byte{0x00, 0xff}, "it's", time.Date(2016, 1, 2, 3, 4, 5, 6000, time.UTC),
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `INSERT INTO t VALUES (-1, 18446744073709551615, 1.5, 1, X'00ff', 'it\'s', '2016-01-02 03:04:05.000006')`
+
+	if query != expected {
+		t.Errorf("got %s\nwant %s", query, expected)
+	}
+
+	_, err = dc.interpolate("SELECT ?", namedValues([]driver.Value{int64(1), int64(2)}))
+
+	if err != ErrArgumentCount {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCheckNamedValue(t *testing.T) {
+	dc := &driverConn{c: new(Connection)}
+
+	nv := &driver.NamedValue{Ordinal: 1, Value: uint(math.MaxUint64)}
+
+	if err := dc.CheckNamedValue(nv); err != nil || nv.Value != uint64(math.MaxUint64) {
+		t.Errorf("uint: %v %v", nv.Value, err)
+	}
+
+	nv = &driver.NamedValue{Ordinal: 1, Value: int32(7)}
+
+	if err := dc.CheckNamedValue(nv); err != nil || nv.Value != int64(7) {
+		t.Errorf("int32: %v %v", nv.Value, err)
+	}
+
+	nv = &driver.NamedValue{Name: "id", Ordinal: 1, Value: int64(7)}
+
+	if err := dc.CheckNamedValue(nv); err != ErrNamedArgument {
+		t.Errorf("named: %v", err)
+	}
+}
+
+func TestDriverConnIsValid(t *testing.T) {
+	dc := &driverConn{c: new(Connection)}
+
+	dc.check(&MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	if dc.IsValid() == false {
+		t.Errorf("a server error must not invalidate the connection")
+	}
+
+	dc.check(ErrMalformedPacket)
+
+	if dc.IsValid() == true {
+		t.Errorf("a protocol error must invalidate the connection")
+	}
+}
+
+func TestDriverConnBadConn(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+	dc := &driverConn{c: c}
+
+	go func() {
+		// The server had timed the idle connection out before the command
+		// arrived, so its goodbye carries sequence number zero.
+		server.readPacket()
+		server.writePacket(0, append([]byte{ERR_PACKET, 0xbf, 0x0f, '#', 'H', 'Y', '0', '0', '0'}, "The client was disconnected"...))
+		serverConn.Close()
+	}()
+
+	_, err := dc.ExecContext(context.Background(), "DELETE FROM t", nil)
+
+	if err != driver.ErrBadConn {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if dc.IsValid() == true {
+		t.Errorf("connection must be invalid")
+	}
+}
+
+func TestDriverConnUnsolicitedNonFatalErr(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+	dc := &driverConn{c: c}
+
+	go func() {
+		server.readPacket()
+		// Out of sequence, but not a goodbye: the command may have run.
+		server.writePacket(0, append([]byte{ERR_PACKET, 0x26, 0x04, '#', '2', '3', '0', '0', '0'}, "Duplicate entry"...))
+		serverConn.Close()
+	}()
+
+	_, err := dc.ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+
+	if err == driver.ErrBadConn || err == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFindPlaceholders(t *testing.T) {
+	tests := []struct {
+		query              string
+		noBackslashEscapes bool
+		expected           int
+	}{
+		{"SELECT ? , ?", false, 2},
+		{"SELECT * FROM t WHERE note = 'why?' AND id = ?", false, 1},
+		{`SELECT "a?", ` + "`col?`" + `, ? FROM t`, false, 1},
+		{`SELECT 'it\'s?', ?`, false, 1},
+		{`SELECT 'a\', ?`, true, 1},
+		{"SELECT 'it''s?', ?", false, 1},
+		{"SELECT ? -- why?\n, ?", false, 2},
+		{"SELECT 1--?\n", false, 1},
+		{"SELECT ? # why?\n", false, 1},
+		{"SELECT /* why? */ ?", false, 1},
+		{"SELECT /*! ? */ 1", false, 1},
+	}
+
+	for _, test := range tests {
+		if got := findPlaceholders(test.query, test.noBackslashEscapes); len(got) != test.expected {
+			t.Errorf("%q: got %d placeholders %v, want %d", test.query, len(got), got, test.expected)
+		}
+	}
+
+	dc := &driverConn{c: new(Connection)}
+
+	query, err := dc.interpolate("SELECT 'why?' FROM t WHERE id = ?", namedValues([]driver.Value{int64(7)}))
+
+	if err != nil || query != "SELECT 'why?' FROM t WHERE id = 7" {
+		t.Errorf("got %q %v", query, err)
+	}
+
+	stmt, _ := dc.Prepare("SELECT 'why?' FROM t WHERE id = ? AND name = ?")
+
+	if stmt.NumInput() != 2 {
+		t.Errorf("NumInput: got %d want 2", stmt.NumInput())
+	}
+}
+
+func TestConnector(t *testing.T) {
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+	done := make(chan bool)
+	resets := 0
+
+	go func() {
+		defer close(done)
+
+		server.handshake()
+
+		for {
+			payload := server.readCommand()
+
+			if payload == nil {
+				return
+			}
+
+			switch {
+			case payload[0] == COM_RESET_CONNECTION:
+				// The session variables are read back in the same round
+				// trip.
+				if query := server.readCommand(); query == nil || string(query[1:]) != SESSION_VARIABLES_QUERY {
+					t.Errorf("unexpected command after the reset %q", query)
+					return
+				}
+
+				server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+				resets++
+
+				fallthrough
+			case string(payload[1:]) == SESSION_VARIABLES_QUERY:
+				// The global sql_mode differs from the one read at connect.
+				server.writeResultSet(1, [][]byte{
+					columnPacket("@@max_allowed_packet", MYSQL_TYPE_LONGLONG, 0, 63),
+					columnPacket("@@sql_mode", MYSQL_TYPE_VAR_STRING, 0, 45),
+					columnPacket("@@wait_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+					columnPacket("@@interactive_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+				}, [][]byte{
+					textRowPacket("4194304", "ANSI_QUOTES", "28800", "28800"),
+				}, SERVER_STATUS_AUTOCOMMIT)
+			case string(payload[1:]) == "SELECT id, name FROM city WHERE id > 0":
+				server.writeResultSet(1, [][]byte{
+					columnPacket("id", MYSQL_TYPE_LONG, NOT_NULL_FLAG, 63),
+					columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+				}, [][]byte{
+					textRowPacket("1", "Taipei"),
+					textRowPacket("2", nil),
+				}, SERVER_STATUS_AUTOCOMMIT)
+			case string(payload[1:]) == "UPDATE city SET name = 'Tainan' WHERE id = 2":
+				server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, "Rows matched: 1  Changed: 1  Warnings: 0"))
+			case string(payload[1:]) == "INSERT INTO city (name) VALUES ('Hsinchu')":
+				server.writePacket(1, okPacket(1, 3, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+			default:
+				t.Errorf("unexpected command %q", payload)
+				return
+			}
+		}
+	}()
+
+	db := sql.OpenDB(NewConnector(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	}))
+
+	db.SetMaxOpenConns(1)
+
+	rows, err := db.Query("SELECT id, name FROM city WHERE id > ?", 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	for rows.Next() {
+		var id int
+		var name sql.NullString
+
+		if err = rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, fmt.Sprintf("%d:%v:%s", id, name.Valid, name.String))
+	}
+
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(got) != "[1:true:Taipei 2:false:]" {
+		t.Errorf("unexpected rows %v", got)
+	}
+
+	result, err := db.Exec("UPDATE city SET name = ? WHERE id = ?", "Tainan", 2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("rows affected: %d", n)
+	}
+
+	result, err = db.Exec("INSERT INTO city (name) VALUES (?)", "Hsinchu")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, _ := result.LastInsertId(); id != 3 {
+		t.Errorf("last insert id: %d", id)
+	}
+
+	db.Close()
+	<-done
+
+	if resets == 0 {
+		t.Errorf("the session was never reset")
+	}
+}
+
+func TestAbandonOnClose(t *testing.T) {
+	dials := 0
+
+	db := sql.OpenDB(NewConnector(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			client, serverConn := net.Pipe()
+			server := newFakeServer(t, serverConn)
+			dials++
+
+			go func() {
+				server.handshake()
+
+				if payload := server.readCommand(); string(payload[1:]) != "SELECT n FROM huge" {
+					t.Errorf("unexpected command %q", payload)
+					return
+				}
+
+				server.writePacket(1, []byte{1})
+				server.writePacket(2, columnPacket("n", MYSQL_TYPE_LONGLONG, 0, 63))
+				server.writePacket(3, eofPacket(0))
+
+				// Rows without end, until the client closes the
+				// connection.
+				row := textRowPacket("1")
+
+				for seq := 4; ; seq++ {
+					packet := append([]byte{byte(len(row)), 0, 0, byte(seq)}, row...)
+
+					if _, err := serverConn.Write(packet); err != nil {
+						return
+					}
+				}
+			}()
+
+			return client, nil
+		},
+	}))
+
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	for i := 0; i < 2; i++ {
+		rows, err := db.QueryContext(AbandonOnClose(context.Background()), "SELECT n FROM huge")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rows.Next() == false {
+			t.Fatal(rows.Err())
+		}
+
+		if err = rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dials != 2 {
+		t.Errorf("abandoned connection reused, %d dials", dials)
+	}
+}
@@ -0,0 +1,169 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDSN = errors.New("Invalid DSN")
+)
+
+// ParseDSN parses a data source name of the form
+//
+//	[username[:password]@][network[(address)]]/dbname[?param1=value1&paramN=valueN]
+//
+// e.g. "root:secret@tcp(127.0.0.1:3306)/test?allowLocalInfile=true".
+// Supported parameters are allowLocalInfile, maxAllowedPacket, interactive,
+// connectTimeout, handshakeTimeout and readTimeout (e.g. "5s") and debug.
+func ParseDSN(dsn string) (ConnectionParameter, error) {
+	var err error
+
+	param := ConnectionParameter{
+		Network: "tcp",
+		Host:    "127.0.0.1",
+		Port:    "3306",
+	}
+
+	// dbname[?params]
+	slash := strings.LastIndex(dsn, "/")
+
+	if slash < 0 {
+		return param, ErrInvalidDSN
+	}
+
+	dbName := dsn[slash+1:]
+
+	if question := strings.Index(dbName, "?"); question >= 0 {
+		err = parseDSNParams(&param, dbName[question+1:])
+
+		if err != nil {
+			return param, err
+		}
+
+		dbName = dbName[:question]
+	}
+
+	param.DBName = dbName
+
+	// [username[:password]@]
+	rest := dsn[:slash]
+
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		credentials := rest[:at]
+		rest = rest[at+1:]
+
+		if colon := strings.Index(credentials, ":"); colon >= 0 {
+			param.Username = credentials[:colon]
+			param.Password = NewSecret(credentials[colon+1:])
+		} else {
+			param.Username = credentials
+		}
+	}
+
+	// [network[(address)]]
+	if rest == "" {
+		return param, nil
+	}
+
+	address := ""
+
+	if open := strings.Index(rest, "("); open >= 0 {
+		if strings.HasSuffix(rest, ")") == false {
+			return param, ErrInvalidDSN
+		}
+
+		address = rest[open+1 : len(rest)-1]
+		rest = rest[:open]
+	}
+
+	param.Network = rest
+
+	if address == "" {
+		return param, nil
+	}
+
+	if param.Network == "unix" || param.Network == "pipe" {
+		param.Host = address
+		param.Port = ""
+		return param, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+
+	if err != nil {
+		// The port is optional.
+		param.Host = address
+		return param, nil
+	}
+
+	param.Host = host
+	param.Port = port
+
+	return param, nil
+}
+
+func parseDSNParams(param *ConnectionParameter, query string) error {
+	values, err := url.ParseQuery(query)
+
+	if err != nil {
+		return ErrInvalidDSN
+	}
+
+	for key, value := range values {
+		switch key {
+		case "allowLocalInfile":
+			param.AllowLocalInfile, err = strconv.ParseBool(value[0])
+		case "maxAllowedPacket":
+			param.MaxAllowedPacket, err = strconv.Atoi(value[0])
+		case "interactive":
+			param.Interactive, err = strconv.ParseBool(value[0])
+		case "connectTimeout":
+			param.ConnectTimeout, err = time.ParseDuration(value[0])
+		case "handshakeTimeout":
+			param.HandshakeTimeout, err = time.ParseDuration(value[0])
+		case "readTimeout":
+			param.ReadTimeout, err = time.ParseDuration(value[0])
+		case "maxPayloadSize":
+			param.MaxPayloadSize, err = strconv.Atoi(value[0])
+		case "maxColumnSize":
+			param.MaxColumnSize, err = strconv.Atoi(value[0])
+		case "tls":
+			param.TLSConfig, err = parseTLSParam(value[0])
+		case "strictSecurity":
+			param.StrictSecurity, err = strconv.ParseBool(value[0])
+		case "minServerVersion":
+			param.MinServerVersion = value[0]
+		case "debug":
+			param.IsDebugPacket, err = strconv.ParseBool(value[0])
+		default:
+			return errors.New("Unknown DSN parameter: " + key)
+		}
+
+		if err != nil {
+			return ErrInvalidDSN
+		}
+	}
+
+	return nil
+}
+
+// parseTLSParam parses the tls DSN parameter: true, false or skip-verify.
+func parseTLSParam(value string) (*tls.Config, error) {
+	if value == "skip-verify" {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+
+	if err != nil || enabled == false {
+		return nil, err
+	}
+
+	return &tls.Config{}, nil
+}
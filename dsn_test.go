@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		expected ConnectionParameter
+	}{
+		{
+			"root:se:cret@tcp(db.local:3307)/shop?allowLocalInfile=true&maxAllowedPacket=-1",
+			ConnectionParameter{Network: "tcp", Host: "db.local", Port: "3307", DBName: "shop", Username: "root", Password: NewSecret("se:cret"), AllowLocalInfile: true, MaxAllowedPacket: -1},
+		},
+		{
+			"app@unix(/var/run/mysqld/mysqld.sock)/",
+			ConnectionParameter{Network: "unix", Host: "/var/run/mysqld/mysqld.sock", Username: "app"},
+		},
+		{
+			"app@pipe(MySQL80)/",
+			ConnectionParameter{Network: "pipe", Host: "MySQL80", Username: "app"},
+		},
+		{
+			"/test",
+			ConnectionParameter{Network: "tcp", Host: "127.0.0.1", Port: "3306", DBName: "test"},
+		},
+		{
+			"app@tcp(db.local)/shop?maxPayloadSize=1048576&maxColumnSize=65536",
+			ConnectionParameter{Network: "tcp", Host: "db.local", Port: "3306", DBName: "shop", Username: "app", MaxPayloadSize: 1048576, MaxColumnSize: 65536},
+		},
+		{
+			"app@tcp(db.local)/shop?tls=skip-verify&strictSecurity=true&minServerVersion=8.0.28",
+			ConnectionParameter{Network: "tcp", Host: "db.local", Port: "3306", DBName: "shop", Username: "app", TLSConfig: &tls.Config{InsecureSkipVerify: true}, StrictSecurity: true, MinServerVersion: "8.0.28"},
+		},
+		{
+			"app@tcp(db.local)/shop?connectTimeout=5s",
+			ConnectionParameter{Network: "tcp", Host: "db.local", Port: "3306", DBName: "shop", Username: "app", ConnectTimeout: 5 * time.Second},
+		},
+		{
+			"u:p@tcp([::1]:3306)/x",
+			ConnectionParameter{Network: "tcp", Host: "::1", Port: "3306", DBName: "x", Username: "u", Password: NewSecret("p")},
+		},
+	}
+
+	for _, test := range tests {
+		param, err := ParseDSN(test.dsn)
+
+		if err != nil {
+			t.Errorf("%s: %v", test.dsn, err)
+			continue
+		}
+
+		if reflect.DeepEqual(param, test.expected) == false {
+			t.Errorf("%s:\n got %+v\nwant %+v", test.dsn, param, test.expected)
+		}
+	}
+
+	for _, dsn := range []string{"no-slash", "tcp(localhost/db", "/db?unknown=1"} {
+		if _, err := ParseDSN(dsn); err == nil {
+			t.Errorf("%s: expected an error", dsn)
+		}
+	}
+}
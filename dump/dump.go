@@ -0,0 +1,317 @@
+// Package dump writes logical backups of databases, similar to mysqldump,
+// over a normal connection.
+package dump
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/junhsieh/go-mysql-pure"
+)
+
+const (
+	DEFAULT_ROWS_PER_INSERT = 100
+)
+
+var (
+	ErrNoDatabase = errors.New("No database to dump")
+)
+
+// Options selects what is dumped and how.
+type Options struct {
+	Databases []string // databases to dump
+	Tables    []string // optional, limits the dump to these tables
+
+	NoCreateInfo bool // skip CREATE TABLE statements
+	NoData       bool // skip table data
+
+	// RowsPerInsert is the number of rows per INSERT statement.
+	RowsPerInsert int
+
+	// SingleTransaction dumps all tables from one consistent snapshot by
+	// running inside a REPEATABLE READ transaction. It only guarantees
+	// consistency for transactional tables such as InnoDB.
+	SingleTransaction bool
+
+	// CSV, when set, receives the data of every table as CSV instead of
+	// INSERT statements. NULL is written as an unquoted NULL, which is what
+	// LOAD DATA (and ImportCSV) reads back as NULL.
+	CSV func(database string, table string) (io.Writer, error)
+}
+
+// Dump writes the selected databases to w.
+func Dump(conn *mysql.Connection, w io.Writer, opt Options) error {
+	var err error
+
+	if len(opt.Databases) == 0 {
+		return ErrNoDatabase
+	}
+
+	if opt.RowsPerInsert <= 0 {
+		opt.RowsPerInsert = DEFAULT_ROWS_PER_INSERT
+	}
+
+	//
+	if opt.SingleTransaction == true {
+		// Without SESSION it only applies to the next transaction, leaving
+		// the isolation level of the connection as it was.
+		err = exec(conn, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ")
+
+		if err != nil {
+			return err
+		}
+
+		err = exec(conn, "START TRANSACTION /*!40100 WITH CONSISTENT SNAPSHOT */")
+
+		if err != nil {
+			return err
+		}
+
+		// The snapshot is only read from, so it is always rolled back.
+		defer exec(conn, "ROLLBACK")
+	}
+
+	//
+	bw := bufio.NewWriter(w)
+
+	// The literals below are escaped with backslashes, so the restoring
+	// session must not run with NO_BACKSLASH_ESCAPES or ANSI_QUOTES.
+	fmt.Fprintf(bw, "SET SQL_MODE='NO_AUTO_VALUE_ON_ZERO';\n")
+	fmt.Fprintf(bw, "SET FOREIGN_KEY_CHECKS=0;\n")
+
+	for _, database := range opt.Databases {
+		err = dumpDatabase(conn, bw, database, &opt)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(bw, "SET FOREIGN_KEY_CHECKS=1;\n")
+
+	return bw.Flush()
+}
+
+func dumpDatabase(conn *mysql.Connection, w *bufio.Writer, database string, opt *Options) error {
+	var err error
+
+	tables := opt.Tables
+
+	if len(tables) == 0 {
+		tables, err = listTables(conn, database)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "\n--\n-- Database: %s\n--\n\n", database)
+
+	if opt.NoCreateInfo == false {
+		fmt.Fprintf(w, "CREATE DATABASE IF NOT EXISTS %s;\n", mysql.QuoteIdentifier(database))
+	}
+
+	fmt.Fprintf(w, "USE %s;\n", mysql.QuoteIdentifier(database))
+
+	for _, table := range tables {
+		if opt.NoCreateInfo == false {
+			err = dumpCreateTable(conn, w, database, table)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if opt.NoData == true {
+			continue
+		}
+
+		if opt.CSV != nil {
+			csvWriter, err := opt.CSV(database, table)
+
+			if err != nil {
+				return err
+			}
+
+			err = dumpTableCSV(conn, csvWriter, database, table)
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = dumpTableInserts(conn, w, database, table, opt.RowsPerInsert)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listTables returns the base tables of a database, leaving out views.
+func listTables(conn *mysql.Connection, database string) ([]string, error) {
+	rows, err := conn.Query("SHOW FULL TABLES FROM " + mysql.QuoteIdentifier(database) + " WHERE Table_type = 'BASE TABLE'")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []string
+
+	for rows.Next() {
+		tables = append(tables, string(rows.Values()[0]))
+	}
+
+	return tables, rows.Close()
+}
+
+func dumpCreateTable(conn *mysql.Connection, w *bufio.Writer, database string, table string) error {
+	rows, err := conn.Query("SHOW CREATE TABLE " + mysql.QuoteIdentifier(database) + "." + mysql.QuoteIdentifier(table))
+
+	if err != nil {
+		return err
+	}
+
+	var createTable string
+
+	// Table [string], Create Table [string]
+	if rows.Next() {
+		createTable = string(rows.Values()[1])
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nDROP TABLE IF EXISTS %s;\n%s;\n", mysql.QuoteIdentifier(table), createTable)
+
+	return nil
+}
+
+func dumpTableInserts(conn *mysql.Connection, w *bufio.Writer, database string, table string, rowsPerInsert int) error {
+	rows, err := conn.Query("SELECT * FROM " + mysql.QuoteIdentifier(database) + "." + mysql.QuoteIdentifier(table))
+
+	if err != nil {
+		return err
+	}
+
+	columns := rows.Columns()
+	n := 0
+
+	for rows.Next() {
+		if n == 0 {
+			fmt.Fprintf(w, "INSERT INTO %s VALUES\n(", mysql.QuoteIdentifier(table))
+		} else {
+			w.WriteString(",\n(")
+		}
+
+		for i, value := range rows.Values() {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+
+			writeLiteral(w, &columns[i], value)
+		}
+
+		w.WriteByte(')')
+		n++
+
+		if n == rowsPerInsert {
+			w.WriteString(";\n")
+			n = 0
+		}
+	}
+
+	if n > 0 {
+		w.WriteString(";\n")
+	}
+
+	return rows.Close()
+}
+
+// writeLiteral writes a text protocol value as an SQL literal.
+func writeLiteral(w *bufio.Writer, col *mysql.Column, value []byte) {
+	switch {
+	case value == nil:
+		w.WriteString("NULL")
+	case col.Type.IsNumeric() == true || col.Type == mysql.MYSQL_TYPE_YEAR:
+		w.Write(value)
+	case len(value) > 0 && (col.Type == mysql.MYSQL_TYPE_BIT || col.CharacterSet == mysql.BINARY_COLLATION && (col.Type.IsString() == true || col.Type == mysql.MYSQL_TYPE_GEOMETRY)):
+		w.WriteString("0x")
+		w.WriteString(hex.EncodeToString(value))
+	default:
+		w.WriteString(mysql.QuoteString(string(value)))
+	}
+}
+
+func dumpTableCSV(conn *mysql.Connection, w io.Writer, database string, table string) error {
+	rows, err := conn.Query("SELECT * FROM " + mysql.QuoteIdentifier(database) + "." + mysql.QuoteIdentifier(table))
+
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for rows.Next() {
+		writeCSVRow(bw, rows.Values())
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// writeCSVRow writes a row the way ImportCSV reads it back. Every value is
+// enclosed so that a string "NULL" stays a string, while NULL itself is an
+// unquoted NULL.
+func writeCSVRow(w *bufio.Writer, values [][]byte) {
+	for i, value := range values {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+
+		if value == nil {
+			w.WriteString("NULL")
+			continue
+		}
+
+		w.WriteByte('"')
+
+		for _, b := range value {
+			if b == '"' {
+				w.WriteByte('"')
+			}
+
+			w.WriteByte(b)
+		}
+
+		w.WriteByte('"')
+	}
+
+	w.WriteByte('\n')
+}
+
+// exec runs a statement which returns no rows.
+func exec(conn *mysql.Connection, query string) error {
+	rows, err := conn.Query(query)
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
@@ -0,0 +1,51 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/junhsieh/go-mysql-pure"
+)
+
+func TestWriteLiteral(t *testing.T) {
+	tests := []struct {
+		column   mysql.Column
+		value    []byte
+		expected string
+	}{
+		{mysql.Column{Type: mysql.MYSQL_TYPE_LONG}, nil, "NULL"},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_NEWDECIMAL}, []byte("-1.50"), "-1.50"},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_VAR_STRING, CharacterSet: 33}, []byte(`it's "x"`), `'it\'s \"x\"'`},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_BLOB, CharacterSet: mysql.BINARY_COLLATION}, []byte{0x00, 0x5c, 0xff}, "0x005cff"},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_BLOB, CharacterSet: mysql.BINARY_COLLATION}, []byte{}, "''"},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_BIT, CharacterSet: mysql.BINARY_COLLATION}, []byte{0x05}, "0x05"},
+		{mysql.Column{Type: mysql.MYSQL_TYPE_DATETIME, CharacterSet: mysql.BINARY_COLLATION}, []byte("2016-01-02 03:04:05"), "'2016-01-02 03:04:05'"},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		w := bufio.NewWriter(buf)
+
+		writeLiteral(w, &test.column, test.value)
+		w.Flush()
+
+		if buf.String() != test.expected {
+			t.Errorf("type %d %q: got %s want %s", test.column.Type, test.value, buf.String(), test.expected)
+		}
+	}
+}
+
+func TestWriteCSVRow(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+
+	writeCSVRow(w, [][]byte{[]byte("1"), nil, []byte("NULL"), []byte(`say "hi", bye`), {}})
+	w.Flush()
+
+	expected := `"1",NULL,"NULL","say ""hi"", bye",""` + "\n"
+
+	if buf.String() != expected {
+		t.Errorf("got %q want %q", buf.String(), expected)
+	}
+}
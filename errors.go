@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+// Server error codes the client reacts to.
+// Reference:
+// https://mariadb.com/kb/en/mariadb/mariadb-error-codes/
+const (
+	ER_CON_COUNT_ERROR            = 1040 // too many connections
+	ER_UNKNOWN_COM_ERROR          = 1047 // the server predates the command
+	ER_SERVER_SHUTDOWN            = 1053
+	ER_PARSE_ERROR                = 1064
+	ER_LOCK_WAIT_TIMEOUT          = 1205
+	ER_LOCK_DEADLOCK              = 1213
+	ER_OPTION_PREVENTS_STATEMENT  = 1290 // e.g. --read-only after a failover
+	ER_QUERY_INTERRUPTED          = 1317 // KILL QUERY
+	ER_CONNECTION_KILLED          = 1927 // MariaDB
+	ER_CLIENT_INTERACTION_TIMEOUT = 4031 // MySQL 8.0.24+ closing an idle connection
+
+	// Client errors, forwarded as ERR packets by some proxies.
+	CR_SERVER_GONE_ERROR = 2006
+	CR_SERVER_LOST       = 2013
+)
+
+var (
+	ErrInvalidConn        = errors.New("Invalid Connection")
+	ErrHandshakeTimeout   = errors.New("Handshake Timed Out")
+	ErrConnectTimeout     = errors.New("Connect Timed Out")
+	ErrUnexpectedSequence = errors.New("Unexpected Sequence Number")
+	ErrMalformedPacket    = protocol.ErrMalformedPacket
+	ErrUnexpectedPacket   = errors.New("Unexpected Packet")
+	ErrUnsupportedServer  = errors.New("Unsupported Server Protocol")
+	ErrNoTLS              = errors.New("Server Does Not Support TLS")
+	ErrBreakerOpen        = errors.New("Circuit Breaker Open")
+)
+
+// MySQLError is an error reported by the server in an ERR packet.
+type MySQLError struct {
+	Number   uint16
+	SQLState string
+	Message  string
+}
+
+func (e *MySQLError) Error() string {
+	if e.SQLState != "" {
+		return fmt.Sprintf("Error %d (%s): %s", e.Number, e.SQLState, e.Message)
+	}
+
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+// ProtocolVersionError is returned by Open when the init packet of the
+// server is not a HandshakeV10, the only one supported. Version 9 is the
+// HandshakeV9 of MySQL before 3.21. It matches ErrUnsupportedServer with
+// errors.Is.
+type ProtocolVersionError struct {
+	Version uint8
+}
+
+func (e *ProtocolVersionError) Error() string {
+	if e.Version == 9 {
+		return "Unsupported protocol version 9 (MySQL before 3.21), only version 10 is supported"
+	}
+
+	return fmt.Sprintf("Unsupported protocol version %d, only version 10 is supported", e.Version)
+}
+
+func (e *ProtocolVersionError) Is(target error) bool {
+	return target == ErrUnsupportedServer
+}
+
+// PacketTooLargeError is returned, without anything being sent, when a
+// command exceeds the max_allowed_packet of the server.
+type PacketTooLargeError struct {
+	Size             int
+	MaxAllowedPacket int
+}
+
+func (e *PacketTooLargeError) Error() string {
+	return fmt.Sprintf("Packet of %d bytes exceeds max_allowed_packet (%d bytes)", e.Size, e.MaxAllowedPacket)
+}
+
+// ResponseTooLargeError is returned when a packet payload or a column value
+// sent by the server exceeds MaxPayloadSize or MaxColumnSize. The response
+// is abandoned halfway, so the connection cannot be used afterwards.
+type ResponseTooLargeError struct {
+	Column string // the column whose value is too large, empty for a payload
+	Size   uint64 // the size announced by the server
+	Limit  int
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("Value of column %s of %d bytes exceeds the limit of %d bytes", e.Column, e.Size, e.Limit)
+	}
+
+	return fmt.Sprintf("Packet payload of %d bytes exceeds the limit of %d bytes", e.Size, e.Limit)
+}
+
+// BatchError is returned by Stmt.ExecuteBatch when the server failed to
+// execute one of the argument sets. The other sets were executed.
+type BatchError struct {
+	Index int   // the first argument set that failed
+	Err   error // its *MySQLError
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("Argument set %d of the batch failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// isFatalServerError reports whether the server error means the server is
+// closing or has closed the connection.
+func isFatalServerError(err error) bool {
+	mysqlErr, ok := err.(*MySQLError)
+
+	if ok == false {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case ER_SERVER_SHUTDOWN, ER_CONNECTION_KILLED, ER_CLIENT_INTERACTION_TIMEOUT,
+		CR_SERVER_GONE_ERROR, CR_SERVER_LOST:
+		return true
+	}
+
+	return false
+}
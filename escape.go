@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"bytes"
+	"strings"
+)
+
+// QuoteIdentifier quotes a database, table or column name with backticks.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// EscapeString escapes the characters that are special inside a string
+// literal the same way mysql_real_escape_string() does.
+//
+// It assumes the default sql_mode: under NO_BACKSLASH_ESCAPES a backslash is
+// literal and the result is open to injection. Use Connection.EscapeString
+// for statements sent on a connection; this function only suits output
+// whose sql_mode is fixed, such as the dump package.
+func EscapeString(s string) string {
+	var buf bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case 0x00:
+			buf.WriteString(`\0`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case 0x1a:
+			buf.WriteString(`\Z`)
+		case '\\', '\'', '"':
+			buf.WriteByte('\\')
+			buf.WriteByte(s[i])
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+
+	return buf.String()
+}
+
+// QuoteString returns s as a single quoted string literal. Like
+// EscapeString it assumes the default sql_mode; prefer
+// Connection.QuoteString.
+func QuoteString(s string) string {
+	return "'" + EscapeString(s) + "'"
+}
+
+// escapeQuotes escapes s for a string literal when NO_BACKSLASH_ESCAPES is
+// in effect. Backslashes are literal then, so a quote can only be escaped by
+// doubling it.
+func escapeQuotes(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
+
+// setSQLMode records the sql_mode flags that change how literals and
+// identifiers have to be quoted.
+func (c *Connection) setSQLMode(sqlMode string) {
+	c.ansiQuotes = false
+
+	for _, mode := range strings.Split(sqlMode, ",") {
+		switch mode {
+		case "ANSI_QUOTES":
+			c.ansiQuotes = true
+		case "NO_BACKSLASH_ESCAPES":
+			c.status |= SERVER_STATUS_NO_BACKSLASH_ESCAPES
+		}
+	}
+}
+
+// RefreshSQLMode reads sql_mode again. NO_BACKSLASH_ESCAPES is tracked from
+// the status flags of every response, but ANSI_QUOTES is only read when
+// connecting, so call this after changing sql_mode with SET.
+func (c *Connection) RefreshSQLMode() error {
+	rows, err := c.Query("SELECT @@sql_mode")
+
+	if err != nil {
+		return err
+	}
+
+	if rows.Next() {
+		c.setSQLMode(string(rows.Values()[0]))
+	}
+
+	return rows.Close()
+}
+
+// NoBackslashEscapes reports whether the NO_BACKSLASH_ESCAPES sql_mode is
+// in effect.
+func (c *Connection) NoBackslashEscapes() bool {
+	return c.status&SERVER_STATUS_NO_BACKSLASH_ESCAPES != 0
+}
+
+// AnsiQuotes reports whether the ANSI_QUOTES sql_mode is in effect, making
+// '"' an identifier quote character.
+func (c *Connection) AnsiQuotes() bool {
+	return c.ansiQuotes
+}
+
+// EscapeString escapes s for a string literal under the current sql_mode.
+func (c *Connection) EscapeString(s string) string {
+	if c.NoBackslashEscapes() == true {
+		return escapeQuotes(s)
+	}
+
+	return EscapeString(s)
+}
+
+// QuoteString returns s as a single quoted string literal under the current
+// sql_mode. Single quotes are used because under ANSI_QUOTES a double quoted
+// string would be read as an identifier.
+func (c *Connection) QuoteString(s string) string {
+	return "'" + c.EscapeString(s) + "'"
+}
+
+// QuoteIdentifier quotes a database, table or column name. Backticks are
+// used since they quote identifiers with or without ANSI_QUOTES.
+func (c *Connection) QuoteIdentifier(name string) string {
+	return QuoteIdentifier(name)
+}
@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestEscapeString(t *testing.T) {
+	c := new(Connection)
+
+	tests := []struct {
+		sqlMode  string
+		input    string
+		expected string
+	}{
+		{"", `it's`, `'it\'s'`},
+		{"", "a\\b\n\x00\"", `'a\\b\n\0\"'`},
+		{"ANSI_QUOTES", `say "hi"`, `'say \"hi\"'`},
+		{"STRICT_TRANS_TABLES,NO_BACKSLASH_ESCAPES", `it's`, `'it''s'`},
+		{"NO_BACKSLASH_ESCAPES", `\' OR 1=1 -- `, `'\'' OR 1=1 -- '`},
+	}
+
+	for _, test := range tests {
+		c.status = 0
+		c.setSQLMode(test.sqlMode)
+
+		if got := c.QuoteString(test.input); got != test.expected {
+			t.Errorf("%q: QuoteString(%q) = %s, want %s", test.sqlMode, test.input, got, test.expected)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := QuoteIdentifier("odd`name"); got != "`odd``name`" {
+		t.Errorf("QuoteIdentifier = %s", got)
+	}
+}
@@ -41,7 +41,7 @@ func main() {
 		Port:          *port,
 		DBName:        *dbName,
 		Username:      *username,
-		Password:      *password,
+		Password:      mysql.NewSecret(*password),
 		IsDebugPacket: true,
 	})
 
@@ -0,0 +1,316 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Plan is the execution plan of a statement as reported by EXPLAIN.
+type Plan struct {
+	Cost           float64 // the query_cost of MySQL, zero when unknown
+	Tables         []PlanTable
+	UsingFilesort  bool
+	UsingTemporary bool
+
+	// JSON is the EXPLAIN FORMAT=JSON document, nil when the server only
+	// supports the tabular format.
+	JSON json.RawMessage
+}
+
+// PlanTable is the access to one table of a Plan, in join order.
+type PlanTable struct {
+	SelectID     int
+	Table        string
+	AccessType   string // ALL, index, range, ref, eq_ref, const, ...
+	PossibleKeys []string
+	Key          string
+	KeyLength    string
+	Ref          []string
+	Rows         uint64 // estimated rows examined per scan
+	Filtered     float64
+	Condition    string // the attached condition, JSON format only
+	Extra        string // the Extra column, tabular format only
+}
+
+// Explain runs EXPLAIN FORMAT=JSON for query, or the tabular EXPLAIN on
+// servers without it (MySQL before 5.6.5, MariaDB before 10.1), and
+// returns the plan. ctx is checked before the statement is sent.
+func (c *Connection) Explain(ctx context.Context, query string) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.Query("EXPLAIN FORMAT=JSON " + query)
+
+	if e, ok := err.(*MySQLError); ok && e.Number == ER_PARSE_ERROR {
+		return c.explainTabular(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var document []byte
+
+	for rows.Next() == true {
+		if values := rows.Values(); len(values) > 0 {
+			document = append(document[:0], values[0]...)
+		}
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseJSONPlan(document)
+}
+
+// explainTabular runs the tabular EXPLAIN for query.
+func (c *Connection) explainTabular(query string) (*Plan, error) {
+	rows, err := c.Query("EXPLAIN " + query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+
+	for rows.Next() == true {
+		var table PlanTable
+
+		for i, value := range rows.Values() {
+			v := string(value)
+
+			switch strings.ToLower(rows.Columns()[i].Name) {
+			case "id":
+				table.SelectID, _ = strconv.Atoi(v)
+			case "table":
+				table.Table = v
+			case "type":
+				table.AccessType = v
+			case "possible_keys":
+				table.PossibleKeys = splitPlanList(v)
+			case "key":
+				table.Key = v
+			case "key_len":
+				table.KeyLength = v
+			case "ref":
+				table.Ref = splitPlanList(v)
+			case "rows":
+				table.Rows, _ = strconv.ParseUint(v, 10, 64)
+			case "filtered":
+				table.Filtered, _ = strconv.ParseFloat(v, 64)
+			case "extra":
+				table.Extra = v
+				plan.UsingFilesort = plan.UsingFilesort || strings.Contains(v, "Using filesort")
+				plan.UsingTemporary = plan.UsingTemporary || strings.Contains(v, "Using temporary")
+			}
+		}
+
+		plan.Tables = append(plan.Tables, table)
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// splitPlanList splits the comma separated lists of the tabular format.
+func splitPlanList(v string) []string {
+	if v == "" {
+		return nil
+	}
+
+	return strings.Split(v, ",")
+}
+
+// parseJSONPlan parses an EXPLAIN FORMAT=JSON document of MySQL or
+// MariaDB, whose layouts differ but share the query_block and table
+// objects.
+func parseJSONPlan(document []byte) (*Plan, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	root, err := decodeOrdered(decoder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{JSON: json.RawMessage(document)}
+	plan.walk(root, 0)
+
+	return plan, nil
+}
+
+// walk collects the tables of a JSON plan in document order, which is the
+// join order.
+func (p *Plan) walk(node interface{}, selectID int) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, item := range v {
+			p.walk(item, selectID)
+		}
+	case jsonObject:
+		if id, ok := v.get("select_id").(json.Number); ok {
+			n, _ := id.Int64()
+			selectID = int(n)
+		}
+
+		for _, member := range v {
+			switch member.key {
+			case "table":
+				if table, ok := member.value.(jsonObject); ok {
+					p.Tables = append(p.Tables, table.planTable(selectID))
+				}
+			case "cost_info":
+				if cost, ok := member.value.(jsonObject); ok && p.Cost == 0 {
+					p.Cost = jsonFloat(cost.get("query_cost"))
+				}
+
+				continue
+			case "using_filesort", "filesort":
+				p.UsingFilesort = p.UsingFilesort || member.value != false
+			case "using_temporary_table", "temporary_table":
+				p.UsingTemporary = p.UsingTemporary || member.value != false
+			}
+
+			p.walk(member.value, selectID)
+		}
+	}
+}
+
+// planTable converts a table object of a JSON plan.
+func (o jsonObject) planTable(selectID int) PlanTable {
+	table := PlanTable{
+		SelectID:     selectID,
+		Table:        jsonString(o.get("table_name")),
+		AccessType:   jsonString(o.get("access_type")),
+		PossibleKeys: jsonStrings(o.get("possible_keys")),
+		Key:          jsonString(o.get("key")),
+		KeyLength:    jsonString(o.get("key_length")),
+		Ref:          jsonStrings(o.get("ref")),
+		Filtered:     jsonFloat(o.get("filtered")),
+		Condition:    jsonString(o.get("attached_condition")),
+	}
+
+	// MySQL and MariaDB respectively.
+	rows := o.get("rows_examined_per_scan")
+
+	if rows == nil {
+		rows = o.get("rows")
+	}
+
+	table.Rows = uint64(jsonFloat(rows))
+
+	return table
+}
+
+// jsonObject is a JSON object keeping the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) get(key string) interface{} {
+	for _, member := range o {
+		if member.key == key {
+			return member.value
+		}
+	}
+
+	return nil
+}
+
+// decodeOrdered decodes the next JSON value, objects as jsonObject.
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		var object jsonObject
+
+		for decoder.More() == true {
+			key, err := decoder.Token()
+
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeOrdered(decoder)
+
+			if err != nil {
+				return nil, err
+			}
+
+			object = append(object, jsonMember{key: key.(string), value: value})
+		}
+
+		_, err = decoder.Token()
+
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+
+		for decoder.More() == true {
+			value, err := decodeOrdered(decoder)
+
+			if err != nil {
+				return nil, err
+			}
+
+			array = append(array, value)
+		}
+
+		_, err = decoder.Token()
+
+		return array, err
+	}
+
+	return token, nil
+}
+
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+
+	return ""
+}
+
+func jsonStrings(v interface{}) []string {
+	array, _ := v.([]interface{})
+
+	var values []string
+
+	for _, item := range array {
+		values = append(values, jsonString(item))
+	}
+
+	return values
+}
+
+// jsonFloat reads a number, which MySQL sends as a string for costs and
+// percentages.
+func jsonFloat(v interface{}) float64 {
+	f, _ := strconv.ParseFloat(jsonString(v), 64)
+	return f
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPlan(t *testing.T) {
+	// MySQL 8.0
+	plan, err := parseJSONPlan([]byte(`{
+  "query_block": {
+    "select_id": 1,
+    "cost_info": {"query_cost": "4.30"},
+    "ordering_operation": {
+      "using_temporary_table": true,
+      "using_filesort": true,
+      "nested_loop": [
+        {"table": {"table_name": "o", "access_type": "ALL", "possible_keys": ["customer_id"],
+          "rows_examined_per_scan": 10, "filtered": "100.00", "attached_condition": "(o.status = 'open')"}},
+        {"table": {"table_name": "c", "access_type": "eq_ref", "possible_keys": ["PRIMARY"], "key": "PRIMARY",
+          "key_length": "4", "ref": ["shop.o.customer_id"], "rows_examined_per_scan": 1, "filtered": "50.00"}}
+      ]
+    }
+  }
+}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PlanTable{
+		{SelectID: 1, Table: "o", AccessType: "ALL", PossibleKeys: []string{"customer_id"}, Rows: 10, Filtered: 100, Condition: "(o.status = 'open')"},
+		{SelectID: 1, Table: "c", AccessType: "eq_ref", PossibleKeys: []string{"PRIMARY"}, Key: "PRIMARY", KeyLength: "4", Ref: []string{"shop.o.customer_id"}, Rows: 1, Filtered: 50},
+	}
+
+	if plan.Cost != 4.3 || plan.UsingFilesort == false || plan.UsingTemporary == false || reflect.DeepEqual(plan.Tables, expected) == false {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+
+	// MariaDB 10.6 with a subquery.
+	plan, err = parseJSONPlan([]byte(`{
+  "query_block": {
+    "select_id": 1,
+    "table": {"table_name": "t", "access_type": "range", "key": "idx", "rows": 120, "filtered": 100},
+    "subqueries": [
+      {"query_block": {"select_id": 2, "table": {"table_name": "u", "access_type": "ALL", "rows": 3000, "filtered": 10}}}
+    ]
+  }
+}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Tables) != 2 || plan.Tables[0].Table != "t" || plan.Tables[0].Rows != 120 ||
+		plan.Tables[1].SelectID != 2 || plan.Tables[1].Rows != 3000 || plan.Tables[1].Filtered != 10 || plan.UsingFilesort == true {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+
+	if _, err := parseJSONPlan([]byte(`{"query_block": `)); err == nil {
+		t.Error("accepted a truncated plan")
+	}
+}
+
+func TestExplainTabular(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		if payload := server.readCommand(); string(payload[1:]) != "EXPLAIN FORMAT=JSON SELECT * FROM t ORDER BY x" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x28, 0x04, '#', '4', '2', '0', '0', '0'}, "You have an error in your SQL syntax"...))
+
+		if payload := server.readCommand(); string(payload[1:]) != "EXPLAIN SELECT * FROM t ORDER BY x" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		var columns [][]byte
+
+		for _, name := range []string{"id", "select_type", "table", "type", "possible_keys", "key", "key_len", "ref", "rows", "Extra"} {
+			columns = append(columns, columnPacket(name, MYSQL_TYPE_VAR_STRING, 0, 33))
+		}
+
+		server.writeResultSet(1, columns, [][]byte{
+			textRowPacket("1", "SIMPLE", "t", "ALL", "a,b", nil, nil, nil, "1000", "Using where; Using filesort"),
+		}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	plan, err := c.Explain(context.Background(), "SELECT * FROM t ORDER BY x")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.JSON != nil || plan.UsingFilesort == false || len(plan.Tables) != 1 || reflect.DeepEqual(plan.Tables[0], PlanTable{
+		SelectID: 1, Table: "t", AccessType: "ALL", PossibleKeys: []string{"a", "b"}, Rows: 1000, Extra: "Using where; Using filesort",
+	}) == false {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
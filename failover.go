@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// IsFailover reports whether err says the server stopped being the primary
+// the connection was opened to: it was turned read-only, is shutting down
+// or killed the connection, as failover tools such as Orchestrator do to
+// the demoted primary.
+func IsFailover(err error) bool {
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &mysqlErr) == false {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case ER_SERVER_SHUTDOWN, ER_CONNECTION_KILLED:
+		return true
+	case ER_OPTION_PREVENTS_STATEMENT:
+		// Also reported for --secure-file-priv and other options.
+		return strings.Contains(mysqlErr.Message, "read-only") == true
+	}
+
+	return false
+}
+
+// failover retires the connections opened before generation, the one of
+// the connection which saw err, once for every failover.
+func (ct *Connector) failover(generation uint64, err error) {
+	if atomic.CompareAndSwapUint64(&ct.generation, generation, generation+1) == false {
+		return
+	}
+
+	if ct.param.OnFailover != nil {
+		ct.param.OnFailover(err)
+	}
+}
+
+// stale reports whether the connection was opened before a failover seen
+// by another connection of its Connector.
+func (dc *driverConn) stale() bool {
+	return dc.connector != nil && atomic.LoadUint64(&dc.connector.generation) != dc.generation
+}
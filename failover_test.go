@@ -0,0 +1,73 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestIsFailover(t *testing.T) {
+	tests := []struct {
+		err      error
+		failover bool
+	}{
+		{&MySQLError{Number: ER_OPTION_PREVENTS_STATEMENT, SQLState: "HY000", Message: "The MySQL server is running with the --super-read-only option so it cannot execute this statement"}, true},
+		{&MySQLError{Number: ER_OPTION_PREVENTS_STATEMENT, SQLState: "HY000", Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"}, false},
+		{&MySQLError{Number: ER_CONNECTION_KILLED, SQLState: "70100", Message: "Connection was killed"}, true},
+		{&MySQLError{Number: ER_LOCK_DEADLOCK, SQLState: "40001", Message: "Deadlock found"}, false},
+		{ErrMalformedPacket, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if IsFailover(test.err) != test.failover {
+			t.Errorf("IsFailover(%v) != %v", test.err, test.failover)
+		}
+	}
+}
+
+func TestFailoverRetiresConnections(t *testing.T) {
+	var failovers []error
+
+	ct := NewConnector(ConnectionParameter{
+		OnFailover: func(err error) {
+			failovers = append(failovers, err)
+		},
+	})
+
+	newDriverConn := func() *driverConn {
+		c, _ := newPipeConnection(ct.param)
+		return &driverConn{c: c, connector: ct, generation: ct.generation}
+	}
+
+	first := newDriverConn()
+	second := newDriverConn()
+	idle := newDriverConn()
+
+	readOnly := &MySQLError{Number: ER_OPTION_PREVENTS_STATEMENT, SQLState: "HY000", Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}
+
+	if err := first.check(readOnly); err != readOnly {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	second.check(readOnly)
+
+	if len(failovers) != 1 || failovers[0] != readOnly {
+		t.Errorf("unexpected failovers %v", failovers)
+	}
+
+	if first.IsValid() == true || second.IsValid() == true || idle.IsValid() == true {
+		t.Error("connection opened before the failover still valid")
+	}
+
+	// Discarded when checked out, without anything being sent.
+	if err := idle.ResetSession(context.Background()); err != driver.ErrBadConn {
+		t.Errorf("unexpected reset error %v", err)
+	}
+
+	third := newDriverConn()
+
+	if third.IsValid() == false || third.stale() == true {
+		t.Error("connection opened after the failover not valid")
+	}
+}
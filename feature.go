@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"strings"
+)
+
+// Feature is something which not every server supports, see Supports.
+type Feature uint8
+
+const (
+	FEATURE_TLS               Feature = iota // CLIENT_SSL
+	FEATURE_COMPRESSION                      // CLIENT_COMPRESS
+	FEATURE_ZSTD                             // CLIENT_ZSTD_COMPRESSION_ALGORITHM, MySQL 8.0.18
+	FEATURE_SESSION_TRACK                    // CLIENT_SESSION_TRACK, MySQL 5.7, MariaDB 10.2
+	FEATURE_DEPRECATE_EOF                    // CLIENT_DEPRECATE_EOF, MySQL 5.7.5, MariaDB 10.2
+	FEATURE_QUERY_ATTRIBUTES                 // CLIENT_QUERY_ATTRIBUTES, MySQL 8.0.23
+	FEATURE_EXTENDED_METADATA                // MARIADB_CLIENT_EXTENDED_METADATA, MariaDB 10.5
+	FEATURE_RESET_CONNECTION                 // COM_RESET_CONNECTION, MySQL 5.7.3, MariaDB 10.2.4
+	FEATURE_JSON_TYPE                        // the JSON type, MySQL 5.7.8, MariaDB 10.2.7 (alias of LONGTEXT)
+	FEATURE_EXPLAIN_JSON                     // EXPLAIN FORMAT=JSON, MySQL 5.6.5, MariaDB 10.1
+	FEATURE_CTE                              // WITH queries, MySQL 8.0, MariaDB 10.2.1
+	FEATURE_WINDOW_FUNCTIONS                 // MySQL 8.0, MariaDB 10.2
+)
+
+// Supports reports whether the server supports feature, from the
+// capabilities it announced in the handshake and its version. Protocol
+// features are those the server offers, which this client may not
+// negotiate (e.g. compression).
+func (c *Connection) Supports(feature Feature) bool {
+	capabilities := ClientFlags(c.ServerCapabilitiesPart1) | ClientFlags(c.ServerCapabilitiesPart2)<<16
+
+	switch feature {
+	case FEATURE_TLS:
+		return capabilities&CLIENT_SSL != 0
+	case FEATURE_COMPRESSION:
+		return capabilities&CLIENT_COMPRESS != 0
+	case FEATURE_ZSTD:
+		return capabilities&CLIENT_ZSTD_COMPRESSION_ALGORITHM != 0
+	case FEATURE_SESSION_TRACK:
+		return capabilities&CLIENT_SESSION_TRACK != 0
+	case FEATURE_DEPRECATE_EOF:
+		return capabilities&CLIENT_DEPRECATE_EOF != 0
+	case FEATURE_QUERY_ATTRIBUTES:
+		// MariaDB servers have no CLIENT_MYSQL, their upper bits differ.
+		return c.isMariaDB() == false && capabilities&CLIENT_QUERY_ATTRIBUTES != 0
+	case FEATURE_EXTENDED_METADATA:
+		return c.ServerMariaDBCapabilities&MARIADB_CLIENT_EXTENDED_METADATA != 0
+	case FEATURE_RESET_CONNECTION:
+		return c.serverAtLeast("5.7.3", "10.2.4")
+	case FEATURE_JSON_TYPE:
+		return c.serverAtLeast("5.7.8", "10.2.7")
+	case FEATURE_EXPLAIN_JSON:
+		return c.serverAtLeast("5.6.5", "10.1")
+	case FEATURE_CTE:
+		return c.serverAtLeast("8.0", "10.2.1")
+	case FEATURE_WINDOW_FUNCTIONS:
+		return c.serverAtLeast("8.0", "10.2")
+	}
+
+	return false
+}
+
+// isMariaDB reports whether the server is MariaDB rather than MySQL.
+func (c *Connection) isMariaDB() bool {
+	return strings.Contains(c.ServerVersion, "MariaDB")
+}
+
+// serverAtLeast reports whether the server is at least the given MySQL or
+// MariaDB version.
+func (c *Connection) serverAtLeast(mysqlVersion string, mariaDBVersion string) bool {
+	if c.isMariaDB() == true {
+		return compareVersions(c.ServerVersion, mariaDBVersion) >= 0
+	}
+
+	return compareVersions(c.ServerVersion, mysqlVersion) >= 0
+}
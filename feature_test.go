@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		version     string
+		part1       uint16
+		part2       uint16
+		supported   []Feature
+		unsupported []Feature
+	}{
+		{
+			"8.0.36", uint16(CLIENT_SSL), uint16((CLIENT_SESSION_TRACK | CLIENT_DEPRECATE_EOF | CLIENT_ZSTD_COMPRESSION_ALGORITHM | CLIENT_QUERY_ATTRIBUTES) >> 16),
+			[]Feature{FEATURE_TLS, FEATURE_ZSTD, FEATURE_SESSION_TRACK, FEATURE_DEPRECATE_EOF, FEATURE_QUERY_ATTRIBUTES, FEATURE_JSON_TYPE, FEATURE_CTE, FEATURE_RESET_CONNECTION},
+			[]Feature{FEATURE_COMPRESSION, FEATURE_EXTENDED_METADATA},
+		},
+		{
+			"5.7.44-log", 0, uint16(CLIENT_SESSION_TRACK >> 16),
+			[]Feature{FEATURE_SESSION_TRACK, FEATURE_JSON_TYPE, FEATURE_EXPLAIN_JSON},
+			[]Feature{FEATURE_TLS, FEATURE_ZSTD, FEATURE_CTE, FEATURE_WINDOW_FUNCTIONS},
+		},
+		{
+			"5.5.5-10.1.48-MariaDB", uint16(CLIENT_COMPRESS), uint16(CLIENT_QUERY_ATTRIBUTES >> 16),
+			[]Feature{FEATURE_COMPRESSION, FEATURE_EXPLAIN_JSON},
+			[]Feature{FEATURE_QUERY_ATTRIBUTES, FEATURE_JSON_TYPE, FEATURE_CTE, FEATURE_RESET_CONNECTION},
+		},
+	}
+
+	for _, test := range tests {
+		c := &Connection{ServerVersion: test.version, ServerCapabilitiesPart1: test.part1, ServerCapabilitiesPart2: test.part2}
+
+		for _, feature := range test.supported {
+			if c.Supports(feature) == false {
+				t.Errorf("%s: feature %d not supported", test.version, feature)
+			}
+		}
+
+		for _, feature := range test.unsupported {
+			if c.Supports(feature) == true {
+				t.Errorf("%s: feature %d supported", test.version, feature)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"strconv"
+)
+
+// FieldType is the type of a column as sent in its column definition.
+type FieldType uint8
+
+const (
+	MYSQL_TYPE_DECIMAL     FieldType = 0
+	MYSQL_TYPE_TINY        FieldType = 1
+	MYSQL_TYPE_SHORT       FieldType = 2
+	MYSQL_TYPE_LONG        FieldType = 3
+	MYSQL_TYPE_FLOAT       FieldType = 4
+	MYSQL_TYPE_DOUBLE      FieldType = 5
+	MYSQL_TYPE_NULL        FieldType = 6
+	MYSQL_TYPE_TIMESTAMP   FieldType = 7
+	MYSQL_TYPE_LONGLONG    FieldType = 8
+	MYSQL_TYPE_INT24       FieldType = 9
+	MYSQL_TYPE_DATE        FieldType = 10
+	MYSQL_TYPE_TIME        FieldType = 11
+	MYSQL_TYPE_DATETIME    FieldType = 12
+	MYSQL_TYPE_YEAR        FieldType = 13
+	MYSQL_TYPE_NEWDATE     FieldType = 14
+	MYSQL_TYPE_VARCHAR     FieldType = 15
+	MYSQL_TYPE_BIT         FieldType = 16
+	MYSQL_TYPE_JSON        FieldType = 245 // MySQL 5.7
+	MYSQL_TYPE_NEWDECIMAL  FieldType = 246
+	MYSQL_TYPE_ENUM        FieldType = 247
+	MYSQL_TYPE_SET         FieldType = 248
+	MYSQL_TYPE_TINY_BLOB   FieldType = 249
+	MYSQL_TYPE_MEDIUM_BLOB FieldType = 250
+	MYSQL_TYPE_LONG_BLOB   FieldType = 251
+	MYSQL_TYPE_BLOB        FieldType = 252
+	MYSQL_TYPE_VAR_STRING  FieldType = 253
+	MYSQL_TYPE_STRING      FieldType = 254
+	MYSQL_TYPE_GEOMETRY    FieldType = 255
+)
+
+var fieldTypeNames = map[FieldType]string{
+	MYSQL_TYPE_DECIMAL:     "DECIMAL",
+	MYSQL_TYPE_TINY:        "TINY",
+	MYSQL_TYPE_SHORT:       "SHORT",
+	MYSQL_TYPE_LONG:        "LONG",
+	MYSQL_TYPE_FLOAT:       "FLOAT",
+	MYSQL_TYPE_DOUBLE:      "DOUBLE",
+	MYSQL_TYPE_NULL:        "NULL",
+	MYSQL_TYPE_TIMESTAMP:   "TIMESTAMP",
+	MYSQL_TYPE_LONGLONG:    "LONGLONG",
+	MYSQL_TYPE_INT24:       "INT24",
+	MYSQL_TYPE_DATE:        "DATE",
+	MYSQL_TYPE_TIME:        "TIME",
+	MYSQL_TYPE_DATETIME:    "DATETIME",
+	MYSQL_TYPE_YEAR:        "YEAR",
+	MYSQL_TYPE_NEWDATE:     "NEWDATE",
+	MYSQL_TYPE_VARCHAR:     "VARCHAR",
+	MYSQL_TYPE_BIT:         "BIT",
+	MYSQL_TYPE_JSON:        "JSON",
+	MYSQL_TYPE_NEWDECIMAL:  "NEWDECIMAL",
+	MYSQL_TYPE_ENUM:        "ENUM",
+	MYSQL_TYPE_SET:         "SET",
+	MYSQL_TYPE_TINY_BLOB:   "TINY_BLOB",
+	MYSQL_TYPE_MEDIUM_BLOB: "MEDIUM_BLOB",
+	MYSQL_TYPE_LONG_BLOB:   "LONG_BLOB",
+	MYSQL_TYPE_BLOB:        "BLOB",
+	MYSQL_TYPE_VAR_STRING:  "VAR_STRING",
+	MYSQL_TYPE_STRING:      "STRING",
+	MYSQL_TYPE_GEOMETRY:    "GEOMETRY",
+}
+
+// String returns the protocol name of the type without its MYSQL_TYPE_
+// prefix, e.g. "LONGLONG" or "VAR_STRING". Use Column.DatabaseTypeName for
+// the name of the column type in SQL.
+func (t FieldType) String() string {
+	if name, ok := fieldTypeNames[t]; ok == true {
+		return name
+	}
+
+	return "FieldType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// IsNumeric reports whether t is an integer, floating point or decimal
+// type. BIT and YEAR are not.
+func (t FieldType) IsNumeric() bool {
+	switch t {
+	case MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL, MYSQL_TYPE_TINY, MYSQL_TYPE_SHORT,
+		MYSQL_TYPE_LONG, MYSQL_TYPE_INT24, MYSQL_TYPE_LONGLONG, MYSQL_TYPE_FLOAT, MYSQL_TYPE_DOUBLE:
+		return true
+	}
+
+	return false
+}
+
+// IsTemporal reports whether t is a date or time type, YEAR included.
+func (t FieldType) IsTemporal() bool {
+	switch t {
+	case MYSQL_TYPE_TIMESTAMP, MYSQL_TYPE_DATE, MYSQL_TYPE_TIME, MYSQL_TYPE_DATETIME,
+		MYSQL_TYPE_YEAR, MYSQL_TYPE_NEWDATE:
+		return true
+	}
+
+	return false
+}
+
+// IsString reports whether t is a character or binary string type: CHAR,
+// VARCHAR, the TEXT and BLOB types, ENUM and SET. The character set of
+// the column tells text from binary, see BINARY_COLLATION.
+func (t FieldType) IsString() bool {
+	switch t {
+	case MYSQL_TYPE_VARCHAR, MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING,
+		MYSQL_TYPE_TINY_BLOB, MYSQL_TYPE_MEDIUM_BLOB, MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BLOB,
+		MYSQL_TYPE_ENUM, MYSQL_TYPE_SET:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestFieldType(t *testing.T) {
+	tests := []struct {
+		fieldType FieldType
+		name      string
+		numeric   bool
+		temporal  bool
+		str       bool
+	}{
+		{MYSQL_TYPE_LONGLONG, "LONGLONG", true, false, false},
+		{MYSQL_TYPE_NEWDECIMAL, "NEWDECIMAL", true, false, false},
+		{MYSQL_TYPE_YEAR, "YEAR", false, true, false},
+		{MYSQL_TYPE_DATETIME, "DATETIME", false, true, false},
+		{MYSQL_TYPE_VAR_STRING, "VAR_STRING", false, false, true},
+		{MYSQL_TYPE_BLOB, "BLOB", false, false, true},
+		{MYSQL_TYPE_BIT, "BIT", false, false, false},
+		{FieldType(100), "FieldType(100)", false, false, false},
+	}
+
+	for _, test := range tests {
+		if test.fieldType.String() != test.name || test.fieldType.IsNumeric() != test.numeric ||
+			test.fieldType.IsTemporal() != test.temporal || test.fieldType.IsString() != test.str {
+			t.Errorf("unexpected %s: %v %v %v", test.fieldType, test.fieldType.IsNumeric(), test.fieldType.IsTemporal(), test.fieldType.IsString())
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Firewall actions.
+const (
+	FIREWALL_ALLOW FirewallAction = iota
+	FIREWALL_LOG                  // allowed, and reported to LogFunc
+	FIREWALL_DENY                 // refused with a *FirewallError, and reported to LogFunc
+)
+
+// FirewallAction is what a Firewall does with a statement.
+type FirewallAction uint8
+
+// FirewallRule matches statements by every criterion it sets; a rule
+// without criteria matches every statement. The statement type is its
+// first keyword, e.g. "DELETE", and the tables those following FROM, JOIN,
+// INTO, UPDATE and TABLE, without their database.
+type FirewallRule struct {
+	Name   string
+	Action FirewallAction
+
+	Fingerprint string // as returned by Fingerprint
+	Statement   string // e.g. "DROP", case insensitive
+	Table       string // case insensitive
+	NoWhere     bool   // the statement has no WHERE clause, e.g. DELETE FROM t
+}
+
+// Firewall guards a database against dangerous statements, such as DROP
+// or a DELETE without WHERE, with rules evaluated in order: the first
+// matching rule decides, Default when none does. A denylist keeps Default
+// FIREWALL_ALLOW, an allowlist of the fingerprints of the application
+// FIREWALL_DENY, e.g.
+//
+//	&Firewall{Rules: []FirewallRule{
+//		{Name: "no-drop", Action: FIREWALL_DENY, Statement: "DROP"},
+//		{Name: "unbounded-delete", Action: FIREWALL_DENY, Statement: "DELETE", NoWhere: true},
+//		{Name: "audit", Action: FIREWALL_LOG, Table: "salaries"},
+//	}}
+//
+// The statements are not parsed, only scanned, so a Firewall is a guard
+// rail against mistakes rather than a security boundary.
+type Firewall struct {
+	Rules   []FirewallRule
+	Default FirewallAction
+
+	// LogFunc, when set, receives the statements logged or denied, with
+	// the rule which matched them, nil for Default.
+	LogFunc func(rule *FirewallRule, query string)
+}
+
+// FirewallError is returned, without anything being sent, for a statement
+// denied by a Firewall.
+type FirewallError struct {
+	Rule  string // name of the rule, empty for Default
+	Query string
+}
+
+func (e *FirewallError) Error() string {
+	if e.Rule == "" {
+		return "Statement denied by the firewall"
+	}
+
+	return fmt.Sprintf("Statement denied by firewall rule %q", e.Rule)
+}
+
+// Check applies the rules to query, returning a *FirewallError if it is
+// denied.
+func (f *Firewall) Check(query string) error {
+	statement := scanStatement(query)
+
+	var rule *FirewallRule
+	action := f.Default
+
+	for i := range f.Rules {
+		if f.Rules[i].matches(&statement) == true {
+			rule = &f.Rules[i]
+			action = rule.Action
+			break
+		}
+	}
+
+	if action != FIREWALL_ALLOW && f.LogFunc != nil {
+		f.LogFunc(rule, query)
+	}
+
+	if action != FIREWALL_DENY {
+		return nil
+	}
+
+	err := &FirewallError{Query: query}
+
+	if rule != nil {
+		err.Rule = rule.Name
+	}
+
+	return err
+}
+
+func (r *FirewallRule) matches(statement *scannedStatement) bool {
+	if r.Fingerprint != "" && r.Fingerprint != statement.fingerprint() {
+		return false
+	}
+
+	if r.Statement != "" && strings.EqualFold(r.Statement, statement.keyword) == false {
+		return false
+	}
+
+	if r.NoWhere == true && statement.where == true {
+		return false
+	}
+
+	if r.Table == "" {
+		return true
+	}
+
+	for _, table := range statement.tables {
+		if strings.EqualFold(r.Table, table) == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scannedStatement is what a Firewall knows of a statement.
+type scannedStatement struct {
+	query   string
+	keyword string   // first keyword, upper case
+	tables  []string // unquoted, without their database
+	where   bool
+
+	normalized string // fingerprint, computed when a rule needs it
+}
+
+func (s *scannedStatement) fingerprint() string {
+	if s.normalized == "" {
+		s.normalized = Fingerprint(s.query)
+	}
+
+	return s.normalized
+}
+
+// scanStatement finds the statement type, the tables and the WHERE clause
+// of query in its words outside of literals and comments.
+func scanStatement(query string) scannedStatement {
+	statement := scannedStatement{query: query}
+
+	words := sqlWords(query)
+
+	for i, word := range words {
+		keyword := strings.ToUpper(word)
+
+		if i == 0 {
+			statement.keyword = keyword
+		}
+
+		switch keyword {
+		case "WHERE":
+			statement.where = true
+		case "FROM", "JOIN", "INTO", "UPDATE", "TABLE":
+			if i+1 < len(words) {
+				table := words[i+1]
+
+				if dot := strings.LastIndexByte(table, '.'); dot >= 0 {
+					table = table[dot+1:]
+				}
+
+				statement.tables = append(statement.tables, strings.Trim(table, "`"))
+			}
+		}
+	}
+
+	return statement
+}
+
+// sqlWords splits query into its identifiers and keywords, dotted names
+// such as `db`.`t` being a single word, skipping literals, comments and
+// punctuation.
+func sqlWords(query string) []string {
+	var words []string
+
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; {
+		case ch == '\'' || ch == '"':
+			for i++; i < len(query) && query[i] != ch; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case ch == '#' || ch == '-' && strings.HasPrefix(query[i:], "-- "):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+
+			if end < 0 {
+				return words
+			}
+
+			i += 2 + end + 1
+		case ch == '`' || isIdentifierByte(ch) == true:
+			start := i
+
+			for i < len(query) && (query[i] == '`' || query[i] == '.' || isIdentifierByte(query[i]) == true) {
+				if query[i] == '`' {
+					for i++; i < len(query) && query[i] != '`'; i++ {
+					}
+				}
+
+				i++
+			}
+
+			words = append(words, query[start:i])
+			i--
+		}
+	}
+
+	return words
+}
+
+// Fingerprint normalizes query so that the statements differing only by
+// their literals, comments, whitespace or the case of their keywords are
+// identical, as pt-query-digest does: the literals are replaced by ?, lists
+// of them by ?+, e.g.
+//
+//	SELECT * FROM t WHERE id IN (1, 2, 3) /* x */  -> select * from t where id in (?+)
+func Fingerprint(query string) string {
+	redacted := redactSQL(query, false)
+
+	var buf strings.Builder
+
+	space := false
+
+	for i := 0; i < len(redacted); i++ {
+		ch := redacted[i]
+
+		switch {
+		case ch == '#' || ch == '-' && strings.HasPrefix(redacted[i:], "-- "):
+			for i < len(redacted) && redacted[i] != '\n' {
+				i++
+			}
+
+			space = true
+			continue
+		case ch == '/' && strings.HasPrefix(redacted[i:], "/*"):
+			end := strings.Index(redacted[i+2:], "*/")
+
+			if end < 0 {
+				i = len(redacted)
+			} else {
+				i += 2 + end + 1
+			}
+
+			space = true
+			continue
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			space = true
+			continue
+		}
+
+		if space == true && buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+
+		space = false
+
+		if ch == '`' {
+			end := strings.IndexByte(redacted[i+1:], '`')
+
+			if end < 0 {
+				end = len(redacted) - i - 1
+			} else {
+				end++
+			}
+
+			buf.WriteString(redacted[i : i+1+end])
+			i += end
+			continue
+		}
+
+		if ch >= 'A' && ch <= 'Z' {
+			ch += 'a' - 'A'
+		}
+
+		buf.WriteByte(ch)
+	}
+
+	return collapsePlaceholderLists(buf.String())
+}
+
+// collapsePlaceholderLists replaces the lists of two or more placeholders,
+// e.g. (?, ?, ?) or VALUES (?, ?), (?, ?), by (?+).
+func collapsePlaceholderLists(s string) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '(' {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		n := 0
+		j := i + 1
+
+		for j < len(s) {
+			for j < len(s) && s[j] == ' ' {
+				j++
+			}
+
+			if j >= len(s) || s[j] != '?' {
+				break
+			}
+
+			n++
+			j++
+
+			for j < len(s) && s[j] == ' ' {
+				j++
+			}
+
+			if j >= len(s) || s[j] != ',' {
+				break
+			}
+
+			j++
+		}
+
+		if n < 2 || j >= len(s) || s[j] != ')' {
+			buf.WriteByte('(')
+			continue
+		}
+
+		buf.WriteString("(?+)")
+		i = j
+	}
+
+	collapsed := buf.String()
+
+	// Several rows of VALUES are one statement too.
+	for _, rows := range []string{"(?+), (?+)", "(?+),(?+)"} {
+		for strings.Contains(collapsed, rows) == true {
+			collapsed = strings.Replace(collapsed, rows, "(?+)", -1)
+		}
+	}
+
+	return collapsed
+}
@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM t WHERE id IN (1, 2, 3) /* x */", "select * from t where id in (?+)"},
+		{"select *\n\tfrom t  where name = 'O''Brien' -- comment\n", "select * from t where name = ?"},
+		{"INSERT INTO `Cities` (a, b) VALUES (1, 'x'), (2,'y'),(3, 'z')", "insert into `Cities` (a, b) values (?+)"},
+		{"SELECT ABS(-1), t1.c FROM t1 # trailing", "select abs(-?), t1.c from t1"},
+	}
+
+	for _, test := range tests {
+		if fingerprint := Fingerprint(test.query); fingerprint != test.expected {
+			t.Errorf("%q: got %q, want %q", test.query, fingerprint, test.expected)
+		}
+	}
+}
+
+func TestFirewallCheck(t *testing.T) {
+	var logged []string
+
+	firewall := &Firewall{
+		Rules: []FirewallRule{
+			{Name: "no-drop", Action: FIREWALL_DENY, Statement: "drop"},
+			{Name: "unbounded-delete", Action: FIREWALL_DENY, Statement: "DELETE", NoWhere: true},
+			{Name: "salaries", Action: FIREWALL_LOG, Table: "salaries"},
+			{Name: "lookup", Action: FIREWALL_ALLOW, Fingerprint: "select name from city where id = ?"},
+		},
+		Default: FIREWALL_DENY,
+		LogFunc: func(rule *FirewallRule, query string) {
+			if rule == nil {
+				logged = append(logged, "default")
+			} else {
+				logged = append(logged, rule.Name)
+			}
+		},
+	}
+
+	tests := []struct {
+		query string
+		rule  string // denied by, "" if allowed
+	}{
+		{"/* cleanup */ DROP TABLE t", "no-drop"},
+		{"DELETE FROM t", "unbounded-delete"},
+		{"DELETE FROM t WHERE 'where' = x", "default"},
+		{"UPDATE hr.`salaries` SET amount = 0 WHERE id = 1", ""},
+		{"SELECT name FROM city WHERE id = 42", ""},
+		{"SELECT name FROM city WHERE id = 'DROP'", ""},
+	}
+
+	for _, test := range tests {
+		err := firewall.Check(test.query)
+
+		if test.rule == "" && err != nil {
+			t.Errorf("%q: unexpected error %v", test.query, err)
+		}
+
+		if e, ok := err.(*FirewallError); test.rule != "" && (ok == false || (e.Rule != test.rule && (test.rule != "default" || e.Rule != ""))) {
+			t.Errorf("%q: unexpected error %v", test.query, err)
+		}
+	}
+
+	if len(logged) != 4 || logged[2] != "default" || logged[3] != "salaries" {
+		t.Errorf("unexpected log %q", logged)
+	}
+}
+
+func TestDriverFirewall(t *testing.T) {
+	c, _ := newPipeConnection(ConnectionParameter{
+		Firewall: &Firewall{Rules: []FirewallRule{{Name: "no-truncate", Action: FIREWALL_DENY, Statement: "TRUNCATE"}}},
+	})
+	dc := &driverConn{c: c}
+
+	// Denied before anything is written to the pipe, which has no reader.
+	_, err := dc.ExecContext(context.Background(), "TRUNCATE TABLE orders", nil)
+
+	if e, ok := err.(*FirewallError); ok == false || e.Rule != "no-truncate" || dc.IsValid() == false {
+		t.Errorf("unexpected error %v", err)
+	}
+}
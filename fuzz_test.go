@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+func FuzzParseInitPacket(f *testing.F) {
+	f.Add(initPacket())
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		c := NewConnection(ConnectionParameter{})
+
+		if c.parseInitPacket(payload) == nil && (len(c.ScramblePart1) != 8 || len(c.ScramblePart2) < 12) {
+			t.Errorf("accepted a scramble of %d+%d bytes", len(c.ScramblePart1), len(c.ScramblePart2))
+		}
+	})
+}
+
+func FuzzParseOKPacket(f *testing.F) {
+	f.Add(okPacket(1, 2, SERVER_STATUS_AUTOCOMMIT, 0, "Rows matched: 1  Changed: 1  Warnings: 0"))
+	f.Add([]byte{OK_PACKET, 0xfe, 1, 2, 3})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		c := NewConnection(ConnectionParameter{})
+		c.parseOKPacket(payload)
+	})
+}
+
+func FuzzParseErrPacket(f *testing.F) {
+	f.Add(append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.x' doesn't exist"...))
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		parseErrPacket(payload)
+	})
+}
+
+func FuzzColumnParse(f *testing.F) {
+	f.Add(columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45), uint8(0))
+
+	f.Fuzz(func(t *testing.T, payload []byte, options uint8) {
+		var col Column
+		col.parse(payload, protocol.ColumnOptions(options))
+	})
+}
+
+func FuzzParseTextRow(f *testing.F) {
+	f.Add(textRowPacket("1", nil, "Taipei"))
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		rows := &Rows{c: NewConnection(ConnectionParameter{MaxColumnSize: 16}), columns: make([]Column, 3)}
+		rows.parseTextRow(payload)
+	})
+}
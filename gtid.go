@@ -0,0 +1,474 @@
+package mysql
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidGTID = errors.New("Invalid GTID set")
+	ErrGTIDFlavor  = errors.New("MySQL and MariaDB GTID sets cannot be combined")
+)
+
+// GTIDSet is a set of global transaction identifiers, as found in
+// gtid_executed or gtid_purged on MySQL,
+//
+//	3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:11,9e41bd38-71ca-11e1-9e33-c80aa9429562:1-3:etl:1-20
+//
+// or in gtid_current_pos or gtid_slave_pos on MariaDB, a domain, server id
+// and sequence number per replication domain:
+//
+//	0-1-100,1-2-5
+//
+// A MariaDB set holds every transaction of a domain up to its sequence
+// number, from whichever server.
+//
+// The zero GTIDSet is empty, and combines with sets of either flavor.
+type GTIDSet struct {
+	mariaDB bool
+
+	// MySQL: the intervals of every source uuid, by tag ("" if untagged,
+	// MySQL 8.3+).
+	sources map[string]map[string][]gtidInterval
+
+	// MariaDB: the sequence number of every domain and server.
+	domains map[mariaDBGTIDKey]uint64
+}
+
+// gtidInterval is a range of transaction numbers, both ends included.
+type gtidInterval struct {
+	start uint64
+	end   uint64
+}
+
+type mariaDBGTIDKey struct {
+	domain uint32
+	server uint32
+}
+
+// ParseGTIDSet parses a MySQL or a MariaDB GTID set, telling them apart by
+// the colon following the uuids of MySQL. The whitespace and newlines MySQL
+// puts after the commas are ignored.
+func ParseGTIDSet(s string) (GTIDSet, error) {
+	if strings.Contains(s, ":") == true || strings.TrimSpace(s) == "" {
+		return ParseMySQLGTIDSet(s)
+	}
+
+	return ParseMariaDBGTIDSet(s)
+}
+
+// ParseMySQLGTIDSet parses a MySQL GTID set.
+func ParseMySQLGTIDSet(s string) (GTIDSet, error) {
+	set := GTIDSet{sources: make(map[string]map[string][]gtidInterval)}
+
+	for _, element := range strings.Split(s, ",") {
+		element = strings.TrimSpace(element)
+
+		if element == "" {
+			continue
+		}
+
+		parts := strings.Split(element, ":")
+
+		if len(parts) < 2 || isUUID(parts[0]) == false {
+			return GTIDSet{}, ErrInvalidGTID
+		}
+
+		uuid := strings.ToLower(parts[0])
+		tag := ""
+
+		for _, part := range parts[1:] {
+			if part == "" {
+				return GTIDSet{}, ErrInvalidGTID
+			}
+
+			if part[0] < '0' || part[0] > '9' {
+				if isGTIDTag(part) == false {
+					return GTIDSet{}, ErrInvalidGTID
+				}
+
+				tag = strings.ToLower(part)
+				continue
+			}
+
+			interval, err := parseGTIDInterval(part)
+
+			if err != nil {
+				return GTIDSet{}, err
+			}
+
+			set.add(uuid, tag, []gtidInterval{interval})
+		}
+	}
+
+	return set, nil
+}
+
+// ParseMariaDBGTIDSet parses a MariaDB GTID list.
+func ParseMariaDBGTIDSet(s string) (GTIDSet, error) {
+	set := GTIDSet{mariaDB: true, domains: make(map[mariaDBGTIDKey]uint64)}
+
+	for _, element := range strings.Split(s, ",") {
+		element = strings.TrimSpace(element)
+
+		if element == "" {
+			continue
+		}
+
+		parts := strings.Split(element, "-")
+
+		if len(parts) != 3 {
+			return GTIDSet{}, ErrInvalidGTID
+		}
+
+		domain, err1 := strconv.ParseUint(parts[0], 10, 32)
+		server, err2 := strconv.ParseUint(parts[1], 10, 32)
+		sequence, err3 := strconv.ParseUint(parts[2], 10, 64)
+
+		if err1 != nil || err2 != nil || err3 != nil {
+			return GTIDSet{}, ErrInvalidGTID
+		}
+
+		key := mariaDBGTIDKey{domain: uint32(domain), server: uint32(server)}
+
+		if sequence > set.domains[key] {
+			set.domains[key] = sequence
+		}
+	}
+
+	return set, nil
+}
+
+// isUUID reports whether s is a uuid in its 8-4-4-4-12 hex digit form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if s[i] != '-' {
+				return false
+			}
+		case s[i] >= '0' && s[i] <= '9', s[i] >= 'a' && s[i] <= 'f', s[i] >= 'A' && s[i] <= 'F':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// isGTIDTag reports whether s is a valid tag: up to 32 letters, digits and
+// underscores, not starting with a digit.
+func isGTIDTag(s string) bool {
+	if len(s) == 0 || len(s) > 32 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i] | 0x20
+
+		if (ch < 'a' || ch > 'z') && s[i] != '_' && (i == 0 || s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseGTIDInterval parses a transaction number or a range, e.g. "1-5".
+func parseGTIDInterval(s string) (gtidInterval, error) {
+	first, last := s, s
+
+	if dash := strings.IndexByte(s, '-'); dash >= 0 {
+		first, last = s[:dash], s[dash+1:]
+	}
+
+	start, err1 := strconv.ParseUint(first, 10, 63)
+	end, err2 := strconv.ParseUint(last, 10, 63)
+
+	if err1 != nil || err2 != nil || start == 0 || end < start {
+		return gtidInterval{}, ErrInvalidGTID
+	}
+
+	return gtidInterval{start: start, end: end}, nil
+}
+
+// IsMariaDB reports whether the set is in the MariaDB format.
+func (set GTIDSet) IsMariaDB() bool {
+	return set.mariaDB
+}
+
+// IsEmpty reports whether the set holds no transaction.
+func (set GTIDSet) IsEmpty() bool {
+	return len(set.sources) == 0 && len(set.domains) == 0
+}
+
+// String returns the set in the format of the server, sorted, with the
+// uuids in lower case.
+func (set GTIDSet) String() string {
+	var buf strings.Builder
+
+	if set.mariaDB == true {
+		keys := make([]mariaDBGTIDKey, 0, len(set.domains))
+
+		for key := range set.domains {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].domain != keys[j].domain {
+				return keys[i].domain < keys[j].domain
+			}
+
+			return keys[i].server < keys[j].server
+		})
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteString(strconv.FormatUint(uint64(key.domain), 10))
+			buf.WriteByte('-')
+			buf.WriteString(strconv.FormatUint(uint64(key.server), 10))
+			buf.WriteByte('-')
+			buf.WriteString(strconv.FormatUint(set.domains[key], 10))
+		}
+
+		return buf.String()
+	}
+
+	uuids := make([]string, 0, len(set.sources))
+
+	for uuid := range set.sources {
+		uuids = append(uuids, uuid)
+	}
+
+	sort.Strings(uuids)
+
+	for i, uuid := range uuids {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(uuid)
+
+		tags := make([]string, 0, len(set.sources[uuid]))
+
+		for tag := range set.sources[uuid] {
+			tags = append(tags, tag)
+		}
+
+		// The untagged intervals come first.
+		sort.Strings(tags)
+
+		for _, tag := range tags {
+			if tag != "" {
+				buf.WriteByte(':')
+				buf.WriteString(tag)
+			}
+
+			for _, interval := range set.sources[uuid][tag] {
+				buf.WriteByte(':')
+				buf.WriteString(strconv.FormatUint(interval.start, 10))
+
+				if interval.end != interval.start {
+					buf.WriteByte('-')
+					buf.WriteString(strconv.FormatUint(interval.end, 10))
+				}
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// Contains reports whether every transaction of other is in the set, e.g.
+// whether a replica whose gtid_executed is the set has applied other.
+func (set GTIDSet) Contains(other GTIDSet) bool {
+	if other.IsEmpty() == true {
+		return true
+	}
+
+	if set.IsEmpty() == true || set.mariaDB != other.mariaDB {
+		return false
+	}
+
+	if set.mariaDB == true {
+		for key, sequence := range other.domains {
+			if set.domainSequence(key.domain) < sequence {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for uuid, tags := range other.sources {
+		for tag, intervals := range tags {
+			have := set.sources[uuid][tag]
+
+			for _, interval := range intervals {
+				// The intervals are merged, a contained one lies within a
+				// single interval of the set.
+				i := sort.Search(len(have), func(i int) bool { return have[i].end >= interval.start })
+
+				if i == len(have) || have[i].start > interval.start || have[i].end < interval.end {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// domainSequence returns the highest sequence number of a MariaDB domain.
+func (set GTIDSet) domainSequence(domain uint32) uint64 {
+	var sequence uint64
+
+	for key, n := range set.domains {
+		if key.domain == domain && n > sequence {
+			sequence = n
+		}
+	}
+
+	return sequence
+}
+
+// Union returns the transactions in the set or in other. For MariaDB it
+// keeps the highest sequence number of every domain and server.
+func (set GTIDSet) Union(other GTIDSet) (GTIDSet, error) {
+	result, err := set.empty(other)
+
+	if err != nil {
+		return GTIDSet{}, err
+	}
+
+	for _, s := range []GTIDSet{set, other} {
+		for uuid, tags := range s.sources {
+			for tag, intervals := range tags {
+				result.add(uuid, tag, intervals)
+			}
+		}
+
+		for key, sequence := range s.domains {
+			if sequence > result.domains[key] {
+				result.domains[key] = sequence
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Subtract returns the transactions of the set which are not in other,
+// e.g. those a replica at other is missing. For MariaDB it returns the
+// GTIDs of the set whose domain other has not reached.
+func (set GTIDSet) Subtract(other GTIDSet) (GTIDSet, error) {
+	result, err := set.empty(other)
+
+	if err != nil {
+		return GTIDSet{}, err
+	}
+
+	for key, sequence := range set.domains {
+		if other.domainSequence(key.domain) < sequence {
+			result.domains[key] = sequence
+		}
+	}
+
+	for uuid, tags := range set.sources {
+		for tag, intervals := range tags {
+			remaining := subtractIntervals(intervals, other.sources[uuid][tag])
+
+			if len(remaining) > 0 {
+				result.add(uuid, tag, remaining)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// empty returns an empty set of the flavor of set and other.
+func (set GTIDSet) empty(other GTIDSet) (GTIDSet, error) {
+	mariaDB := set.mariaDB || other.mariaDB
+
+	if set.mariaDB != other.mariaDB && set.IsEmpty() == false && other.IsEmpty() == false {
+		return GTIDSet{}, ErrGTIDFlavor
+	}
+
+	if mariaDB == true {
+		return GTIDSet{mariaDB: true, domains: make(map[mariaDBGTIDKey]uint64)}, nil
+	}
+
+	return GTIDSet{sources: make(map[string]map[string][]gtidInterval)}, nil
+}
+
+// add merges intervals into those of a uuid and tag.
+func (set GTIDSet) add(uuid string, tag string, intervals []gtidInterval) {
+	tags := set.sources[uuid]
+
+	if tags == nil {
+		tags = make(map[string][]gtidInterval)
+		set.sources[uuid] = tags
+	}
+
+	merged := append(append([]gtidInterval{}, tags[tag]...), intervals...)
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].start < merged[j].start })
+
+	n := 0
+
+	for _, interval := range merged {
+		// Adjacent intervals are merged too, 1-3 and 4-5 being 1-5.
+		if n > 0 && interval.start <= merged[n-1].end+1 {
+			if interval.end > merged[n-1].end {
+				merged[n-1].end = interval.end
+			}
+
+			continue
+		}
+
+		merged[n] = interval
+		n++
+	}
+
+	tags[tag] = merged[:n]
+}
+
+// subtractIntervals returns the parts of the merged intervals a which are
+// not in the merged intervals b.
+func subtractIntervals(a []gtidInterval, b []gtidInterval) []gtidInterval {
+	var result []gtidInterval
+
+	for _, interval := range a {
+		for _, remove := range b {
+			if remove.end < interval.start || remove.start > interval.end {
+				continue
+			}
+
+			if remove.start > interval.start {
+				result = append(result, gtidInterval{start: interval.start, end: remove.start - 1})
+			}
+
+			interval.start = remove.end + 1
+
+			if interval.start > interval.end {
+				break
+			}
+		}
+
+		if interval.start <= interval.end {
+			result = append(result, interval)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"testing"
+)
+
+const (
+	GTID_UUID_A = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	GTID_UUID_B = "9e41bd38-71ca-11e1-9e33-c80aa9429562"
+)
+
+func TestParseGTIDSet(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"", ""},
+		{GTID_UUID_B + ":1-3,\n3E11FA47-71CA-11E1-9E33-C80AA9429562:7:1-5:6", GTID_UUID_A + ":1-7," + GTID_UUID_B + ":1-3"},
+		{GTID_UUID_A + ":1-5:ETL:3:1-2:11", GTID_UUID_A + ":1-5:etl:1-3:11"},
+		{"1-2-5, 0-1-100,0-1-90", "0-1-100,1-2-5"},
+	}
+
+	for _, test := range tests {
+		set, err := ParseGTIDSet(test.s)
+
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+			continue
+		}
+
+		if set.String() != test.expected {
+			t.Errorf("%q: got %q, want %q", test.s, set.String(), test.expected)
+		}
+	}
+
+	for _, s := range []string{GTID_UUID_A, GTID_UUID_A + ":0", GTID_UUID_A + ":5-3", GTID_UUID_A + ":1tag:1", "not-a-uuid:1", "0-1", "0-1-x"} {
+		if _, err := ParseGTIDSet(s); err != ErrInvalidGTID {
+			t.Errorf("%q: unexpected error %v", s, err)
+		}
+	}
+}
+
+func TestGTIDSetArithmetic(t *testing.T) {
+	parse := func(s string) GTIDSet {
+		set, err := ParseGTIDSet(s)
+
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+
+		return set
+	}
+
+	executed := parse(GTID_UUID_A + ":1-100:etl:1-10," + GTID_UUID_B + ":1-20")
+
+	if executed.Contains(parse(GTID_UUID_A+":5-50:100:etl:10,"+GTID_UUID_B+":20")) == false {
+		t.Error("contained transactions not found")
+	}
+
+	if executed.Contains(parse(GTID_UUID_A+":100-101")) == true || executed.Contains(parse(GTID_UUID_A+":other:1")) == true {
+		t.Error("missing transactions found")
+	}
+
+	union, err := executed.Union(parse(GTID_UUID_A + ":101-110:200," + GTID_UUID_B + ":25"))
+
+	if err != nil || union.String() != GTID_UUID_A+":1-110:200:etl:1-10,"+GTID_UUID_B+":1-20:25" {
+		t.Errorf("union %q: %v", union, err)
+	}
+
+	missing, err := executed.Subtract(parse(GTID_UUID_A + ":1-10:20-30:100:etl:1-10"))
+
+	if err != nil || missing.String() != GTID_UUID_A+":11-19:31-99,"+GTID_UUID_B+":1-20" {
+		t.Errorf("subtract %q: %v", missing, err)
+	}
+
+	position := parse("0-1-100,1-2-5")
+
+	if position.Contains(parse("0-3-90")) == false || position.Contains(parse("1-2-6")) == true {
+		t.Error("unexpected MariaDB containment")
+	}
+
+	union, err = position.Union(parse("0-1-120,2-1-1"))
+
+	if err != nil || union.String() != "0-1-120,1-2-5,2-1-1" {
+		t.Errorf("MariaDB union %q: %v", union, err)
+	}
+
+	missing, err = position.Subtract(parse("0-2-100"))
+
+	if err != nil || missing.String() != "1-2-5" {
+		t.Errorf("MariaDB subtract %q: %v", missing, err)
+	}
+
+	if _, err = executed.Union(position); err != ErrGTIDFlavor {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if union, err = (GTIDSet{}).Union(position); err != nil || union.IsMariaDB() == false || union.String() != position.String() {
+		t.Errorf("union with the zero set %q: %v", union, err)
+	}
+}
@@ -0,0 +1,145 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrReadOnly   = errors.New("Server is read only")
+	ErrReplicaLag = errors.New("Replica lag above MaxReplicaLag")
+)
+
+// DEFAULT_HEALTH_TIMEOUT bounds a health check when HealthHandler.Timeout
+// is zero.
+const DEFAULT_HEALTH_TIMEOUT = 2 * time.Second
+
+// Health is the JSON document served by HealthHandler.
+type Health struct {
+	Healthy  bool   `json:"healthy"`
+	Error    string `json:"error,omitempty"`
+	ReadOnly bool   `json:"read_only"`
+
+	// ReplicaLag is only reported with HealthHandler.ReplicaLag.
+	ReplicaLag *float64 `json:"replica_lag_seconds,omitempty"`
+
+	Pool HealthPool `json:"pool"`
+}
+
+// HealthPool reports the connections of the sql.DB.
+type HealthPool struct {
+	Open         int     `json:"open"`
+	InUse        int     `json:"in_use"`
+	Idle         int     `json:"idle"`
+	MaxOpen      int     `json:"max_open"`
+	WaitCount    int64   `json:"wait_count"`
+	WaitDuration float64 `json:"wait_seconds"`
+}
+
+// HealthHandler serves the health of a database as JSON for readiness
+// probes, with 200 OK when the server answers a ping and 503 Service
+// Unavailable otherwise, e.g.
+//
+//	http.Handle("/health/db", &mysql.HealthHandler{DB: db, Writable: true})
+type HealthHandler struct {
+	DB      *sql.DB
+	Timeout time.Duration // DEFAULT_HEALTH_TIMEOUT if zero
+
+	// Writable, for a primary, makes a server with read_only set
+	// unhealthy, as it is while being demoted by a failover.
+	Writable bool
+
+	// ReplicaLag, when set, measures the replication lag of the server,
+	// e.g. DBReplicaLag, which is unhealthy past MaxReplicaLag unless it
+	// is zero.
+	ReplicaLag    func(ctx context.Context, db *sql.DB) (time.Duration, error)
+	MaxReplicaLag time.Duration
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	health := h.Check(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if health.Healthy == false {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(health)
+}
+
+// Check runs the health check served by ServeHTTP.
+func (h *HealthHandler) Check(ctx context.Context) Health {
+	var health Health
+
+	timeout := h.Timeout
+
+	if timeout <= 0 {
+		timeout = DEFAULT_HEALTH_TIMEOUT
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := h.check(ctx, &health)
+
+	if err != nil {
+		health.Error = err.Error()
+	} else {
+		health.Healthy = true
+	}
+
+	stats := h.DB.Stats()
+
+	health.Pool = HealthPool{
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		MaxOpen:      stats.MaxOpenConnections,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration.Seconds(),
+	}
+
+	return health
+}
+
+// check fills in the state of the server, returning why it is unhealthy.
+func (h *HealthHandler) check(ctx context.Context, health *Health) error {
+	err := h.DB.PingContext(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	err = h.DB.QueryRowContext(ctx, "SELECT @@read_only").Scan(&health.ReadOnly)
+
+	if err != nil {
+		return err
+	}
+
+	if h.ReplicaLag != nil {
+		lag, err := h.ReplicaLag(ctx, h.DB)
+
+		if err != nil {
+			return err
+		}
+
+		seconds := lag.Seconds()
+		health.ReplicaLag = &seconds
+
+		if h.MaxReplicaLag > 0 && lag > h.MaxReplicaLag {
+			return ErrReplicaLag
+		}
+	}
+
+	if h.Writable == true && health.ReadOnly == true {
+		return ErrReadOnly
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.handshake()
+
+		for {
+			payload := server.readCommand()
+
+			if payload == nil {
+				return
+			}
+
+			switch {
+			case payload[0] == COM_QUERY && string(payload[1:]) == "SELECT @@read_only":
+				server.writeResultSet(1, [][]byte{
+					columnPacket("@@read_only", MYSQL_TYPE_LONGLONG, 0, 63),
+				}, [][]byte{
+					textRowPacket("1"),
+				}, SERVER_STATUS_AUTOCOMMIT)
+			case string(payload[1:]) == SESSION_VARIABLES_QUERY:
+				server.writeResultSet(1, [][]byte{
+					columnPacket("@@max_allowed_packet", MYSQL_TYPE_LONGLONG, 0, 63),
+					columnPacket("@@sql_mode", MYSQL_TYPE_VAR_STRING, 0, 45),
+					columnPacket("@@wait_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+					columnPacket("@@interactive_timeout", MYSQL_TYPE_LONGLONG, 0, 63),
+				}, [][]byte{
+					textRowPacket("4194304", "", "28800", "28800"),
+				}, SERVER_STATUS_AUTOCOMMIT)
+			case payload[0] == COM_PING || payload[0] == COM_RESET_CONNECTION:
+				server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+			default:
+				t.Errorf("unexpected command %q", payload)
+				return
+			}
+		}
+	}()
+
+	db := sql.OpenDB(NewConnector(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	}))
+
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	handler := &HealthHandler{
+		DB: db,
+		ReplicaLag: func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+			return 1500 * time.Millisecond, nil
+		},
+		MaxReplicaLag: 5 * time.Second,
+	}
+
+	serve := func() (int, Health) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/db", nil))
+
+		var health Health
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &health); err != nil {
+			t.Fatalf("%s: %v", recorder.Body, err)
+		}
+
+		return recorder.Code, health
+	}
+
+	code, health := serve()
+
+	if code != http.StatusOK || health.Healthy == false || health.ReadOnly == false ||
+		health.ReplicaLag == nil || *health.ReplicaLag != 1.5 || health.Pool.Open != 1 || health.Pool.MaxOpen != 1 {
+		t.Errorf("unexpected health %d %+v", code, health)
+	}
+
+	// The read only replica is no primary.
+	handler.Writable = true
+
+	code, health = serve()
+
+	if code != http.StatusServiceUnavailable || health.Healthy == true || health.Error != ErrReadOnly.Error() {
+		t.Errorf("unexpected health %d %+v", code, health)
+	}
+}
@@ -0,0 +1,355 @@
+package mysql
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DEFAULT_INFILE_CHUNK_SIZE is the payload size of each packet sent
+	// while streaming a LOCAL INFILE request.
+	DEFAULT_INFILE_CHUNK_SIZE = 64 * 1024
+)
+
+var (
+	ErrLocalInfileName    = errors.New("Server requested an unexpected LOCAL INFILE")
+	ErrLocalInfileRefused = errors.New("LOCAL INFILE refused without LocalInfileFS")
+	ErrCharacterSetName   = errors.New("Invalid Character Set Name")
+)
+
+// CSVImport describes how a CSV stream is loaded into a table with
+// LOAD DATA LOCAL INFILE.
+type CSVImport struct {
+	Table   string
+	Columns []string // optional, defaults to all columns in table order
+
+	FieldsTerminatedBy string // defaults to ","
+	FieldsEnclosedBy   string // defaults to `"`
+	LinesTerminatedBy  string // defaults to "\n"
+	IgnoreLines        int    // e.g. 1 to skip a header line
+
+	// CharacterSet is the character set of the stream, e.g. "utf8mb4",
+	// character_set_database if empty. It may only hold letters, digits
+	// and underscores.
+	CharacterSet string
+
+	// ChunkSize is the number of bytes sent per packet.
+	ChunkSize int
+
+	// Progress is called after every chunk sent to the server with the
+	// number of chunks and bytes sent so far. It runs before the next
+	// chunk is read, so sleeping in it slows the upload down.
+	Progress func(chunks int, bytes int64)
+}
+
+// statement builds the LOAD DATA statement for the import, quoting the
+// literals for the sql_mode of the connection. The character set name
+// cannot be quoted and is checked instead.
+func (imp *CSVImport) statement(c *Connection, fileName string) (string, error) {
+	if isCharacterSetName(imp.CharacterSet) == false {
+		return "", ErrCharacterSetName
+	}
+
+	fieldsTerminatedBy := imp.FieldsTerminatedBy
+	fieldsEnclosedBy := imp.FieldsEnclosedBy
+	linesTerminatedBy := imp.LinesTerminatedBy
+
+	if fieldsTerminatedBy == "" {
+		fieldsTerminatedBy = ","
+	}
+
+	if fieldsEnclosedBy == "" {
+		fieldsEnclosedBy = `"`
+	}
+
+	if linesTerminatedBy == "" {
+		linesTerminatedBy = "\n"
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("LOAD DATA LOCAL INFILE ")
+	buf.WriteString(c.QuoteString(fileName))
+	buf.WriteString(" INTO TABLE ")
+	buf.WriteString(QuoteIdentifier(imp.Table))
+
+	if imp.CharacterSet != "" {
+		buf.WriteString(" CHARACTER SET ")
+		buf.WriteString(imp.CharacterSet)
+	}
+
+	// CSV doubles the enclosing character instead of using backslashes.
+	buf.WriteString(" FIELDS TERMINATED BY ")
+	buf.WriteString(c.QuoteString(fieldsTerminatedBy))
+	buf.WriteString(" OPTIONALLY ENCLOSED BY ")
+	buf.WriteString(c.QuoteString(fieldsEnclosedBy))
+	buf.WriteString(" ESCAPED BY ''")
+	buf.WriteString(" LINES TERMINATED BY ")
+	buf.WriteString(c.QuoteString(linesTerminatedBy))
+
+	if imp.IgnoreLines > 0 {
+		buf.WriteString(" IGNORE ")
+		buf.WriteString(strconv.Itoa(imp.IgnoreLines))
+		buf.WriteString(" LINES")
+	}
+
+	if len(imp.Columns) > 0 {
+		buf.WriteString(" (")
+		buf.WriteString(quoteIdentifiers(imp.Columns))
+		buf.WriteString(")")
+	}
+
+	return buf.String(), nil
+}
+
+// isCharacterSetName reports whether name, if not empty, only holds
+// [A-Za-z0-9_] like every character set of MySQL and MariaDB.
+func isCharacterSetName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+
+		if (b < 'a' || b > 'z') && (b < 'A' || b > 'Z') && (b < '0' || b > '9') && b != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ImportCSV streams the CSV read from rd into a table through the
+// LOAD DATA LOCAL INFILE sub-protocol. The connection must be opened with
+// AllowLocalInfile and the server must have local_infile enabled.
+//
+// If reading rd fails halfway the transfer is still terminated normally, so
+// the server loads (and under autocommit commits) the chunks already sent.
+// The read error is then returned together with the *Result of that partial
+// load; run the import inside a transaction to roll it back.
+func (c *Connection) ImportCSV(rd io.Reader, imp CSVImport) (*Result, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	fileName := "csv::" + imp.Table
+	statement, err := imp.statement(c, fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	result, err := c.importCSV(rd, imp, statement, fileName)
+
+	c.audit(statement, start, result, err)
+	c.recordStatement(statement, start, 0, err)
+
+	return result, err
+}
+
+func (c *Connection) importCSV(rd io.Reader, imp CSVImport, statement string, fileName string) (*Result, error) {
+	var err error
+
+	err = c.writeCommand(COM_QUERY, []byte(statement))
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.readPacket()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) == 0 {
+		return nil, ErrMalformedPacket
+	}
+
+	switch payload[0] {
+	case OK_PACKET:
+		return c.parseOKPacket(payload)
+	case ERR_PACKET:
+		return nil, parseErrPacket(payload)
+	case LOCAL_INFILE_PACKET:
+		// The server must ask for the very file named in the statement.
+		if string(payload[1:]) != fileName {
+			err = c.sendLocalInfile(nil, 0, nil)
+
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = c.readOKPacket()
+
+			if _, ok := err.(*MySQLError); err != nil && !ok {
+				return nil, err
+			}
+
+			return nil, ErrLocalInfileName
+		}
+	default:
+		return nil, ErrUnexpectedPacket
+	}
+
+	//
+	err = c.sendLocalInfile(rd, imp.ChunkSize, imp.Progress)
+
+	readErr, isReadErr := err.(*localInfileReadError)
+
+	if err != nil && !isReadErr {
+		// A write failure leaves the connection unusable.
+		return nil, err
+	}
+
+	result, err := c.readOKPacket()
+
+	if isReadErr {
+		return result, readErr.err
+	}
+
+	return result, err
+}
+
+// answerLocalInfile answers the LOCAL INFILE request of a statement run
+// with Query. The file is read from LocalInfileFS, the request is refused
+// with an empty file and ErrLocalInfileRefused otherwise. ImportCSV
+// answers its own requests.
+func (c *Connection) answerLocalInfile(name string) (*Result, error) {
+	if c.param.LocalInfileFS == nil {
+		err := c.sendLocalInfile(nil, 0, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The server loads the empty file: the statement did not fail
+		// but nothing was loaded.
+		_, err = c.readOKPacket()
+
+		if _, ok := err.(*MySQLError); err != nil && !ok {
+			return nil, err
+		}
+
+		return nil, ErrLocalInfileRefused
+	}
+
+	// Absolute names are taken from the root of the file system, names
+	// reaching out of it with .. are refused by every fs.FS.
+	var file fs.File
+	var openErr error
+
+	if path := strings.TrimPrefix(name, "/"); fs.ValidPath(path) == true {
+		file, openErr = c.param.LocalInfileFS.Open(path)
+	} else {
+		openErr = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	var rd io.Reader
+	var progress func(chunks int, bytes int64)
+
+	if openErr == nil {
+		defer file.Close()
+		rd = file
+	}
+
+	if c.param.LocalInfileProgress != nil {
+		progress = func(chunks int, bytes int64) {
+			c.param.LocalInfileProgress(name, chunks, bytes)
+		}
+	}
+
+	err := c.sendLocalInfile(rd, 0, progress)
+
+	readErr, isReadErr := err.(*localInfileReadError)
+
+	if err != nil && !isReadErr {
+		return nil, err
+	}
+
+	result, err := c.readOKPacket()
+
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	if isReadErr {
+		return result, readErr.err
+	}
+
+	return result, err
+}
+
+// localInfileReadError wraps an error of the local reader. The protocol is
+// still in sync when it happens because the empty terminating packet is
+// sent regardless, which also means the server keeps what was sent.
+type localInfileReadError struct {
+	err error
+}
+
+func (e *localInfileReadError) Error() string {
+	return e.err.Error()
+}
+
+// sendLocalInfile sends the contents of rd in chunks followed by the empty
+// packet that ends the transfer. A nil reader sends an empty file, which is
+// how a request is refused.
+func (c *Connection) sendLocalInfile(rd io.Reader, chunkSize int, progress func(chunks int, bytes int64)) error {
+	var err error
+	var readErr error
+
+	if chunkSize <= 0 || chunkSize > MAX_PACKET_SIZE-1 {
+		chunkSize = DEFAULT_INFILE_CHUNK_SIZE
+	}
+
+	if c.maxAllowedPacket > 0 && chunkSize > c.maxAllowedPacket {
+		chunkSize = c.maxAllowedPacket
+	}
+
+	if rd != nil {
+		chunks := 0
+		total := int64(0)
+		byteArr := make([]byte, chunkSize)
+
+		for {
+			n, rdErr := io.ReadFull(rd, byteArr)
+
+			if n > 0 {
+				err = c.writePacket(byteArr[:n])
+
+				if err != nil {
+					return err
+				}
+
+				chunks++
+				total += int64(n)
+
+				if progress != nil {
+					progress(chunks, total)
+				}
+			}
+
+			if rdErr == io.EOF || rdErr == io.ErrUnexpectedEOF {
+				break
+			}
+
+			if rdErr != nil {
+				readErr = &localInfileReadError{err: rdErr}
+				break
+			}
+		}
+	}
+
+	// empty packet [0 bytes]
+	err = c.writePacket(nil)
+
+	if err != nil {
+		return err
+	}
+
+	return readErr
+}
@@ -0,0 +1,226 @@
+package mysql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCSVImportStatement(t *testing.T) {
+	imp := CSVImport{
+		Table:       "city",
+		Columns:     []string{"id", "name"},
+		IgnoreLines: 1,
+	}
+
+	expected := "LOAD DATA LOCAL INFILE 'csv::city' INTO TABLE `city`" +
+		" FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '\\\"' ESCAPED BY ''" +
+		" LINES TERMINATED BY '\\n' IGNORE 1 LINES (`id`, `name`)"
+
+	if got, err := imp.statement(new(Connection), "csv::city"); err != nil || got != expected {
+		t.Errorf("statement:\n got %s %v\nwant %s", got, err, expected)
+	}
+
+	imp.CharacterSet = "utf8mb4"
+
+	if got, err := imp.statement(new(Connection), "csv::city"); err != nil || strings.Contains(got, " CHARACTER SET utf8mb4 ") == false {
+		t.Errorf("character set: got %s %v", got, err)
+	}
+
+	imp.CharacterSet = "utf8mb4 FIELDS TERMINATED BY ';' -- "
+
+	if _, err := imp.statement(new(Connection), "csv::city"); err != ErrCharacterSetName {
+		t.Errorf("hostile character set: got %v", err)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{AllowLocalInfile: true})
+	server := newFakeServer(t, serverConn)
+
+	csv := "1,Taipei\n2,Kaohsiung\n3,Tainan\n"
+	received := new(bytes.Buffer)
+
+	go func() {
+		seq, payload := server.readPacket()
+
+		if seq != 0 || payload[0] != COM_QUERY {
+			t.Errorf("unexpected command %d %q", seq, payload)
+		}
+
+		server.writePacket(1, append([]byte{LOCAL_INFILE_PACKET}, "csv::city"...))
+
+		for seq = 2; ; seq++ {
+			gotSeq, payload := server.readPacket()
+
+			if gotSeq != seq {
+				t.Errorf("sequence: got %d want %d", gotSeq, seq)
+			}
+
+			if len(payload) == 0 {
+				break
+			}
+
+			received.Write(payload)
+		}
+
+		server.writePacket(seq+1, okPacket(3, 0, 0, 0, "Records: 3  Deleted: 0  Skipped: 0  Warnings: 0"))
+	}()
+
+	var progress []int64
+
+	result, err := c.ImportCSV(strings.NewReader(csv), CSVImport{
+		Table:     "city",
+		ChunkSize: 10,
+		Progress: func(chunks int, bytes int64) {
+			progress = append(progress, bytes)
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.AffectedRows() != 3 {
+		t.Errorf("affected rows: got %d want 3", result.AffectedRows())
+	}
+
+	if received.String() != csv {
+		t.Errorf("received %q want %q", received.String(), csv)
+	}
+
+	if len(progress) != 3 || progress[2] != int64(len(csv)) {
+		t.Errorf("unexpected progress %v", progress)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(byteArr []byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+func TestImportCSVReaderError(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{AllowLocalInfile: true})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readPacket()
+		server.writePacket(1, append([]byte{LOCAL_INFILE_PACKET}, "csv::city"...))
+
+		// The client still terminates the transfer with an empty packet.
+		_, payload := server.readPacket()
+
+		if len(payload) != 0 {
+			t.Errorf("expected empty packet, got %q", payload)
+		}
+
+		server.writePacket(3, okPacket(0, 0, 0, 0, ""))
+	}()
+
+	result, err := c.ImportCSV(failingReader{}, CSVImport{Table: "city"})
+
+	if err == nil || err.Error() != "disk on fire" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The partial load is reported along with the read error.
+	if result == nil {
+		t.Errorf("expected the result of the partial load")
+	}
+}
+
+func TestLocalInfileFS(t *testing.T) {
+	var progress []string
+
+	c, serverConn := newPipeConnection(ConnectionParameter{
+		AllowLocalInfile: true,
+		LocalInfileFS: fstest.MapFS{
+			"data/city.csv": &fstest.MapFile{Data: []byte("1,Taipei\n2,Tainan\n")},
+		},
+		LocalInfileProgress: func(name string, chunks int, bytes int64) {
+			progress = append(progress, fmt.Sprintf("%s:%d:%d", name, chunks, bytes))
+		},
+	})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		for _, name := range []string{"/data/city.csv", "../etc/passwd"} {
+			server.readCommand()
+			server.writePacket(1, append([]byte{LOCAL_INFILE_PACKET}, name...))
+
+			var data []byte
+			seq := uint8(2)
+
+			for {
+				_, payload := server.readPacket()
+				seq++
+
+				if len(payload) == 0 {
+					break
+				}
+
+				data = append(data, payload...)
+			}
+
+			if name == "/data/city.csv" && string(data) != "1,Taipei\n2,Tainan\n" || name != "/data/city.csv" && len(data) != 0 {
+				t.Errorf("%s: unexpected data %q", name, data)
+			}
+
+			server.writePacket(seq, okPacket(2, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+		}
+	}()
+
+	rows, err := c.Query("LOAD DATA LOCAL INFILE '/data/city.csv' INTO TABLE city FIELDS TERMINATED BY ','")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Result().AffectedRows() != 2 {
+		t.Errorf("unexpected result %+v", rows.Result())
+	}
+
+	rows.Close()
+
+	if len(progress) != 1 || progress[0] != "/data/city.csv:1:18" {
+		t.Errorf("unexpected progress %q", progress)
+	}
+
+	// Nothing outside of the file system is sent.
+	if _, err = c.Query("LOAD DATA LOCAL INFILE '../etc/passwd' INTO TABLE t"); errors.Is(err, fs.ErrInvalid) == false {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLocalInfileRefused(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{AllowLocalInfile: true})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, append([]byte{LOCAL_INFILE_PACKET}, "/etc/passwd"...))
+
+		if _, payload := server.readPacket(); len(payload) != 0 {
+			t.Errorf("unexpected data %q", payload)
+		}
+
+		server.writePacket(3, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	if _, err := c.Query("LOAD DATA LOCAL INFILE '/etc/passwd' INTO TABLE t"); err != ErrLocalInfileRefused {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The response was read: the connection is still usable.
+	if _, err := c.Exec("DO 1"); err != nil {
+		t.Error(err)
+	}
+}
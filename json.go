@@ -0,0 +1,170 @@
+package mysql
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"unicode/utf8"
+)
+
+// WriteJSON writes the rows of the result set to w as a JSON array of
+// objects keyed by column name, streaming them without building maps.
+// Numbers are written as numbers (DECIMAL keeps its precision), NULL as
+// null, JSON columns as they are, binary strings in base64 like
+// encoding/json does for []byte, and other values as strings.
+func (r *Rows) WriteJSON(w io.Writer) error {
+	return r.writeJSON(w, false)
+}
+
+// WriteNDJSON is like WriteJSON but writes one object per line instead of
+// an array.
+func (r *Rows) WriteNDJSON(w io.Writer) error {
+	return r.writeJSON(w, true)
+}
+
+func (r *Rows) writeJSON(w io.Writer, ndjson bool) error {
+	bw := bufio.NewWriter(w)
+
+	// The quoted names followed by a colon, computed once.
+	names := make([][]byte, len(r.columns))
+
+	for i := range r.columns {
+		names[i] = append(appendJSONString(nil, []byte(r.columns[i].Name)), ':')
+	}
+
+	var buf []byte
+	n := 0
+
+	if ndjson == false {
+		bw.WriteByte('[')
+	}
+
+	for r.Next() == true {
+		buf = buf[:0]
+
+		if ndjson == false && n > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = append(buf, '{')
+
+		for i, value := range r.values {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+
+			buf = append(buf, names[i]...)
+			buf = appendJSONValue(buf, &r.columns[i], value)
+		}
+
+		buf = append(buf, '}')
+
+		if ndjson == true {
+			buf = append(buf, '\n')
+		}
+
+		_, err := bw.Write(buf)
+
+		if err != nil {
+			return err
+		}
+
+		n++
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	if ndjson == false {
+		bw.WriteByte(']')
+	}
+
+	return bw.Flush()
+}
+
+// appendJSONValue appends the JSON for a text protocol value of col.
+func appendJSONValue(buf []byte, col *Column, value []byte) []byte {
+	if value == nil {
+		return append(buf, "null"...)
+	}
+
+	switch col.Type {
+	case MYSQL_TYPE_TINY, MYSQL_TYPE_SHORT, MYSQL_TYPE_INT24, MYSQL_TYPE_LONG, MYSQL_TYPE_LONGLONG,
+		MYSQL_TYPE_YEAR, MYSQL_TYPE_FLOAT, MYSQL_TYPE_DOUBLE, MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL:
+		return append(buf, value...)
+	case MYSQL_TYPE_JSON:
+		return append(buf, value...)
+	case MYSQL_TYPE_BIT, MYSQL_TYPE_GEOMETRY:
+		return appendJSONBase64(buf, value)
+	case MYSQL_TYPE_TINY_BLOB, MYSQL_TYPE_MEDIUM_BLOB, MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BLOB,
+		MYSQL_TYPE_VARCHAR, MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING:
+		if col.ExtendedFormat == "json" {
+			return append(buf, value...)
+		}
+
+		if col.CharacterSet == BINARY_COLLATION {
+			return appendJSONBase64(buf, value)
+		}
+	}
+
+	return appendJSONString(buf, value)
+}
+
+func appendJSONBase64(buf []byte, value []byte) []byte {
+	n := len(buf)
+	buf = append(buf, make([]byte, base64.StdEncoding.EncodedLen(len(value))+2)...)
+	buf[n] = '"'
+	base64.StdEncoding.Encode(buf[n+1:], value)
+	buf[len(buf)-1] = '"'
+
+	return buf
+}
+
+// appendJSONString appends s as a JSON string, replacing invalid UTF-8 with
+// U+FFFD like encoding/json.
+func appendJSONString(buf []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+
+	buf = append(buf, '"')
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			buf = append(buf, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			// Valid JSON, but not JavaScript.
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			buf = append(buf, s[i:i+size]...)
+		}
+
+		i += size
+	}
+
+	return append(buf, '"')
+}
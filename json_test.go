@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRowsWriteJSON(t *testing.T) {
+	for _, ndjson := range []bool{false, true} {
+		c, serverConn := newPipeConnection(ConnectionParameter{})
+		server := newFakeServer(t, serverConn)
+
+		go func() {
+			server.readCommand()
+			server.writeResultSet(1, [][]byte{
+				columnPacket("id", MYSQL_TYPE_LONGLONG, UNSIGNED_FLAG, 63),
+				columnPacket("price", MYSQL_TYPE_NEWDECIMAL, 0, 63),
+				columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 33),
+				columnPacket("raw", MYSQL_TYPE_BLOB, BLOB_FLAG|BINARY_FLAG, 63),
+				columnPacket("doc", MYSQL_TYPE_JSON, BLOB_FLAG|BINARY_FLAG, 63),
+			}, [][]byte{
+				textRowPacket("18446744073709551615", "12345678901234567890.12", "say \"hi\"\n\x01\xff\u2028", "\x00\xff", `{"a": [1, 2]}`),
+				textRowPacket("2", nil, "", nil, nil),
+			}, SERVER_STATUS_AUTOCOMMIT)
+		}()
+
+		rows, err := c.Query("SELECT * FROM t")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if ndjson == true {
+			err = rows.WriteNDJSON(&buf)
+		} else {
+			err = rows.WriteJSON(&buf)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first := `{"id":18446744073709551615,"price":12345678901234567890.12,"name":"say \"hi\"\n\u0001` + "\ufffd" + `\u2028","raw":"AP8=","doc":{"a": [1, 2]}}`
+		second := `{"id":2,"price":null,"name":"","raw":null,"doc":null}`
+		expected := "[" + first + "," + second + "]"
+
+		if ndjson == true {
+			expected = first + "\n" + second + "\n"
+		}
+
+		if buf.String() != expected {
+			t.Errorf("got  %s\nwant %s", buf.String(), expected)
+		}
+
+		if ndjson == false && json.Valid(buf.Bytes()) == false {
+			t.Errorf("invalid JSON %s", buf.String())
+		}
+	}
+}
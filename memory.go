@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrMemoryBudget = errors.New("Memory budget exceeded")
+
+// MemoryBudget caps the memory the connections sharing it, e.g. those of a
+// Connector, hold in packet buffers and in the rows Pipeline buffers
+// before returning them. The accounting is approximate: it counts the
+// buffers the connections keep between packets, not the copies the
+// application makes, and a connection's buffers are only given back when
+// it drops them or is closed.
+type MemoryBudget struct {
+	limit int64
+	wait  time.Duration
+
+	mutex    sync.Mutex
+	used     int64
+	released chan struct{} // closed and replaced whenever memory is given back
+}
+
+// NewMemoryBudget returns a MemoryBudget of limit bytes. A buffer which
+// does not fit waits up to wait for other connections to give memory
+// back, then fails with ErrMemoryBudget.
+func NewMemoryBudget(limit int64, wait time.Duration) *MemoryBudget {
+	return &MemoryBudget{
+		limit:    limit,
+		wait:     wait,
+		released: make(chan struct{}),
+	}
+}
+
+// InUse returns the number of bytes currently accounted for.
+func (b *MemoryBudget) InUse() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.used
+}
+
+// acquire accounts for n more bytes, waiting for them if needed.
+func (b *MemoryBudget) acquire(n int64) error {
+	if n <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(b.wait)
+
+	b.mutex.Lock()
+
+	for b.used+n > b.limit {
+		remaining := time.Until(deadline)
+
+		if n > b.limit || remaining <= 0 {
+			b.mutex.Unlock()
+			return ErrMemoryBudget
+		}
+
+		released := b.released
+		b.mutex.Unlock()
+
+		timer := time.NewTimer(remaining)
+
+		select {
+		case <-released:
+		case <-timer.C:
+		}
+
+		timer.Stop()
+
+		b.mutex.Lock()
+	}
+
+	b.used += n
+
+	b.mutex.Unlock()
+
+	return nil
+}
+
+// release gives n bytes back.
+func (b *MemoryBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.used -= n
+
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// growReadBuf accounts for the read buffer of the connection growing to
+// size bytes.
+func (c *Connection) growReadBuf(size int) error {
+	if c.param.MemoryBudget == nil || int64(size) <= c.readBufMemory {
+		return nil
+	}
+
+	err := c.param.MemoryBudget.acquire(int64(size) - c.readBufMemory)
+
+	if err != nil {
+		return err
+	}
+
+	c.readBufMemory = int64(size)
+
+	return nil
+}
+
+// dropReadBuf gives the memory of the read buffer back.
+func (c *Connection) dropReadBuf() {
+	c.readBuf = nil
+
+	if c.param.MemoryBudget != nil {
+		c.param.MemoryBudget.release(c.readBufMemory)
+	}
+
+	c.readBufMemory = 0
+}
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	budget := NewMemoryBudget(100, 50*time.Millisecond)
+
+	if err := budget.acquire(80); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := budget.acquire(101); err != ErrMemoryBudget {
+		t.Errorf("larger than the limit: %v", err)
+	}
+
+	// Given back while waiting.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		budget.release(50)
+	}()
+
+	if err := budget.acquire(40); err != nil || budget.InUse() != 70 {
+		t.Fatalf("in use %d: %v", budget.InUse(), err)
+	}
+
+	start := time.Now()
+
+	if err := budget.acquire(40); err != ErrMemoryBudget || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("unexpected error %v after %v", err, time.Since(start))
+	}
+}
+
+func TestMemoryBudgetReadBuf(t *testing.T) {
+	budget := NewMemoryBudget(1024, 0)
+	c, serverConn := newPipeConnection(ConnectionParameter{MemoryBudget: budget})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		server.readCommand()
+		server.writePacket(1, make([]byte, 2048))
+	}()
+
+	rows, err := c.Query("DO 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows.Close()
+
+	if budget.InUse() == 0 || budget.InUse() > 1024 {
+		t.Errorf("read buffer accounted as %d bytes", budget.InUse())
+	}
+
+	if _, err = c.Query("SELECT REPEAT('x', 2048)"); err != ErrMemoryBudget || c.broken == false {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	c.Close()
+
+	if budget.InUse() != 0 {
+		t.Errorf("%d bytes still accounted after Close", budget.InUse())
+	}
+}
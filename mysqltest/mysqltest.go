@@ -0,0 +1,264 @@
+// Package mysqltest provides a server to the integration tests of code
+// written against this client: the one of MYSQL_TEST_DSN, or a container
+// started with Docker when MYSQL_TEST_DOCKER names an image. Tests are
+// skipped when neither is set. Every test gets a throwaway database which
+// is dropped when it ends.
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		mysqltest.Stop()
+//		os.Exit(code)
+//	}
+//
+//	func TestOrders(t *testing.T) {
+//		c := mysqltest.Connect(t)
+//		...
+//	}
+package mysqltest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure"
+)
+
+const (
+	ENV_DSN         = "MYSQL_TEST_DSN"         // DSN of an existing server, see mysql.ParseDSN
+	ENV_DOCKER      = "MYSQL_TEST_DOCKER"      // image to start, e.g. mariadb:10.11
+	ENV_DOCKER_ARGS = "MYSQL_TEST_DOCKER_ARGS" // extra server options, space separated
+
+	// STARTUP_TIMEOUT bounds the wait for a started container to accept
+	// connections, which includes the initialization of its data.
+	STARTUP_TIMEOUT = 2 * time.Minute
+
+	rootPassword = "mysqltest"
+)
+
+var (
+	ErrNoServer = errors.New("No test server: set " + ENV_DSN + " or " + ENV_DOCKER)
+)
+
+var (
+	once      sync.Once
+	server    mysql.ConnectionParameter
+	serverErr error
+	container string
+)
+
+// Server returns the parameters of the test server, starting the container
+// on the first call. The test is skipped when no server is configured, and
+// fails when it cannot be started.
+func Server(t testing.TB) mysql.ConnectionParameter {
+	t.Helper()
+
+	once.Do(func() {
+		server, serverErr = start()
+	})
+
+	if serverErr == ErrNoServer {
+		t.Skip(serverErr)
+	}
+
+	if serverErr != nil {
+		t.Fatal(serverErr)
+	}
+
+	return server
+}
+
+// Database creates a database for the test and drops it when the test
+// ends. The returned parameters select it.
+func Database(t testing.TB) mysql.ConnectionParameter {
+	t.Helper()
+
+	param := Server(t)
+	param.DBName = databaseName(t.Name())
+
+	admin := open(t, Server(t))
+
+	run(t, admin, "CREATE DATABASE "+admin.QuoteIdentifier(param.DBName))
+
+	t.Cleanup(func() {
+		run(t, admin, "DROP DATABASE "+admin.QuoteIdentifier(param.DBName))
+		admin.Close()
+	})
+
+	return param
+}
+
+// Connect returns a connection to a database created for the test, see
+// Database. The connection is closed when the test ends.
+func Connect(t testing.TB) *mysql.Connection {
+	t.Helper()
+
+	c := open(t, Database(t))
+
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	return c
+}
+
+// Stop removes the container started by Server, if any.
+func Stop() error {
+	if container == "" {
+		return nil
+	}
+
+	err := exec.Command("docker", "rm", "-f", container).Run()
+
+	container = ""
+
+	return err
+}
+
+func open(t testing.TB, param mysql.ConnectionParameter) *mysql.Connection {
+	t.Helper()
+
+	c := mysql.NewConnection(param)
+
+	err := c.Open()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func run(t testing.TB, c *mysql.Connection, query string) {
+	t.Helper()
+
+	rows, err := c.Query(query)
+
+	if err == nil {
+		err = rows.Close()
+	}
+
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// databaseName returns a unique database name for a test.
+func databaseName(testName string) string {
+	var buf strings.Builder
+
+	buf.WriteString("test_")
+
+	for _, ch := range strings.ToLower(testName) {
+		if buf.Len() >= 40 {
+			break
+		}
+
+		if ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' {
+			buf.WriteRune(ch)
+		} else {
+			buf.WriteByte('_')
+		}
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	buf.WriteByte('_')
+	buf.WriteString(hex.EncodeToString(suffix))
+
+	return buf.String()
+}
+
+// start returns the parameters of the configured server, starting the
+// container if needed.
+func start() (mysql.ConnectionParameter, error) {
+	if dsn := os.Getenv(ENV_DSN); dsn != "" {
+		return mysql.ParseDSN(dsn)
+	}
+
+	image := os.Getenv(ENV_DOCKER)
+
+	if image == "" {
+		return mysql.ConnectionParameter{}, ErrNoServer
+	}
+
+	args := []string{"run", "--detach", "--rm",
+		"--env", "MYSQL_ROOT_PASSWORD=" + rootPassword,
+		"--publish", "127.0.0.1::3306",
+		image,
+	}
+
+	args = append(args, strings.Fields(os.Getenv(ENV_DOCKER_ARGS))...)
+
+	out, err := exec.Command("docker", args...).Output()
+
+	if err != nil {
+		return mysql.ConnectionParameter{}, dockerError("docker run", err)
+	}
+
+	container = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", container, "3306/tcp").Output()
+
+	if err != nil {
+		Stop()
+		return mysql.ConnectionParameter{}, dockerError("docker port", err)
+	}
+
+	// e.g. 127.0.0.1:49153, possibly followed by the IPv6 mapping.
+	address := strings.Fields(string(out))
+
+	if len(address) == 0 || strings.LastIndex(address[0], ":") < 0 {
+		Stop()
+		return mysql.ConnectionParameter{}, errors.New("docker port: no port published")
+	}
+
+	colon := strings.LastIndex(address[0], ":")
+
+	param := mysql.ConnectionParameter{
+		Network:  "tcp",
+		Host:     address[0][:colon],
+		Port:     address[0][colon+1:],
+		Username: "root",
+		Password: mysql.NewSecret(rootPassword),
+	}
+
+	// The server restarts once while initializing its data.
+	deadline := time.Now().Add(STARTUP_TIMEOUT)
+
+	for {
+		c := mysql.NewConnection(param)
+
+		err = c.Open()
+
+		if err == nil {
+			c.Close()
+			return param, nil
+		}
+
+		if time.Now().After(deadline) == true {
+			Stop()
+			return mysql.ConnectionParameter{}, err
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// dockerError adds the output of a failed docker command to its error.
+func dockerError(command string, err error) error {
+	var exitErr *exec.ExitError
+
+	if errors.As(err, &exitErr) {
+		return errors.New(command + ": " + strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return err
+}
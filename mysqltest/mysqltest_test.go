@@ -0,0 +1,34 @@
+package mysqltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDatabaseName(t *testing.T) {
+	name := databaseName("TestOrders/with spaces & a very long subtest name that goes on")
+
+	if strings.HasPrefix(name, "test_testorders_with_spaces___a_very_lon_") == false || len(name) != 40+1+8 {
+		t.Errorf("unexpected name %q", name)
+	}
+
+	if databaseName("TestX") == databaseName("TestX") {
+		t.Error("names are not unique")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	c := Connect(t)
+
+	rows, err := c.Query("SELECT DATABASE()")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false || strings.HasPrefix(string(rows.Values()[0]), "test_testconnect_") == false {
+		t.Errorf("unexpected database %q", rows.Values())
+	}
+
+	rows.Close()
+}
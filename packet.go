@@ -2,7 +2,17 @@ package mysql
 
 import (
 	"bufio"
-	"fmt"
+	"net"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+const (
+	OK_PACKET           = 0x00
+	LOCAL_INFILE_PACKET = 0xfb
+	EOF_PACKET          = 0xfe
+	ERR_PACKET          = 0xff
 )
 
 type PacketHeader struct {
@@ -30,11 +40,9 @@ func ReadPacket(rd *bufio.Reader, byteArr []byte) error {
 	var numOfBytes int
 	var err error
 
-	for i := 0; i < len(byteArr); i++ {
+	for i := 0; i < len(byteArr); {
 		numOfBytes, err = rd.Read(byteArr[i:])
 
-		fmt.Printf("====== Debug Read: %d\n", numOfBytes)
-
 		if err != nil {
 			return err
 		}
@@ -45,9 +53,10 @@ func ReadPacket(rd *bufio.Reader, byteArr []byte) error {
 	return nil
 }
 
+// IgnoreBytes discards n bytes without allocating a buffer of that size.
 func IgnoreBytes(rd *bufio.Reader, n uint64) error {
-	byteArr := make([]byte, n)
-	return ReadPacket(rd, byteArr)
+	_, err := rd.Discard(int(n))
+	return err
 }
 
 func UnpackNumber(byteArr []byte, n uint8) uint64 {
@@ -59,3 +68,453 @@ func UnpackNumber(byteArr []byte, n uint8) uint64 {
 
 	return num
 }
+
+// unpackLenEncInt decodes a length encoded integer. It returns the number,
+// whether the value was the NULL marker (0xfb) and the number of bytes used.
+func unpackLenEncInt(byteArr []byte) (uint64, bool, int) {
+	return protocol.ParseLenEncInt(byteArr)
+}
+
+// DIRECT_IO_READ_BUFFER_SIZE is the read buffer size of DirectIO
+// connections, enough for the response to a point query.
+const DIRECT_IO_READ_BUFFER_SIZE = 512
+
+// MAX_READ_BUFFER_SIZE is the largest read buffer a connection keeps
+// between packets. Larger payloads get a buffer of their own.
+const MAX_READ_BUFFER_SIZE = 1 << 20
+
+// readPacket reads the payload of the next packet, joining payloads which
+// the server had to split into several MAX_PACKET_SIZE-1 sized packets.
+// The payload is only valid until the next call: its buffer is reused so
+// that reading rows does not allocate.
+func (c *Connection) readPacket() ([]byte, error) {
+	if cap(c.readBuf) > MAX_READ_BUFFER_SIZE {
+		c.dropReadBuf()
+	}
+
+	payload := c.readBuf[:0]
+	interruptible := c.interruptible
+
+	for {
+		packetHeader, err := c.readPacketHeader()
+
+		if err != nil {
+			return nil, err
+		}
+
+		n := len(payload)
+
+		payload, err = c.appendPayload(payload, packetHeader.Len)
+
+		if err != nil {
+			return nil, err
+		}
+
+		byteArr := payload[n:]
+
+		if packetHeader.Seq != c.sequence {
+			c.broken = true
+
+			// A server closing an idle connection (wait_timeout, shutdown,
+			// KILL) sends an ERR packet before it reads our command.
+			// Only those codes prove the command was never read.
+			if packetHeader.Seq == 0 && c.sequence == 1 && len(byteArr) > 0 && byteArr[0] == ERR_PACKET {
+				err = parseErrPacket(byteArr)
+
+				if isFatalServerError(err) == true {
+					c.unsent = true
+				}
+
+				return nil, err
+			}
+
+			return nil, ErrUnexpectedSequence
+		}
+
+		c.sequence++
+
+		if packetHeader.Len < MAX_PACKET_SIZE-1 {
+			c.readBuf = payload
+
+			if isProgressPacket(payload) == true && c.mariaDBCapabilities&MARIADB_CLIENT_PROGRESS != 0 {
+				c.reportProgress(payload)
+
+				// The response is still to come.
+				c.interruptible = interruptible
+				payload = payload[:0]
+				continue
+			}
+
+			return payload, nil
+		}
+	}
+}
+
+// readPacketHeader reads the header of the next packet and restarts the read
+// deadline.
+func (c *Connection) readPacketHeader() (PacketHeader, error) {
+	if c.readTimeout > 0 && c.draining == false {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+
+	// The deadline set above must not hide a context done in the meantime.
+	if c.watch != nil && c.watch.ctx.Err() != nil {
+		c.conn.SetReadDeadline(time.Unix(1, 0))
+	}
+
+	if c.interruptible == true && (c.watch != nil || c.draining == true) {
+		c.interruptible = false
+
+		// Waiting with Peek leaves the stream as it is when interrupted.
+		_, err := c.reader.Peek(len(c.header))
+
+		if err != nil && isTimeout(err) == true {
+			return PacketHeader{}, errInterrupted
+		}
+	}
+
+	c.interruptible = false
+
+	// The header is read into the connection rather than with
+	// ReadPacketHeader, which allocates.
+	err := ReadPacket(c.reader, c.header[:])
+
+	if err != nil {
+		c.broken = true
+		return PacketHeader{}, err
+	}
+
+	length := UnpackNumber(c.header[:], 3)
+	c.readBytes += int64(len(c.header)) + int64(length)
+
+	return PacketHeader{
+		Len: length,
+		Seq: c.header[3],
+	}, nil
+}
+
+// appendPayload reads n bytes of payload and appends them to payload.
+func (c *Connection) appendPayload(payload []byte, n uint64) ([]byte, error) {
+	pos := len(payload)
+
+	if c.param.MaxPayloadSize > 0 && uint64(pos)+n > uint64(c.param.MaxPayloadSize) {
+		c.broken = true
+
+		return nil, &ResponseTooLargeError{
+			Size:  uint64(pos) + n,
+			Limit: c.param.MaxPayloadSize,
+		}
+	}
+
+	if uint64(cap(payload)-pos) < n {
+		// The buffer is not read yet: the stream is out of sync once
+		// the budget refuses it.
+		if err := c.growReadBuf(pos + int(n)); err != nil {
+			c.broken = true
+			return nil, err
+		}
+
+		payload = append(payload[:cap(payload)], make([]byte, uint64(pos)+n-uint64(cap(payload)))...)
+	}
+
+	payload = payload[:uint64(pos)+n]
+
+	err := ReadPacket(c.reader, payload[pos:])
+
+	if err != nil {
+		c.broken = true
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// writePacket sends the payload, splitting it into several packets when it
+// does not fit into a single one, and flushes the writer.
+func (c *Connection) writePacket(payload []byte) error {
+	if c.writev == true && len(payload) < MAX_PACKET_SIZE-1 {
+		return c.writeDirect(payload)
+	}
+
+	err := c.bufferPacket(payload)
+
+	if err != nil {
+		return err
+	}
+
+	err = c.writer.Flush()
+
+	if err != nil {
+		c.broken = true
+		return err
+	}
+
+	return nil
+}
+
+// writeDirect writes a packet of less than MAX_PACKET_SIZE-1 bytes with a
+// single writev call, without copying the payload into the write buffer.
+func (c *Connection) writeDirect(payload []byte) error {
+	n := len(payload)
+
+	// packet length + sequence number [4 bytes]
+	c.writeHeader = [4]byte{byte(n), byte(n >> 8), byte(n >> 16), c.sequence}
+	c.writeBuffers = [2][]byte{c.writeHeader[:], payload}
+
+	buffers := net.Buffers(c.writeBuffers[:])
+
+	_, err := buffers.WriteTo(c.conn)
+
+	c.writeBuffers = [2][]byte{}
+
+	if err != nil {
+		c.broken = true
+		return err
+	}
+
+	c.sequence++
+
+	return nil
+}
+
+// bufferPacket writes the payload like writePacket without flushing the
+// writer.
+func (c *Connection) bufferPacket(payload []byte) error {
+	var err error
+
+	for {
+		n := len(payload)
+
+		if n > MAX_PACKET_SIZE-1 {
+			n = MAX_PACKET_SIZE - 1
+		}
+
+		// packet length + sequence number [4 bytes]
+		header := []byte{byte(n), byte(n >> 8), byte(n >> 16), c.sequence}
+
+		_, err = c.writer.Write(header)
+
+		if err != nil {
+			c.broken = true
+			return err
+		}
+
+		_, err = c.writer.Write(payload[:n])
+
+		if err != nil {
+			c.broken = true
+			return err
+		}
+
+		c.sequence++
+		payload = payload[n:]
+
+		// A payload of exactly MAX_PACKET_SIZE-1 bytes is followed by an
+		// empty packet so the server knows it is complete.
+		if n < MAX_PACKET_SIZE-1 {
+			return nil
+		}
+	}
+}
+
+// writeCommand starts a new command phase by sending the command byte and
+// its argument with the sequence number reset to zero.
+func (c *Connection) writeCommand(command byte, arg []byte) error {
+	c.unsent = false
+
+	payload, err := c.commandPayload(command, arg)
+
+	if err != nil {
+		return err
+	}
+
+	c.sequence = 0
+	c.commandBytes = c.readBytes
+
+	// A command that could not be written was not executed. Once it is
+	// flushed the outcome is unknown until the response arrives.
+	c.unsent = true
+
+	err = c.writePacket(payload)
+
+	if err != nil {
+		return err
+	}
+
+	c.unsent = false
+
+	return nil
+}
+
+// writeCommands writes the command payloads in a single write. The writer
+// flushes by itself when its buffer is full, so part of them may have been
+// sent when it fails.
+func (c *Connection) writeCommands(payloads [][]byte) error {
+	c.unsent = false
+
+	for _, payload := range payloads {
+		c.sequence = 0
+
+		err := c.bufferPacket(payload)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.writer.Flush()
+
+	if err != nil {
+		c.broken = true
+		return err
+	}
+
+	return nil
+}
+
+// commandPayload checks that the command can be sent and returns the
+// command byte followed by its argument.
+func (c *Connection) commandPayload(command byte, arg []byte) ([]byte, error) {
+	if c.broken == true {
+		c.unsent = true
+		return nil, ErrInvalidConn
+	}
+
+	// Sending a command larger than max_allowed_packet makes the server
+	// drop the connection halfway through the write.
+	if c.maxAllowedPacket > 0 && 1+len(arg) > c.maxAllowedPacket {
+		return nil, &PacketTooLargeError{
+			Size:             1 + len(arg),
+			MaxAllowedPacket: c.maxAllowedPacket,
+		}
+	}
+
+	payload := make([]byte, 1+len(arg))
+	payload[0] = command
+	copy(payload[1:], arg)
+
+	return payload, nil
+}
+
+// parseOKPacket parses an OK packet, see protocol.ParseOKPacket, and
+// applies its status and session state changes to the connection.
+func (c *Connection) parseOKPacket(payload []byte) (*Result, error) {
+	if len(payload) == 0 || payload[0] != OK_PACKET {
+		return nil, ErrMalformedPacket
+	}
+
+	ok, err := protocol.ParseOKPacket(payload, uint32(c.clientFlags))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ok.SessionStateChanges) > 0 {
+		err = c.parseSessionStateChanges(ok.SessionStateChanges)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.status = ok.StatusFlags
+
+	return &Result{
+		affectedRows: ok.AffectedRows,
+		lastInsertID: ok.LastInsertID,
+		statusFlags:  ok.StatusFlags,
+		warnings:     ok.Warnings,
+		info:         ok.Info,
+	}, nil
+}
+
+// parseSessionStateChanges applies the session state changes of an OK
+// packet, a list of type [1 byte] and data [length encoded string] pairs.
+// Only the schema is tracked.
+func (c *Connection) parseSessionStateChanges(changes []byte) error {
+	for pos := 0; pos < len(changes); {
+		changeType := changes[pos]
+		data, _, n := unpackLenEncString(changes[pos+1:])
+
+		if n == 0 {
+			return ErrMalformedPacket
+		}
+
+		if changeType == SESSION_TRACK_SCHEMA {
+			// schema name [length encoded string]
+			schema, _, n := unpackLenEncString(data)
+
+			if n == 0 {
+				return ErrMalformedPacket
+			}
+
+			c.database = string(schema)
+		}
+
+		pos += 1 + n
+	}
+
+	return nil
+}
+
+// parseErrPacket parses an ERR packet into a *MySQLError.
+func parseErrPacket(payload []byte) error {
+	e, err := protocol.ParseErrPacket(payload)
+
+	if err != nil {
+		return err
+	}
+
+	return &MySQLError{Number: e.Code, SQLState: e.SQLState, Message: e.Message}
+}
+
+// readOKPacket reads the response of a command which is expected to be
+// either an OK or an ERR packet.
+func (c *Connection) readOKPacket() (*Result, error) {
+	payload, err := c.readPacket()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) == 0 {
+		return nil, ErrMalformedPacket
+	}
+
+	switch payload[0] {
+	case OK_PACKET:
+		return c.parseOKPacket(payload)
+	case ERR_PACKET:
+		return nil, parseErrPacket(payload)
+	}
+
+	return nil, ErrUnexpectedPacket
+}
+
+// unpackLenEncString decodes a length encoded string. It returns the string,
+// whether it was NULL and the number of bytes used (0 when malformed).
+func unpackLenEncString(byteArr []byte) ([]byte, bool, int) {
+	return protocol.ParseLenEncString(byteArr)
+}
+
+// isEOFPacket reports whether the payload is an EOF packet rather than a row
+// starting with a 0xfe length encoded integer.
+func isEOFPacket(payload []byte) bool {
+	return protocol.IsEOFPacket(payload)
+}
+
+// parseEOFPacket records the status flags of an EOF packet.
+// Reference:
+// https://mariadb.com/kb/en/mariadb/eof_packet/
+func (c *Connection) parseEOFPacket(payload []byte) (uint16, error) {
+	// header [1 byte]
+	// warning count [2 bytes]
+	// server status [2 bytes]
+	if len(payload) < 5 {
+		return 0, ErrMalformedPacket
+	}
+
+	c.status = uint16(UnpackNumber(payload[3:], 2))
+
+	return uint16(UnpackNumber(payload[1:], 2)), nil
+}
@@ -0,0 +1,144 @@
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// newTCPConnection connects to a server answering every command with
+// response, over the loopback interface so that writev is used.
+func newTCPConnection(tb testing.TB, param ConnectionParameter, response []byte) *Connection {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		tb.Skip(err)
+	}
+
+	tb.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+
+		for {
+			if _, err := ReadPacketHeader(reader); err != nil {
+				return
+			}
+
+			// Commands are small, the rest of the packet is buffered.
+			reader.Discard(reader.Buffered())
+
+			if _, err := conn.Write(response); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(func() { conn.Close() })
+
+	c := &Connection{param: param, conn: conn, mutex: new(sync.Mutex), debugBuf: new(bytes.Buffer)}
+	c.setBuffers()
+
+	return c
+}
+
+// pointQueryResponse is the response to SELECT name FROM city WHERE id = 1.
+func pointQueryResponse() []byte {
+	var response []byte
+
+	for i, payload := range [][]byte{
+		{1},
+		columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+		eofPacket(0),
+		textRowPacket("Taipei"),
+		eofPacket(SERVER_STATUS_AUTOCOMMIT),
+	} {
+		n := len(payload)
+		response = append(response, byte(n), byte(n>>8), byte(n>>16), byte(i+1))
+		response = append(response, payload...)
+	}
+
+	return response
+}
+
+func TestDirectIO(t *testing.T) {
+	c := newTCPConnection(t, ConnectionParameter{DirectIO: true}, pointQueryResponse())
+
+	if c.writev == false || c.reader.Size() != DIRECT_IO_READ_BUFFER_SIZE {
+		t.Fatal("direct IO not set up")
+	}
+
+	for i := 0; i < 3; i++ {
+		rows, err := c.Query("SELECT name FROM city WHERE id = 1")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rows.Next() == false || string(rows.Values()[0]) != "Taipei" {
+			t.Fatalf("unexpected row: %v", rows.Err())
+		}
+
+		if err = rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// TLS or any other wrapper writes through the buffer.
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+
+	c = &Connection{param: ConnectionParameter{DirectIO: true}, conn: client}
+	c.setBuffers()
+
+	if c.writev == true {
+		t.Error("writev on a net.Pipe")
+	}
+}
+
+func benchmarkPointQuery(b *testing.B, directIO bool) {
+	c := newTCPConnection(b, ConnectionParameter{DirectIO: directIO}, pointQueryResponse())
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := c.Query("SELECT name FROM city WHERE id = 1")
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for rows.Next() {
+		}
+
+		if err = rows.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPointQueryBuffered(b *testing.B) {
+	benchmarkPointQuery(b, false)
+}
+
+func BenchmarkPointQueryDirectIO(b *testing.B) {
+	benchmarkPointQuery(b, true)
+}
@@ -0,0 +1,276 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoPageKey     = errors.New("Pagination without key columns")
+	ErrPageKeyColumn = errors.New("Pagination key column missing from the result set")
+	ErrNullPageKey   = errors.New("Pagination key column with a NULL value")
+)
+
+// PageKey is a column of the key a Paginator orders and seeks by.
+type PageKey struct {
+	Column string // name of the column in the result set of the query
+	Desc   bool
+}
+
+// Paginator reads the result set of a query page by page with keyset (or
+// seek) pagination: every page starts right after the key of the last row
+// of the previous one, e.g.
+//
+//	SELECT * FROM (query) AS paginated WHERE (`a`, `b`) > (1, 'x') ORDER BY `a`, `b` LIMIT 1000
+//
+// so that, unlike with OFFSET, reading a page costs the same at the end of
+// a large table as at its start when the key is indexed. The key must be
+// unique and not NULL, and its columns must be selected by the query. The
+// query may have a WHERE clause of its own: the server merges the derived
+// table into the outer statement.
+type Paginator struct {
+	c        *Connection
+	query    string
+	keys     []PageKey
+	pageSize int
+
+	last []string // literals of the key of the last row read
+	done bool
+	err  error
+
+	columns []Column
+	rows    [][][]byte
+}
+
+// NewPaginator returns a Paginator reading the rows of query by pages of
+// pageSize rows, ordered by keys.
+func NewPaginator(c *Connection, query string, pageSize int, keys ...PageKey) *Paginator {
+	p := &Paginator{
+		c:        c,
+		query:    query,
+		keys:     keys,
+		pageSize: pageSize,
+	}
+
+	if len(keys) == 0 {
+		p.err = ErrNoPageKey
+	}
+
+	return p
+}
+
+// Next reads the next page, returning false once the rows are exhausted or
+// on error. ctx is checked before the statement is sent.
+func (p *Paginator) Next(ctx context.Context) bool {
+	p.columns = nil
+	p.rows = nil
+
+	if p.done == true || p.err != nil {
+		return false
+	}
+
+	if p.err = ctx.Err(); p.err != nil {
+		return false
+	}
+
+	rows, err := p.c.QueryContext(ctx, p.pageQuery())
+
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	p.columns = rows.Columns()
+
+	for rows.Next() == true {
+		row := make([][]byte, len(rows.Values()))
+
+		// The values point into the read buffer of the connection.
+		for i, value := range rows.Values() {
+			if value != nil {
+				row[i] = append([]byte{}, value...)
+			}
+		}
+
+		p.rows = append(p.rows, row)
+	}
+
+	p.err = rows.Close()
+
+	if p.err != nil {
+		p.rows = nil
+		return false
+	}
+
+	if len(p.rows) < p.pageSize {
+		p.done = true
+	}
+
+	if len(p.rows) == 0 {
+		return false
+	}
+
+	p.err = p.seek(p.rows[len(p.rows)-1])
+
+	return p.err == nil
+}
+
+// Columns returns the columns of the current page.
+func (p *Paginator) Columns() []Column {
+	return p.columns
+}
+
+// Rows returns the rows of the current page, nil values being NULL.
+func (p *Paginator) Rows() [][][]byte {
+	return p.rows
+}
+
+// Err returns the error which stopped Next.
+func (p *Paginator) Err() error {
+	return p.err
+}
+
+// seek records the key of row as the start of the next page.
+func (p *Paginator) seek(row [][]byte) error {
+	last := make([]string, len(p.keys))
+
+	for i, key := range p.keys {
+		column := -1
+
+		for j := range p.columns {
+			if p.columns[j].Name == key.Column {
+				column = j
+				break
+			}
+		}
+
+		if column < 0 {
+			return ErrPageKeyColumn
+		}
+
+		value := row[column]
+
+		if value == nil {
+			return ErrNullPageKey
+		}
+
+		// Comparing a number with a string compares them as floating
+		// point numbers, which BIGINT keys do not survive.
+		if p.columns[column].Type.IsNumeric() == true {
+			last[i] = string(value)
+		} else {
+			last[i] = p.c.QuoteString(string(value))
+		}
+	}
+
+	p.last = last
+
+	return nil
+}
+
+// pageQuery returns the statement reading the next page.
+func (p *Paginator) pageQuery() string {
+	var buf strings.Builder
+
+	buf.WriteString("SELECT * FROM (")
+	buf.WriteString(p.query)
+	buf.WriteString(") AS paginated")
+
+	if p.last != nil {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(p.seekCondition())
+	}
+
+	buf.WriteString(" ORDER BY ")
+
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+
+		buf.WriteString(p.c.QuoteIdentifier(key.Column))
+
+		if key.Desc == true {
+			buf.WriteString(" DESC")
+		}
+	}
+
+	buf.WriteString(" LIMIT ")
+	buf.WriteString(strconv.Itoa(p.pageSize))
+
+	return buf.String()
+}
+
+// seekCondition returns the condition selecting the rows after the last
+// one read. A key in a single direction is compared as a row constructor,
+// which the server turns into a range scan; mixed directions need the
+// expanded form
+//
+//	a > 1 OR (a = 1 AND b < 'x')
+func (p *Paginator) seekCondition() string {
+	var buf strings.Builder
+
+	mixed := false
+
+	for _, key := range p.keys {
+		if key.Desc != p.keys[0].Desc {
+			mixed = true
+		}
+	}
+
+	if mixed == false {
+		operator := " > "
+
+		if p.keys[0].Desc == true {
+			operator = " < "
+		}
+
+		buf.WriteString("(")
+
+		for i, key := range p.keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+
+			buf.WriteString(p.c.QuoteIdentifier(key.Column))
+		}
+
+		buf.WriteString(")")
+		buf.WriteString(operator)
+		buf.WriteString("(")
+		buf.WriteString(strings.Join(p.last, ", "))
+		buf.WriteString(")")
+
+		return buf.String()
+	}
+
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteString(" OR ")
+		}
+
+		buf.WriteString("(")
+
+		for j := 0; j < i; j++ {
+			buf.WriteString(p.c.QuoteIdentifier(p.keys[j].Column))
+			buf.WriteString(" = ")
+			buf.WriteString(p.last[j])
+			buf.WriteString(" AND ")
+		}
+
+		buf.WriteString(p.c.QuoteIdentifier(key.Column))
+
+		if key.Desc == true {
+			buf.WriteString(" < ")
+		} else {
+			buf.WriteString(" > ")
+		}
+
+		buf.WriteString(p.last[i])
+		buf.WriteString(")")
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPaginator(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	columns := [][]byte{
+		columnPacket("id", MYSQL_TYPE_LONGLONG, 0, 63),
+		columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+	}
+
+	go func() {
+		if query := string(server.readCommand()[1:]); query != "SELECT * FROM (SELECT id, name FROM city WHERE country = 'TW') AS paginated ORDER BY `id` LIMIT 2" {
+			t.Errorf("unexpected first page %q", query)
+		}
+
+		server.writeResultSet(1, columns, [][]byte{textRowPacket("1", "Taipei"), textRowPacket("9007199254740993", "Tainan")}, 0)
+
+		if query := string(server.readCommand()[1:]); query != "SELECT * FROM (SELECT id, name FROM city WHERE country = 'TW') AS paginated WHERE (`id`) > (9007199254740993) ORDER BY `id` LIMIT 2" {
+			t.Errorf("unexpected second page %q", query)
+		}
+
+		server.writeResultSet(1, columns, [][]byte{textRowPacket("9007199254740994", "Hsinchu")}, 0)
+	}()
+
+	p := NewPaginator(c, "SELECT id, name FROM city WHERE country = 'TW'", 2, PageKey{Column: "id"})
+
+	var names []string
+
+	for p.Next(context.Background()) {
+		for _, row := range p.Rows() {
+			names = append(names, string(row[1]))
+		}
+	}
+
+	if p.Err() != nil {
+		t.Fatal(p.Err())
+	}
+
+	if len(names) != 3 || names[0] != "Taipei" || names[1] != "Tainan" || names[2] != "Hsinchu" {
+		t.Errorf("unexpected rows %q", names)
+	}
+}
+
+func TestPaginatorSeekCondition(t *testing.T) {
+	c := NewConnection(ConnectionParameter{})
+
+	tests := []struct {
+		keys     []PageKey
+		expected string
+	}{
+		{[]PageKey{{Column: "a", Desc: true}, {Column: "b", Desc: true}}, "(`a`, `b`) < (1, 'x')"},
+		{[]PageKey{{Column: "a"}, {Column: "b", Desc: true}}, "(`a` > 1) OR (`a` = 1 AND `b` < 'x')"},
+	}
+
+	for _, test := range tests {
+		p := NewPaginator(c, "SELECT a, b FROM t", 10, test.keys...)
+		p.last = []string{"1", "'x'"}
+
+		if condition := p.seekCondition(); condition != test.expected {
+			t.Errorf("got %q, want %q", condition, test.expected)
+		}
+	}
+}
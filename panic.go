@@ -0,0 +1,44 @@
+package mysql
+
+// A panic in the middle of a command, raised by a callback such as
+// AuditFunc, ProgressFunc or KillHelper or by the driver itself, leaves
+// the response half read. The connection must then never be used again,
+// even when the application recovers: it is marked broken and closed, it
+// is handed back so that the next command fails with ErrInvalidConn rather
+// than waiting for the lock forever, and the panic goes on.
+
+// poison marks the connection broken and closes it.
+func (c *Connection) poison() {
+	c.broken = true
+	c.unwatchContext()
+	c.conn.Close()
+}
+
+// poisonOnPanic is deferred, after the unlock, by the commands which
+// unlock the connection with defer.
+func (c *Connection) poisonOnPanic() {
+	if r := recover(); r != nil {
+		c.poison()
+		panic(r)
+	}
+}
+
+// unlockOnPanic is deferred by the commands which may return with the
+// connection still locked by their rows.
+func (c *Connection) unlockOnPanic() {
+	if r := recover(); r != nil {
+		c.poison()
+		c.mutex.Unlock()
+		panic(r)
+	}
+}
+
+// releaseOnPanic is deferred by the methods of Rows reading from the
+// connection.
+func (r *Rows) releaseOnPanic() {
+	if p := recover(); p != nil {
+		r.c.poison()
+		r.release()
+		panic(p)
+	}
+}
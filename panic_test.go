@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+// recoverPanic runs f and returns the value it panicked with.
+func recoverPanic(f func()) (value interface{}) {
+	defer func() {
+		value = recover()
+	}()
+
+	f()
+
+	return nil
+}
+
+func TestPanicPoisonsConnection(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{
+		AuditFunc: func(event AuditEvent) {
+			if event.Err == nil {
+				panic("audit")
+			}
+		},
+	})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	value := recoverPanic(func() {
+		c.Query("DO 1")
+	})
+
+	if value != "audit" {
+		t.Fatalf("unexpected panic %v", value)
+	}
+
+	if c.broken == false {
+		t.Error("the connection is not broken")
+	}
+
+	// The lock was given back and the connection refuses further commands.
+	_, err := c.Query("DO 1")
+
+	if err != ErrInvalidConn {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPanicInRowsClose(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{KillOnClose: true})
+	server := newFakeServer(t, serverConn)
+
+	c.param.KillHelper = func(ctx context.Context) (*Connection, error) {
+		panic("kill")
+	}
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("n", MYSQL_TYPE_LONGLONG, 0, 63))
+		server.writePacket(3, eofPacket(0))
+	}()
+
+	rows, err := c.Query("SELECT n FROM huge")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := recoverPanic(func() {
+		rows.Close()
+	})
+
+	if value != "kill" || c.broken == false {
+		t.Fatalf("unexpected panic %v", value)
+	}
+
+	if _, err = c.Query("DO 1"); err != ErrInvalidConn {
+		t.Errorf("unexpected error %v", err)
+	}
+}
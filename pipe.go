@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNamedPipe = errors.New("Named pipes are only supported on Windows")
+)
+
+// DEFAULT_PIPE_NAME is the named pipe of a Windows server started with
+// --named-pipe, see its socket system variable.
+const DEFAULT_PIPE_NAME = "MySQL"
+
+// pipePath returns the path of a named pipe of the local host given by its
+// name, e.g. "MySQL", or by its full path.
+func pipePath(name string) string {
+	if name == "" {
+		name = DEFAULT_PIPE_NAME
+	}
+
+	if strings.HasPrefix(name, `\\`) == true {
+		return name
+	}
+
+	return `\\.\pipe\` + name
+}
+
+// pipeAddr is the address of a named pipe.
+type pipeAddr string
+
+func (addr pipeAddr) Network() string {
+	return "pipe"
+}
+
+func (addr pipeAddr) String() string {
+	return string(addr)
+}
+
+// pipeConn is a named pipe opened as a file. The file is not pollable, the
+// deadlines are not supported: ReadTimeout, HandshakeTimeout and the
+// cancellation of a context do not interrupt a blocked read.
+type pipeConn struct {
+	file *os.File
+}
+
+func (conn *pipeConn) Read(p []byte) (int, error) {
+	return conn.file.Read(p)
+}
+
+func (conn *pipeConn) Write(p []byte) (int, error) {
+	return conn.file.Write(p)
+}
+
+func (conn *pipeConn) Close() error {
+	return conn.file.Close()
+}
+
+func (conn *pipeConn) LocalAddr() net.Addr {
+	return pipeAddr(conn.file.Name())
+}
+
+func (conn *pipeConn) RemoteAddr() net.Addr {
+	return pipeAddr(conn.file.Name())
+}
+
+func (conn *pipeConn) SetDeadline(t time.Time) error {
+	return conn.file.SetDeadline(t)
+}
+
+func (conn *pipeConn) SetReadDeadline(t time.Time) error {
+	return conn.file.SetReadDeadline(t)
+}
+
+func (conn *pipeConn) SetWriteDeadline(t time.Time) error {
+	return conn.file.SetWriteDeadline(t)
+}
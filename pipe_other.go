@@ -0,0 +1,13 @@
+//go:build !windows
+
+package mysql
+
+import (
+	"context"
+	"net"
+)
+
+// dialNamedPipe fails, named pipes being a Windows transport.
+func dialNamedPipe(ctx context.Context, name string) (net.Conn, error) {
+	return nil, ErrNamedPipe
+}
@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPipePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"", `\\.\pipe\MySQL`},
+		{"MySQL80", `\\.\pipe\MySQL80`},
+		{`\\db01\pipe\MySQL`, `\\db01\pipe\MySQL`},
+	}
+
+	for _, test := range tests {
+		if path := pipePath(test.name); path != test.expected {
+			t.Errorf("%q: got %q, want %q", test.name, path, test.expected)
+		}
+	}
+}
+
+func TestOpenPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes are supported")
+	}
+
+	c := NewConnection(ConnectionParameter{Network: "pipe"})
+
+	if err := c.Open(); err != ErrNamedPipe {
+		t.Errorf("unexpected error %v", err)
+	}
+}
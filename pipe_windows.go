@@ -0,0 +1,42 @@
+//go:build windows
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ERROR_PIPE_BUSY is returned while every instance of the pipe is in use.
+const ERROR_PIPE_BUSY = syscall.Errno(231)
+
+// dialNamedPipe opens the named pipe of the server, waiting for an instance to
+// be free until ctx is done.
+func dialNamedPipe(ctx context.Context, name string) (net.Conn, error) {
+	path := pipePath(name)
+
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+
+		if err == nil {
+			return &pipeConn{file: file}, nil
+		}
+
+		if errors.Is(err, ERROR_PIPE_BUSY) == false {
+			return nil, err
+		}
+
+		timer := time.NewTimer(10 * time.Millisecond)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package mysql
+
+import (
+	"time"
+)
+
+// PipelineResult is the response to one query of a pipeline.
+type PipelineResult struct {
+	Columns []Column   // nil if the query produced no result set
+	Rows    [][][]byte // the rows of the result set, nil for NULL values
+	Result  *Result    // the OK packet of a query without result set
+	Err     error      // the error returned by the server for this query
+}
+
+// Pipeline sends the queries back to back in a single write, then reads the
+// responses in order, so a batch of independent queries (e.g. point SELECTs)
+// costs one round trip instead of one per query. The rows are buffered in
+// memory and only the first result set of each query is kept.
+//
+// The server runs every query even after one of them failed; its error is
+// returned in the PipelineResult.Err of that query. The returned error is
+// set when the connection itself failed and the responses could not be
+// read.
+//
+// The server does not read further commands while it is blocked sending a
+// response, so keep the pipeline small enough for the queries to fit into
+// the socket buffers, i.e. a few hundred short queries.
+func (c *Connection) Pipeline(queries ...string) ([]PipelineResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	payloads := make([][]byte, len(queries))
+
+	// Check every query before writing any of them: the writer flushes
+	// by itself when its buffer is full.
+	for i, query := range queries {
+		payload, err := c.commandPayload(COM_QUERY, []byte(query))
+
+		if err != nil {
+			return nil, err
+		}
+
+		payloads[i] = payload
+	}
+
+	start := time.Now()
+
+	for _, payload := range payloads {
+		c.sequence = 0
+
+		err := c.bufferPacket(payload)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.writer.Flush()
+
+	if err != nil {
+		c.broken = true
+		return nil, err
+	}
+
+	results := make([]PipelineResult, len(queries))
+
+	// The rows are accounted in the MemoryBudget until they are returned.
+	var memory int64
+
+	if c.param.MemoryBudget != nil {
+		defer func() { c.param.MemoryBudget.release(memory) }()
+	}
+
+	// The time of every query but the first one is counted from the end
+	// of the previous response.
+	responseStart := start
+
+	for i := range results {
+		// Every response starts right after its one packet command.
+		c.sequence = 1
+		c.commandBytes = c.readBytes
+
+		results[i] = c.readPipelineResponse(&memory)
+
+		c.audit(queries[i], start, results[i].Result, results[i].Err)
+		c.recordStatement(queries[i], responseStart, int64(len(results[i].Rows)), results[i].Err)
+		responseStart = time.Now()
+
+		if c.broken == true {
+			return results[:i+1], results[i].Err
+		}
+	}
+
+	return results, nil
+}
+
+// readPipelineResponse reads the response to a query of a pipeline,
+// discarding any result set after the first one. The size of the rows
+// kept is added to memory; once the MemoryBudget refuses more, the rest of
+// the rows are discarded and Err is ErrMemoryBudget.
+func (c *Connection) readPipelineResponse(memory *int64) PipelineResult {
+	var result PipelineResult
+
+	// The pipeline holds the mutex, the rows must not release it.
+	rows := &Rows{c: c, released: true}
+
+	result.Err = rows.readResultSetHeader()
+
+	if result.Err != nil {
+		return result
+	}
+
+	result.Columns = rows.columns
+	result.Result = rows.result
+
+	var budgetErr error
+
+	for rows.Next() == true {
+		if budgetErr != nil {
+			continue
+		}
+
+		if c.param.MemoryBudget != nil {
+			size := int64(len(rows.values)) * 24
+
+			for _, value := range rows.values {
+				size += int64(len(value))
+			}
+
+			budgetErr = c.param.MemoryBudget.acquire(size)
+
+			if budgetErr != nil {
+				result.Rows = nil
+				continue
+			}
+
+			*memory += size
+		}
+
+		row := make([][]byte, len(rows.values))
+
+		// The values point into the read buffer of the connection.
+		for i, value := range rows.values {
+			if value != nil {
+				row[i] = append([]byte{}, value...)
+			}
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	result.Err = rows.err
+
+	for result.Err == nil && c.status&SERVER_MORE_RESULTS_EXISTS != 0 {
+		result.Err = rows.readResultSetHeader()
+
+		for result.Err == nil && rows.Next() == true {
+		}
+
+		if result.Err == nil {
+			result.Err = rows.err
+		}
+	}
+
+	if result.Err == nil {
+		result.Err = budgetErr
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package mysql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	queries := []string{"SELECT name FROM t WHERE id = 1", "SELECT * FROM x", "DELETE FROM t WHERE id = 2"}
+
+	go func() {
+		// Every query is written before the first response is read.
+		for _, query := range queries {
+			seq, payload := server.readPacket()
+
+			if seq != 0 || payload[0] != COM_QUERY || string(payload[1:]) != query {
+				t.Errorf("unexpected command %d %q", seq, payload)
+			}
+		}
+
+		server.writeResultSet(1, [][]byte{
+			columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+		}, [][]byte{
+			textRowPacket("Taipei"),
+			textRowPacket(nil),
+		}, SERVER_STATUS_AUTOCOMMIT)
+
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.x' doesn't exist"...))
+		server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	results, err := c.Pipeline(queries...)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+
+	if len(results[0].Columns) != 1 || results[0].Err != nil ||
+		fmt.Sprintf("%q", results[0].Rows) != `[["Taipei"] [""]]` || results[0].Rows[1][0] != nil {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+
+	if mysqlErr, ok := results[1].Err.(*MySQLError); !ok || mysqlErr.Number != 1146 {
+		t.Errorf("unexpected second result %+v", results[1])
+	}
+
+	if results[2].Err != nil || results[2].Result == nil || results[2].Result.AffectedRows() != 1 {
+		t.Errorf("unexpected third result %+v", results[2])
+	}
+
+	// The connection was handed back.
+	c.mutex.Lock()
+	c.mutex.Unlock()
+}
+
+func TestPipelineMemoryBudget(t *testing.T) {
+	budget := NewMemoryBudget(4096, 0)
+	c, serverConn := newPipeConnection(ConnectionParameter{MemoryBudget: budget})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readPacket()
+		server.readPacket()
+
+		var rows [][]byte
+
+		for i := 0; i < 100; i++ {
+			rows = append(rows, textRowPacket(fmt.Sprintf("%064d", i)))
+		}
+
+		server.writeResultSet(1, [][]byte{columnPacket("n", MYSQL_TYPE_VAR_STRING, 0, 45)}, rows, SERVER_STATUS_AUTOCOMMIT)
+		server.writePacket(1, okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	results, err := c.Pipeline("SELECT n FROM big", "DELETE FROM t WHERE id = 2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rows which do not fit are discarded, the next response is read.
+	if results[0].Err != ErrMemoryBudget || results[0].Rows != nil || results[1].Err != nil || results[1].Result.AffectedRows() != 1 {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if budget.InUse() > 1024 {
+		t.Errorf("%d bytes still accounted", budget.InUse())
+	}
+}
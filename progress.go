@@ -0,0 +1,55 @@
+package mysql
+
+// Progress is a progress report of MariaDB for the running statement.
+type Progress struct {
+	Stage    int     // current stage, from 1
+	MaxStage int     // number of stages, e.g. 2 for an ALTER TABLE copying the table and building its indexes
+	Percent  float64 // progress of the current stage
+	Info     string  // the state of the statement, e.g. "copy to tmp table"
+}
+
+// ProgressFunc receives the progress reports of the running statement. It
+// is called while the connection waits for the response, so it must not
+// use the connection.
+type ProgressFunc func(progress Progress)
+
+// isProgressPacket reports whether the payload is a progress report, an
+// ERR packet with the error code 0xffff.
+func isProgressPacket(payload []byte) bool {
+	return len(payload) > 3 && payload[0] == ERR_PACKET && payload[1] == 0xff && payload[2] == 0xff
+}
+
+// reportProgress hands a progress report to ProgressFunc. Malformed
+// reports are ignored, like the MariaDB client does.
+// Reference:
+// https://mariadb.com/kb/en/progress-reporting/
+func (c *Connection) reportProgress(payload []byte) {
+	// header [1 byte]
+	// error code 0xffff [2 bytes]
+	// number of strings [1 byte]
+	// stage [1 byte]
+	// max stage [1 byte]
+	// progress in thousandths of a percent [3 bytes]
+	// state [length encoded string]
+	if c.param.ProgressFunc == nil || len(payload) < 3+1+1+1+3 {
+		return
+	}
+
+	pos := 3 + 1
+
+	progress := Progress{
+		Stage:    int(payload[pos]),
+		MaxStage: int(payload[pos+1]),
+		Percent:  float64(UnpackNumber(payload[pos+2:], 3)) / 1000,
+	}
+
+	pos += 1 + 1 + 3
+
+	info, _, n := unpackLenEncString(payload[pos:])
+
+	if n > 0 {
+		progress.Info = string(info)
+	}
+
+	c.param.ProgressFunc(progress)
+}
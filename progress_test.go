@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func progressPacket(stage byte, maxStage byte, thousandths uint32, info string) []byte {
+	payload := []byte{ERR_PACKET, 0xff, 0xff, 1, stage, maxStage, byte(thousandths), byte(thousandths >> 8), byte(thousandths >> 16)}
+
+	return append(payload, lenEncString(info)...)
+}
+
+func TestProgress(t *testing.T) {
+	var reports []Progress
+
+	c, serverConn := newPipeConnection(ConnectionParameter{
+		ProgressFunc: func(progress Progress) {
+			reports = append(reports, progress)
+		},
+	})
+	c.mariaDBCapabilities = MARIADB_CLIENT_PROGRESS
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, progressPacket(1, 2, 42500, "copy to tmp table"))
+		server.writePacket(2, progressPacket(2, 2, 100000, "Enabling keys"))
+		server.writePacket(3, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	rows, err := c.Query("ALTER TABLE t ADD INDEX (x)")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows.Close()
+
+	if len(reports) != 2 || reports[0] != (Progress{1, 2, 42.5, "copy to tmp table"}) || reports[1] != (Progress{2, 2, 100, "Enabling keys"}) {
+		t.Errorf("unexpected reports %+v", reports)
+	}
+}
@@ -0,0 +1,192 @@
+package protocol
+
+// ColumnDefinition is a ColumnDefinition41 packet describing a column of a
+// result set.
+// Reference:
+// https://mariadb.com/kb/en/result-set-packets/#column-definition-packet
+type ColumnDefinition struct {
+	Catalog      string // always "def"
+	Schema       string
+	Table        string
+	OrgTable     string
+	Name         string
+	OrgName      string
+	CharacterSet uint16 // collation id, 63 for binary
+	ColumnLength uint32
+	Type         uint8
+	Flags        uint16
+	Decimals     uint8
+
+	// ExtendedType and ExtendedFormat are the MariaDB extended metadata,
+	// with COLUMN_EXTENDED_METADATA.
+	ExtendedType   string
+	ExtendedFormat string
+
+	// Default is the default value, nil for NULL, with COLUMN_DEFAULT.
+	Default []byte
+}
+
+// ColumnOptions select the optional parts of a column definition.
+type ColumnOptions uint8
+
+const (
+	// COLUMN_LITE only decodes Name of the strings; the others are
+	// skipped without being allocated.
+	COLUMN_LITE ColumnOptions = 1 << iota
+
+	// COLUMN_EXTENDED_METADATA is set once MARIADB_CLIENT_EXTENDED_METADATA
+	// is negotiated: the extended metadata follows the names.
+	COLUMN_EXTENDED_METADATA
+
+	// COLUMN_DEFAULT is set in the response to COM_FIELD_LIST: the default
+	// value follows the fixed fields.
+	COLUMN_DEFAULT
+)
+
+// ParseColumnDefinition decodes a column definition.
+func ParseColumnDefinition(payload []byte, options ColumnOptions) (*ColumnDefinition, error) {
+	col := new(ColumnDefinition)
+	pos := 0
+
+	// catalog, schema, table, org_table, name, org_name
+	// [length encoded strings]
+	for i, field := range []*string{&col.Catalog, &col.Schema, &col.Table, &col.OrgTable, &col.Name, &col.OrgName} {
+		str, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		if options&COLUMN_LITE == 0 || i == 4 {
+			*field = string(str)
+		}
+
+		pos += n
+	}
+
+	// extended metadata [length encoded string]
+	if options&COLUMN_EXTENDED_METADATA != 0 {
+		metadata, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		err := col.parseExtendedMetadata(metadata)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pos += n
+	}
+
+	// length of fixed fields [length encoded integer, always 0x0c]
+	// character set [2 bytes]
+	// column length [4 bytes]
+	// type [1 byte]
+	// flags [2 bytes]
+	// decimals [1 byte]
+	// filler [2 bytes]
+	if len(payload) < pos+1+2+4+1+2+1 {
+		return nil, ErrMalformedPacket
+	}
+
+	pos += 1
+
+	col.CharacterSet = uint16At(payload[pos:])
+	pos += 2
+
+	col.ColumnLength = uint32At(payload[pos:])
+	pos += 4
+
+	col.Type = payload[pos]
+	pos += 1
+
+	col.Flags = uint16At(payload[pos:])
+	pos += 2
+
+	col.Decimals = payload[pos]
+	pos += 1 + 2
+
+	// default value [length encoded string]
+	if options&COLUMN_DEFAULT != 0 && pos < len(payload) {
+		value, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		if value != nil {
+			col.Default = append([]byte{}, value...)
+		}
+	}
+
+	return col, nil
+}
+
+// parseExtendedMetadata decodes the MariaDB extended metadata, a list of
+// key [1 byte] and value [length encoded string] pairs.
+func (col *ColumnDefinition) parseExtendedMetadata(metadata []byte) error {
+	for pos := 0; pos < len(metadata); {
+		key := metadata[pos]
+		value, _, n := ParseLenEncString(metadata[pos+1:])
+
+		if n == 0 {
+			return ErrMalformedPacket
+		}
+
+		switch key {
+		case 0:
+			col.ExtendedType = string(value)
+		case 1:
+			col.ExtendedFormat = string(value)
+		}
+
+		pos += 1 + n
+	}
+
+	return nil
+}
+
+// AppendColumnDefinition appends col as a column definition with the
+// optional parts of options; COLUMN_LITE is ignored.
+func AppendColumnDefinition(b []byte, col *ColumnDefinition, options ColumnOptions) []byte {
+	for _, field := range []string{col.Catalog, col.Schema, col.Table, col.OrgTable, col.Name, col.OrgName} {
+		b = AppendLenEncString(b, []byte(field))
+	}
+
+	if options&COLUMN_EXTENDED_METADATA != 0 {
+		var metadata []byte
+
+		if col.ExtendedType != "" {
+			metadata = append(metadata, 0)
+			metadata = AppendLenEncString(metadata, []byte(col.ExtendedType))
+		}
+
+		if col.ExtendedFormat != "" {
+			metadata = append(metadata, 1)
+			metadata = AppendLenEncString(metadata, []byte(col.ExtendedFormat))
+		}
+
+		b = AppendLenEncString(b, metadata)
+	}
+
+	b = append(b, 0x0c)
+	b = appendUint16(b, col.CharacterSet)
+	b = appendUint32(b, col.ColumnLength)
+	b = append(b, col.Type)
+	b = appendUint16(b, col.Flags)
+
+	b = append(b, col.Decimals, 0, 0)
+
+	if options&COLUMN_DEFAULT != 0 {
+		if col.Default == nil {
+			return AppendLenEncNull(b)
+		}
+
+		return AppendLenEncString(b, col.Default)
+	}
+
+	return b
+}
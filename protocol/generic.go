@@ -0,0 +1,193 @@
+package protocol
+
+// OKPacket is the OK packet ending a command, or a result set when
+// CLIENT_DEPRECATE_EOF is set.
+// Reference:
+// https://mariadb.com/kb/en/ok_packet/
+type OKPacket struct {
+	AffectedRows uint64
+	LastInsertID uint64
+	StatusFlags  uint16
+	Warnings     uint16
+	Info         string
+
+	// SessionStateChanges are the raw changes, a list of type [1 byte]
+	// and data [length encoded string] pairs, with CLIENT_SESSION_TRACK
+	// and SERVER_SESSION_STATE_CHANGED.
+	SessionStateChanges []byte
+}
+
+// ParseOKPacket decodes an OK packet, whose layout depends on the
+// capabilities of the client. The OK packet replacing the EOF packet, with
+// an 0xfe header, is accepted too.
+func ParseOKPacket(payload []byte, capabilities uint32) (*OKPacket, error) {
+	if len(payload) == 0 || payload[0] != OK_PACKET && payload[0] != EOF_PACKET {
+		return nil, ErrMalformedPacket
+	}
+
+	ok := new(OKPacket)
+	pos := 1
+
+	// affected rows [length encoded integer]
+	// last insert id [length encoded integer]
+	for _, field := range []*uint64{&ok.AffectedRows, &ok.LastInsertID} {
+		num, _, n := ParseLenEncInt(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		*field = num
+		pos += n
+	}
+
+	// server status [2 bytes]
+	// warning count [2 bytes]
+	if len(payload) < pos+4 {
+		return nil, ErrMalformedPacket
+	}
+
+	ok.StatusFlags = uint16At(payload[pos:])
+	ok.Warnings = uint16At(payload[pos+2:])
+	pos += 4
+
+	// info [string<EOF>]
+	if capabilities&CLIENT_SESSION_TRACK == 0 {
+		ok.Info = string(payload[pos:])
+		return ok, nil
+	}
+
+	// With CLIENT_SESSION_TRACK, both are left out when empty:
+	// info [length encoded string]
+	// session state changes [length encoded string]
+	if pos < len(payload) {
+		info, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		ok.Info = string(info)
+		pos += n
+	}
+
+	if pos < len(payload) && ok.StatusFlags&SERVER_SESSION_STATE_CHANGED != 0 {
+		changes, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		ok.SessionStateChanges = changes
+	}
+
+	return ok, nil
+}
+
+// AppendOKPacket appends ok as an OK packet for a client with capabilities.
+func AppendOKPacket(b []byte, ok *OKPacket, capabilities uint32) []byte {
+	b = append(b, OK_PACKET)
+	b = AppendLenEncInt(b, ok.AffectedRows)
+	b = AppendLenEncInt(b, ok.LastInsertID)
+	b = appendUint16(b, ok.StatusFlags)
+	b = appendUint16(b, ok.Warnings)
+
+	if capabilities&CLIENT_SESSION_TRACK == 0 {
+		return append(b, ok.Info...)
+	}
+
+	changes := ok.StatusFlags&SERVER_SESSION_STATE_CHANGED != 0 && len(ok.SessionStateChanges) > 0
+
+	if ok.Info != "" || changes == true {
+		b = AppendLenEncString(b, []byte(ok.Info))
+	}
+
+	if changes == true {
+		b = AppendLenEncString(b, ok.SessionStateChanges)
+	}
+
+	return b
+}
+
+// ErrPacket is the ERR packet of a failed command.
+// Reference:
+// https://mariadb.com/kb/en/err_packet/
+type ErrPacket struct {
+	Code     uint16
+	SQLState string // empty when the server sent none, e.g. before 4.1
+	Message  string
+}
+
+// ParseErrPacket decodes an ERR packet.
+func ParseErrPacket(payload []byte) (*ErrPacket, error) {
+	if len(payload) < 3 || payload[0] != ERR_PACKET {
+		return nil, ErrMalformedPacket
+	}
+
+	// error code [2 bytes]
+	e := &ErrPacket{Code: uint16At(payload[1:])}
+	pos := 3
+
+	// sql state marker '#' [1 byte]
+	// sql state [5 bytes]
+	if len(payload) >= pos+6 && payload[pos] == '#' {
+		e.SQLState = string(payload[pos+1 : pos+6])
+		pos += 6
+	}
+
+	// error message [string<EOF>]
+	e.Message = string(payload[pos:])
+
+	return e, nil
+}
+
+// AppendErrPacket appends e as an ERR packet.
+func AppendErrPacket(b []byte, e *ErrPacket) []byte {
+	b = append(b, ERR_PACKET)
+	b = appendUint16(b, e.Code)
+
+	if len(e.SQLState) == 5 {
+		b = append(b, '#')
+		b = append(b, e.SQLState...)
+	}
+
+	return append(b, e.Message...)
+}
+
+// EOFPacket is the EOF packet ending the column definitions and the rows of
+// a result set without CLIENT_DEPRECATE_EOF.
+// Reference:
+// https://mariadb.com/kb/en/eof_packet/
+type EOFPacket struct {
+	Warnings    uint16
+	StatusFlags uint16
+}
+
+// IsEOFPacket reports whether payload is an EOF packet rather than a row
+// starting with an 0xfe length encoded integer, which takes 9 bytes.
+func IsEOFPacket(payload []byte) bool {
+	return len(payload) > 0 && len(payload) < 9 && payload[0] == EOF_PACKET
+}
+
+// ParseEOFPacket decodes an EOF packet.
+func ParseEOFPacket(payload []byte) (*EOFPacket, error) {
+	// header [1 byte]
+	// warning count [2 bytes]
+	// server status [2 bytes]
+	if IsEOFPacket(payload) == false || len(payload) < 5 {
+		return nil, ErrMalformedPacket
+	}
+
+	return &EOFPacket{
+		Warnings:    uint16At(payload[1:]),
+		StatusFlags: uint16At(payload[3:]),
+	}, nil
+}
+
+// AppendEOFPacket appends eof as an EOF packet.
+func AppendEOFPacket(b []byte, eof *EOFPacket) []byte {
+	b = append(b, EOF_PACKET)
+	b = appendUint16(b, eof.Warnings)
+
+	return appendUint16(b, eof.StatusFlags)
+}
@@ -0,0 +1,403 @@
+package protocol
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Handshake is the HandshakeV10 packet a server starts a connection with.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_handshake_v10.html
+type Handshake struct {
+	ProtocolVersion uint8 // 10
+	ServerVersion   string
+	ConnectionID    uint32
+	AuthPluginData  []byte // the scramble, both parts without the terminating zero
+	Capabilities    uint32
+	CharacterSet    uint8
+	StatusFlags     uint16
+
+	// MariaDBCapabilities are sent by MariaDB servers in the reserved
+	// bytes, those without CLIENT_LONG_PASSWORD.
+	MariaDBCapabilities uint32
+
+	// AuthPluginDataLength is the length of the scramble announced by the
+	// server, 0 without CLIENT_PLUGIN_AUTH. AppendHandshake writes the
+	// length of AuthPluginData and its terminating zero.
+	AuthPluginDataLength uint8
+
+	AuthPluginName string
+}
+
+// VersionError is returned by ParseHandshake for a packet of another
+// protocol version than 10, e.g. the HandshakeV9 of MySQL before 3.21,
+// whose layout differs.
+type VersionError struct {
+	Version uint8
+}
+
+func (e *VersionError) Error() string {
+	return "Unsupported Protocol Version " + strconv.Itoa(int(e.Version))
+}
+
+// ParseHandshake decodes a HandshakeV10 packet. The optional parts are read
+// according to the capabilities of the server, which accepts the layouts of
+// older MySQL versions, MariaDB and proxies such as ProxySQL.
+func ParseHandshake(payload []byte) (*Handshake, error) {
+	h := new(Handshake)
+	pos := 0
+
+	// protocol version [1 byte]
+	// server version [null terminated string]
+	if len(payload) < 1 {
+		return nil, ErrMalformedPacket
+	}
+
+	h.ProtocolVersion = payload[0]
+	pos++
+
+	if h.ProtocolVersion != 10 {
+		return nil, &VersionError{Version: h.ProtocolVersion}
+	}
+
+	version, n := parseNullTerminated(payload[pos:])
+
+	if n == 0 {
+		return nil, ErrMalformedPacket
+	}
+
+	h.ServerVersion = version
+	pos += n
+
+	// connection id [4 bytes]
+	// auth plugin data part 1 [8 bytes]
+	// filler [1 byte]
+	// capabilities, lower 2 bytes [2 bytes]
+	if len(payload)-pos < 4+8+1+2 {
+		return nil, ErrMalformedPacket
+	}
+
+	h.ConnectionID = uint32At(payload[pos:])
+	pos += 4
+
+	h.AuthPluginData = append([]byte(nil), payload[pos:pos+8]...)
+	pos += 8 + 1
+
+	h.Capabilities = uint32(uint16At(payload[pos:]))
+	pos += 2
+
+	// Very old servers end the packet here.
+	if pos < len(payload) {
+		// character set [1 byte]
+		// status flags [2 bytes]
+		// capabilities, upper 2 bytes [2 bytes]
+		// auth plugin data length [1 byte]
+		// reserved [6 bytes]
+		// reserved, MariaDB capabilities [4 bytes]
+		if len(payload)-pos < 1+2+2+1+10 {
+			return nil, ErrMalformedPacket
+		}
+
+		h.CharacterSet = payload[pos]
+		pos++
+
+		h.StatusFlags = uint16At(payload[pos:])
+		pos += 2
+
+		h.Capabilities |= uint32(uint16At(payload[pos:])) << 16
+		pos += 2
+
+		h.AuthPluginDataLength = payload[pos]
+		pos += 1 + 6
+
+		if h.Capabilities&CLIENT_LONG_PASSWORD == 0 {
+			h.MariaDBCapabilities = uint32At(payload[pos:])
+		}
+
+		pos += 4
+	}
+
+	// auth plugin data part 2 [max(13, auth plugin data length - 8) bytes]
+	// The part ends with a zero byte which some servers leave out when
+	// nothing follows.
+	if h.Capabilities&CLIENT_SECURE_CONNECTION != 0 {
+		n := 13
+
+		if int(h.AuthPluginDataLength)-8 > n {
+			n = int(h.AuthPluginDataLength) - 8
+		}
+
+		if n > len(payload)-pos {
+			n = len(payload) - pos
+		}
+
+		part2 := payload[pos : pos+n]
+		pos += n
+
+		if len(part2) > 0 && part2[len(part2)-1] == 0 {
+			part2 = part2[:len(part2)-1]
+		}
+
+		h.AuthPluginData = append(h.AuthPluginData, part2...)
+	}
+
+	// auth plugin name [null terminated string]
+	// MySQL 5.5.7 to 5.5.9 do not terminate it (bug #59453).
+	if h.Capabilities&CLIENT_PLUGIN_AUTH != 0 {
+		name := payload[pos:]
+
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+
+		h.AuthPluginName = string(name)
+	}
+
+	return h, nil
+}
+
+// AppendHandshake appends h as a HandshakeV10 packet. AuthPluginData must
+// hold at least 8 bytes.
+func AppendHandshake(b []byte, h *Handshake) []byte {
+	b = append(b, h.ProtocolVersion)
+	b = append(b, h.ServerVersion...)
+	b = append(b, 0)
+	b = appendUint32(b, h.ConnectionID)
+	b = append(b, h.AuthPluginData[:8]...)
+	b = append(b, 0)
+	b = appendUint16(b, uint16(h.Capabilities))
+	b = append(b, h.CharacterSet)
+	b = appendUint16(b, h.StatusFlags)
+	b = appendUint16(b, uint16(h.Capabilities>>16))
+
+	if h.Capabilities&CLIENT_PLUGIN_AUTH != 0 {
+		b = append(b, byte(len(h.AuthPluginData)+1))
+	} else {
+		b = append(b, 0)
+	}
+
+	b = append(b, 0, 0, 0, 0, 0, 0)
+
+	if h.Capabilities&CLIENT_LONG_PASSWORD == 0 {
+		b = appendUint32(b, h.MariaDBCapabilities)
+	} else {
+		b = append(b, 0, 0, 0, 0)
+	}
+
+	if h.Capabilities&CLIENT_SECURE_CONNECTION != 0 {
+		part2 := h.AuthPluginData[8:]
+		b = append(b, part2...)
+
+		// At least 13 bytes, the last one being zero.
+		for i := len(part2); i < 13; i++ {
+			b = append(b, 0)
+		}
+	}
+
+	if h.Capabilities&CLIENT_PLUGIN_AUTH != 0 {
+		b = append(b, h.AuthPluginName...)
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+// SSL_REQUEST_SIZE is the size of the SSLRequest packet, the fixed part of
+// a HandshakeResponse41 a client sends before starting TLS.
+const SSL_REQUEST_SIZE = 32
+
+// HandshakeResponse is the HandshakeResponse41 packet a client answers the
+// Handshake with, or the SSLRequest packet when it only holds the fixed
+// fields (see IsSSLRequest).
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_handshake_response.html
+type HandshakeResponse struct {
+	Capabilities  uint32
+	MaxPacketSize uint32
+	CharacterSet  uint8
+
+	// MariaDBCapabilities are sent by MariaDB clients in the last reserved
+	// bytes, those without CLIENT_LONG_PASSWORD.
+	MariaDBCapabilities uint32
+
+	Username       string
+	AuthResponse   []byte
+	Database       string // with CLIENT_CONNECT_WITH_DB
+	AuthPluginName string // with CLIENT_PLUGIN_AUTH
+
+	// Attributes are the connection attributes, with CLIENT_CONNECT_ATTRS.
+	Attributes map[string]string
+}
+
+// IsSSLRequest reports whether payload is an SSLRequest packet.
+func IsSSLRequest(payload []byte) bool {
+	return len(payload) == SSL_REQUEST_SIZE && uint32At(payload)&CLIENT_SSL != 0
+}
+
+// ParseHandshakeResponse decodes a HandshakeResponse41 or an SSLRequest
+// packet.
+func ParseHandshakeResponse(payload []byte) (*HandshakeResponse, error) {
+	// capabilities [4 bytes]
+	// max packet size [4 bytes]
+	// character set [1 byte]
+	// reserved [19 bytes]
+	// reserved, MariaDB capabilities [4 bytes]
+	if len(payload) < SSL_REQUEST_SIZE {
+		return nil, ErrMalformedPacket
+	}
+
+	r := &HandshakeResponse{
+		Capabilities:  uint32At(payload),
+		MaxPacketSize: uint32At(payload[4:]),
+		CharacterSet:  payload[8],
+	}
+
+	if r.Capabilities&CLIENT_LONG_PASSWORD == 0 {
+		r.MariaDBCapabilities = uint32At(payload[28:])
+	}
+
+	pos := SSL_REQUEST_SIZE
+
+	if pos == len(payload) {
+		return r, nil
+	}
+
+	// username [null terminated string]
+	username, n := parseNullTerminated(payload[pos:])
+
+	if n == 0 {
+		return nil, ErrMalformedPacket
+	}
+
+	r.Username = username
+	pos += n
+
+	// auth response [length encoded string] with
+	// CLIENT_PLUGIN_AUTH_LENENC_DATA, [1 byte length and string] otherwise
+	if r.Capabilities&CLIENT_PLUGIN_AUTH_LENENC_DATA != 0 {
+		auth, _, n := ParseLenEncString(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		r.AuthResponse = append([]byte(nil), auth...)
+		pos += n
+	} else {
+		if pos >= len(payload) || len(payload)-pos-1 < int(payload[pos]) {
+			return nil, ErrMalformedPacket
+		}
+
+		n := int(payload[pos])
+		r.AuthResponse = append([]byte(nil), payload[pos+1:pos+1+n]...)
+		pos += 1 + n
+	}
+
+	// database [null terminated string]
+	if r.Capabilities&CLIENT_CONNECT_WITH_DB != 0 && pos < len(payload) {
+		database, n := parseNullTerminated(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		r.Database = database
+		pos += n
+	}
+
+	// auth plugin name [null terminated string]
+	if r.Capabilities&CLIENT_PLUGIN_AUTH != 0 && pos < len(payload) {
+		name, n := parseNullTerminated(payload[pos:])
+
+		if n == 0 {
+			return nil, ErrMalformedPacket
+		}
+
+		r.AuthPluginName = name
+		pos += n
+	}
+
+	// attributes [length encoded integer] followed by key and value
+	// [length encoded string] pairs
+	if r.Capabilities&CLIENT_CONNECT_ATTRS != 0 && pos < len(payload) {
+		length, _, n := ParseLenEncInt(payload[pos:])
+
+		if n == 0 || uint64(len(payload)-pos-n) < length {
+			return nil, ErrMalformedPacket
+		}
+
+		attributes := payload[pos+n : pos+n+int(length)]
+		r.Attributes = make(map[string]string)
+
+		for len(attributes) > 0 {
+			key, _, n := ParseLenEncString(attributes)
+
+			if n == 0 {
+				return nil, ErrMalformedPacket
+			}
+
+			value, _, m := ParseLenEncString(attributes[n:])
+
+			if m == 0 {
+				return nil, ErrMalformedPacket
+			}
+
+			r.Attributes[string(key)] = string(value)
+			attributes = attributes[n+m:]
+		}
+	}
+
+	return r, nil
+}
+
+// AppendSSLRequest appends the SSLRequest packet of r, its fixed fields.
+func AppendSSLRequest(b []byte, r *HandshakeResponse) []byte {
+	b = appendUint32(b, r.Capabilities)
+	b = appendUint32(b, r.MaxPacketSize)
+	b = append(b, r.CharacterSet)
+	b = append(b, make([]byte, 19)...)
+
+	if r.Capabilities&CLIENT_LONG_PASSWORD == 0 {
+		return appendUint32(b, r.MariaDBCapabilities)
+	}
+
+	return append(b, 0, 0, 0, 0)
+}
+
+// AppendHandshakeResponse appends r as a HandshakeResponse41 packet. The
+// attributes are written in no particular order.
+func AppendHandshakeResponse(b []byte, r *HandshakeResponse) []byte {
+	b = AppendSSLRequest(b, r)
+	b = append(b, r.Username...)
+	b = append(b, 0)
+
+	if r.Capabilities&CLIENT_PLUGIN_AUTH_LENENC_DATA != 0 {
+		b = AppendLenEncString(b, r.AuthResponse)
+	} else {
+		b = append(b, byte(len(r.AuthResponse)))
+		b = append(b, r.AuthResponse...)
+	}
+
+	if r.Capabilities&CLIENT_CONNECT_WITH_DB != 0 {
+		b = append(b, r.Database...)
+		b = append(b, 0)
+	}
+
+	if r.Capabilities&CLIENT_PLUGIN_AUTH != 0 {
+		b = append(b, r.AuthPluginName...)
+		b = append(b, 0)
+	}
+
+	if r.Capabilities&CLIENT_CONNECT_ATTRS != 0 {
+		var attributes []byte
+
+		for key, value := range r.Attributes {
+			attributes = AppendLenEncString(attributes, []byte(key))
+			attributes = AppendLenEncString(attributes, []byte(value))
+		}
+
+		b = AppendLenEncString(b, attributes)
+	}
+
+	return b
+}
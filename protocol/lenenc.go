@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// lenEncIntSize returns the number of bytes following the first byte of a
+// length encoded integer, -1 for the NULL marker and the undefined 0xff.
+func lenEncIntSize(first byte) int {
+	switch first {
+	case 0xfb, 0xff:
+		return -1
+	case 0xfc:
+		return 2
+	case 0xfd:
+		return 3
+	case 0xfe:
+		return 8
+	}
+
+	return 0
+}
+
+// ParseLenEncInt decodes a length encoded integer: values below 251 in one
+// byte, then 0xfc, 0xfd or 0xfe followed by 2, 3 or 8 bytes. It returns the
+// number, whether the value was the NULL marker (0xfb) and the number of
+// bytes used, 0 when b is too short or starts with the undefined 0xff.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_dt_integers.html
+func ParseLenEncInt(b []byte) (uint64, bool, int) {
+	if len(b) == 0 {
+		return 0, false, 0
+	}
+
+	size := lenEncIntSize(b[0])
+
+	switch {
+	case b[0] == 0xfb:
+		return 0, true, 1
+	case size < 0:
+		return 0, false, 0
+	case size == 0:
+		return uint64(b[0]), false, 1
+	}
+
+	if len(b) < 1+size {
+		return 0, false, 0
+	}
+
+	var num uint64
+
+	for i := 0; i < size; i++ {
+		num |= uint64(b[1+i]) << (uint(i) * 8)
+	}
+
+	return num, false, 1 + size
+}
+
+// AppendLenEncInt appends n as a length encoded integer.
+func AppendLenEncInt(b []byte, n uint64) []byte {
+	switch {
+	case n < 251:
+		return append(b, byte(n))
+	case n < 1<<16:
+		return append(b, 0xfc, byte(n), byte(n>>8))
+	case n < 1<<24:
+		return append(b, 0xfd, byte(n), byte(n>>8), byte(n>>16))
+	}
+
+	return append(b, 0xfe, byte(n), byte(n>>8), byte(n>>16), byte(n>>24), byte(n>>32), byte(n>>40), byte(n>>48), byte(n>>56))
+}
+
+// AppendLenEncNull appends the NULL marker of the length encoded values.
+func AppendLenEncNull(b []byte) []byte {
+	return append(b, 0xfb)
+}
+
+// ReadLenEncInt reads a length encoded integer from rd, returning
+// ErrMalformedPacket for 0xff and io.ErrUnexpectedEOF when rd ends within
+// the integer.
+func ReadLenEncInt(rd io.Reader) (uint64, bool, error) {
+	var buf [9]byte
+
+	_, err := io.ReadFull(rd, buf[:1])
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	n := 1
+
+	if size := lenEncIntSize(buf[0]); size > 0 {
+		_, err = io.ReadFull(rd, buf[1:1+size])
+
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		n += size
+	}
+
+	num, isNull, n := ParseLenEncInt(buf[:n])
+
+	if n == 0 {
+		return 0, false, ErrMalformedPacket
+	}
+
+	return num, isNull, nil
+}
+
+// WriteLenEncInt writes n to w as a length encoded integer.
+func WriteLenEncInt(w io.Writer, n uint64) error {
+	var buf [9]byte
+
+	_, err := w.Write(AppendLenEncInt(buf[:0], n))
+
+	return err
+}
+
+// ParseLenEncString decodes a length encoded string. It returns the
+// string, which points into b, whether it was NULL and the number of bytes
+// used, 0 when b is too short.
+func ParseLenEncString(b []byte) ([]byte, bool, int) {
+	num, isNull, n := ParseLenEncInt(b)
+
+	if n == 0 || isNull {
+		return nil, isNull, n
+	}
+
+	if uint64(len(b)-n) < num {
+		return nil, false, 0
+	}
+
+	return b[n : n+int(num)], false, n + int(num)
+}
+
+// AppendLenEncString appends s as a length encoded string.
+func AppendLenEncString(b []byte, s []byte) []byte {
+	b = AppendLenEncInt(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// ReadLenEncString reads a length encoded string from rd, returning whether
+// it was NULL. The string is read as it arrives rather than allocated from
+// its announced length, which a corrupt stream could make huge.
+func ReadLenEncString(rd io.Reader) ([]byte, bool, error) {
+	length, isNull, err := ReadLenEncInt(rd)
+
+	if err != nil || isNull == true {
+		return nil, isNull, err
+	}
+
+	if length > math.MaxInt64 {
+		return nil, false, ErrMalformedPacket
+	}
+
+	var buf bytes.Buffer
+
+	n, err := io.CopyN(&buf, rd, int64(length))
+
+	if err == io.EOF || err == nil && uint64(n) < length {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buf.Bytes(), false, nil
+}
+
+// WriteLenEncString writes s to w as a length encoded string.
+func WriteLenEncString(w io.Writer, s []byte) error {
+	err := WriteLenEncInt(w, uint64(len(s)))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(s)
+
+	return err
+}
@@ -0,0 +1,157 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestLenEncInt(t *testing.T) {
+	tests := []struct {
+		n       uint64
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{1, []byte{0x01}},
+		{250, []byte{0xfa}},
+		{251, []byte{0xfc, 0xfb, 0x00}},
+		{252, []byte{0xfc, 0xfc, 0x00}},
+		{255, []byte{0xfc, 0xff, 0x00}},
+		{256, []byte{0xfc, 0x00, 0x01}},
+		{0xfffe, []byte{0xfc, 0xfe, 0xff}},
+		{0xffff, []byte{0xfc, 0xff, 0xff}},
+		{0x10000, []byte{0xfd, 0x00, 0x00, 0x01}},
+		{0xfffffe, []byte{0xfd, 0xfe, 0xff, 0xff}},
+		{0xffffff, []byte{0xfd, 0xff, 0xff, 0xff}},
+		{0x1000000, []byte{0xfe, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{math.MaxUint32, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00}},
+		{math.MaxUint64 - 1, []byte{0xfe, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{math.MaxUint64, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, test := range tests {
+		if got := AppendLenEncInt(nil, test.n); bytes.Equal(got, test.encoded) == false {
+			t.Errorf("AppendLenEncInt(%d): got % x want % x", test.n, got, test.encoded)
+		}
+
+		var buf bytes.Buffer
+
+		if err := WriteLenEncInt(&buf, test.n); err != nil || bytes.Equal(buf.Bytes(), test.encoded) == false {
+			t.Errorf("WriteLenEncInt(%d): got % x %v", test.n, buf.Bytes(), err)
+		}
+
+		// Followed by the next value, which must not be consumed.
+		encoded := append(append([]byte{}, test.encoded...), 0x2a)
+
+		if n, isNull, size := ParseLenEncInt(encoded); n != test.n || isNull == true || size != len(test.encoded) {
+			t.Errorf("ParseLenEncInt(% x): got %d %v %d", test.encoded, n, isNull, size)
+		}
+
+		rd := bytes.NewReader(encoded)
+
+		if n, isNull, err := ReadLenEncInt(rd); n != test.n || isNull == true || err != nil || rd.Len() != 1 {
+			t.Errorf("ReadLenEncInt(% x): got %d %v %v, %d left", test.encoded, n, isNull, err, rd.Len())
+		}
+
+		// Every truncation of a multi-byte integer is malformed.
+		for i := 1; i < len(test.encoded); i++ {
+			if _, _, size := ParseLenEncInt(test.encoded[:i]); size != 0 {
+				t.Errorf("ParseLenEncInt(% x): got size %d", test.encoded[:i], size)
+			}
+
+			if _, _, err := ReadLenEncInt(bytes.NewReader(test.encoded[:i])); err != io.ErrUnexpectedEOF {
+				t.Errorf("ReadLenEncInt(% x): got %v", test.encoded[:i], err)
+			}
+		}
+	}
+}
+
+func TestLenEncIntMarkers(t *testing.T) {
+	if n, isNull, size := ParseLenEncInt([]byte{0xfb}); n != 0 || isNull == false || size != 1 {
+		t.Errorf("NULL: got %d %v %d", n, isNull, size)
+	}
+
+	if _, isNull, err := ReadLenEncInt(bytes.NewReader([]byte{0xfb})); isNull == false || err != nil {
+		t.Errorf("NULL: got %v %v", isNull, err)
+	}
+
+	if _, _, size := ParseLenEncInt([]byte{0xff, 0x00}); size != 0 {
+		t.Errorf("0xff: got size %d", size)
+	}
+
+	if _, _, err := ReadLenEncInt(bytes.NewReader([]byte{0xff})); err != ErrMalformedPacket {
+		t.Errorf("0xff: got %v", err)
+	}
+
+	if _, _, size := ParseLenEncInt(nil); size != 0 {
+		t.Errorf("empty: got size %d", size)
+	}
+
+	if _, _, err := ReadLenEncInt(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("empty: got %v", err)
+	}
+}
+
+func TestLenEncString(t *testing.T) {
+	for _, length := range []int{0, 1, 250, 251, 252, 0xffff, 0x10000, 0xffffff, 0x1000000} {
+		s := bytes.Repeat([]byte{'x'}, length)
+		encoded := AppendLenEncString(nil, s)
+
+		var buf bytes.Buffer
+
+		if err := WriteLenEncString(&buf, s); err != nil || bytes.Equal(buf.Bytes(), encoded) == false {
+			t.Errorf("WriteLenEncString(%d bytes): %v", length, err)
+		}
+
+		if got, isNull, size := ParseLenEncString(encoded); bytes.Equal(got, s) == false || isNull == true || size != len(encoded) {
+			t.Errorf("ParseLenEncString(%d bytes): got %d bytes %v %d", length, len(got), isNull, size)
+		}
+
+		if got, isNull, err := ReadLenEncString(bytes.NewReader(encoded)); bytes.Equal(got, s) == false || isNull == true || err != nil {
+			t.Errorf("ReadLenEncString(%d bytes): got %d bytes %v %v", length, len(got), isNull, err)
+		}
+
+		if length > 0 {
+			if _, _, size := ParseLenEncString(encoded[:len(encoded)-1]); size != 0 {
+				t.Errorf("ParseLenEncString(%d bytes truncated): got size %d", length, size)
+			}
+
+			if _, _, err := ReadLenEncString(bytes.NewReader(encoded[:len(encoded)-1])); err != io.ErrUnexpectedEOF {
+				t.Errorf("ReadLenEncString(%d bytes truncated): got %v", length, err)
+			}
+		}
+	}
+
+	if got, isNull, err := ReadLenEncString(bytes.NewReader([]byte{0xfb})); got != nil || isNull == false || err != nil {
+		t.Errorf("NULL: got %v %v %v", got, isNull, err)
+	}
+
+	// An announced length the stream does not hold is not allocated.
+	huge := []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 'x'}
+
+	if _, _, err := ReadLenEncString(bytes.NewReader(huge)); err != io.ErrUnexpectedEOF {
+		t.Errorf("huge: got %v", err)
+	}
+
+	if _, _, err := ReadLenEncString(bytes.NewReader(AppendLenEncInt(nil, math.MaxUint64))); err != ErrMalformedPacket {
+		t.Errorf("beyond int64: got %v", err)
+	}
+}
+
+func TestWriteLenEncError(t *testing.T) {
+	failure := errors.New("write failed")
+
+	if err := WriteLenEncString(failingWriter{failure}, []byte("x")); err != failure {
+		t.Errorf("got %v", err)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,50 @@
+package protocol
+
+// Offsets of the first column in the NULL bitmaps of the binary protocol:
+// none in COM_STMT_EXECUTE, 2 bits in a binary result row, whose first two
+// bits are reserved.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html#sect_protocol_binary_resultset_row_null_bitmap
+const (
+	STMT_EXECUTE_NULL_BITMAP_OFFSET = 0
+	BINARY_ROW_NULL_BITMAP_OFFSET   = 2
+)
+
+// NullBitmapSize returns the size in bytes of the NULL bitmap of columns
+// columns starting at offset.
+func NullBitmapSize(columns int, offset int) int {
+	return (columns + offset + 7) / 8
+}
+
+// AppendNullBitmap appends a NULL bitmap of columns columns starting at
+// offset, with the bits of the NULL columns set by isNull.
+func AppendNullBitmap(b []byte, columns int, offset int, isNull func(column int) bool) []byte {
+	start := len(b)
+	b = append(b, make([]byte, NullBitmapSize(columns, offset))...)
+
+	for column := 0; column < columns; column++ {
+		if isNull(column) == true {
+			SetNull(b[start:], column, offset)
+		}
+	}
+
+	return b
+}
+
+// SetNull sets the bit of column in bitmap.
+func SetNull(bitmap []byte, column int, offset int) {
+	bit := column + offset
+	bitmap[bit/8] |= 1 << uint(bit%8)
+}
+
+// IsNull reports whether the bit of column is set in bitmap. A column
+// beyond the bitmap is not NULL.
+func IsNull(bitmap []byte, column int, offset int) bool {
+	bit := column + offset
+
+	if bit/8 >= len(bitmap) {
+		return false
+	}
+
+	return bitmap[bit/8]&(1<<uint(bit%8)) != 0
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNullBitmap(t *testing.T) {
+	nulls := map[int]bool{0: true, 5: true, 6: true, 13: true}
+
+	tests := []struct {
+		columns int
+		offset  int
+		bitmap  []byte
+	}{
+		{14, STMT_EXECUTE_NULL_BITMAP_OFFSET, []byte{0x61, 0x20}},
+		{14, BINARY_ROW_NULL_BITMAP_OFFSET, []byte{0x84, 0x81}},
+		{15, BINARY_ROW_NULL_BITMAP_OFFSET, []byte{0x84, 0x81, 0x00}},
+		{6, BINARY_ROW_NULL_BITMAP_OFFSET, []byte{0x84}},
+		{7, BINARY_ROW_NULL_BITMAP_OFFSET, []byte{0x84, 0x01}},
+		{0, STMT_EXECUTE_NULL_BITMAP_OFFSET, []byte{}},
+	}
+
+	for _, test := range tests {
+		if size := NullBitmapSize(test.columns, test.offset); size != len(test.bitmap) {
+			t.Errorf("NullBitmapSize(%d, %d): got %d", test.columns, test.offset, size)
+		}
+
+		bitmap := AppendNullBitmap([]byte{0xff}, test.columns, test.offset, func(column int) bool { return nulls[column] })
+
+		if bytes.Equal(bitmap[1:], test.bitmap) == false {
+			t.Errorf("AppendNullBitmap(%d, %d): got % x want % x", test.columns, test.offset, bitmap[1:], test.bitmap)
+		}
+
+		for column := 0; column < test.columns; column++ {
+			if IsNull(test.bitmap, column, test.offset) != nulls[column] {
+				t.Errorf("IsNull(% x, %d, %d): got %v", test.bitmap, column, test.offset, nulls[column] == false)
+			}
+		}
+	}
+
+	if IsNull([]byte{0xff}, 6, BINARY_ROW_NULL_BITMAP_OFFSET) == true {
+		t.Error("column beyond the bitmap is NULL")
+	}
+}
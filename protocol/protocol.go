@@ -0,0 +1,119 @@
+// Package protocol encodes and decodes the packets of the MySQL/MariaDB
+// client/server protocol without a connection: the packet header, length
+// encoded integers and strings, the handshake, OK, ERR and EOF packets and
+// column definitions. It is the packet layer of package mysql, exported for
+// protocol tooling such as fuzzers, proxies and traffic analyzers.
+//
+// Parse functions check every read against the payload and return
+// ErrMalformedPacket rather than panic on truncated input. Append functions
+// append the payload, without its header, to a slice like strconv.Append.
+// Only the 4.1 protocol, which every server since MySQL 4.1 speaks, is
+// supported.
+package protocol
+
+import (
+	"errors"
+)
+
+// Packet headers.
+const (
+	OK_PACKET           = 0x00
+	LOCAL_INFILE_PACKET = 0xfb
+	EOF_PACKET          = 0xfe
+	ERR_PACKET          = 0xff
+
+	HEADER_SIZE      = 4
+	MAX_PAYLOAD_SIZE = 1<<24 - 1 // longer payloads are split into several packets
+)
+
+// Capabilities the codecs depend on, the same flags as the ClientFlags of
+// package mysql.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/group__group__cs__capabilities__flags.html
+const (
+	CLIENT_LONG_PASSWORD           uint32 = 1 << 0 // unset by MariaDB servers, see Handshake.MariaDBCapabilities
+	CLIENT_CONNECT_WITH_DB         uint32 = 1 << 3
+	CLIENT_PROTOCOL_41             uint32 = 1 << 9
+	CLIENT_SSL                     uint32 = 1 << 11
+	CLIENT_SECURE_CONNECTION       uint32 = 1 << 15
+	CLIENT_PLUGIN_AUTH             uint32 = 1 << 19
+	CLIENT_CONNECT_ATTRS           uint32 = 1 << 20
+	CLIENT_PLUGIN_AUTH_LENENC_DATA uint32 = 1 << 21
+	CLIENT_SESSION_TRACK           uint32 = 1 << 23
+)
+
+// SERVER_SESSION_STATE_CHANGED is the status flag of an OK packet carrying
+// session state changes.
+const SERVER_SESSION_STATE_CHANGED = 1 << 14
+
+var (
+	ErrMalformedPacket = errors.New("Malformed Packet")
+)
+
+// ParseHeader decodes a packet header, the payload length [3 bytes] and
+// the sequence number [1 byte].
+func ParseHeader(header []byte) (int, uint8, error) {
+	if len(header) < HEADER_SIZE {
+		return 0, 0, ErrMalformedPacket
+	}
+
+	return int(header[0]) | int(header[1])<<8 | int(header[2])<<16, header[3], nil
+}
+
+// AppendHeader appends the header of a packet of length bytes.
+func AppendHeader(b []byte, length int, seq uint8) []byte {
+	return append(b, byte(length), byte(length>>8), byte(length>>16), seq)
+}
+
+// AppendPacket appends payload with its header, split into several packets
+// when it is too long for one, and returns the sequence number of the next
+// packet.
+func AppendPacket(b []byte, seq uint8, payload []byte) ([]byte, uint8) {
+	for {
+		n := len(payload)
+
+		if n > MAX_PAYLOAD_SIZE {
+			n = MAX_PAYLOAD_SIZE
+		}
+
+		b = AppendHeader(b, n, seq)
+		b = append(b, payload[:n]...)
+		payload = payload[n:]
+		seq++
+
+		// A payload of exactly MAX_PAYLOAD_SIZE bytes is followed by an
+		// empty packet.
+		if n < MAX_PAYLOAD_SIZE {
+			return b, seq
+		}
+	}
+}
+
+// uint16At and the following decode little endian integers.
+func uint16At(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func uint32At(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n), byte(n>>8))
+}
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+}
+
+// parseNullTerminated returns the string at the start of b and the number
+// of bytes used including the zero byte, 0 when it is missing.
+func parseNullTerminated(b []byte) (string, int) {
+	for i := range b {
+		if b[i] == 0 {
+			return string(b[:i]), i + 1
+		}
+	}
+
+	return "", 0
+}
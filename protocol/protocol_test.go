@@ -0,0 +1,231 @@
+package protocol
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAppendPacket(t *testing.T) {
+	b, seq := AppendPacket(nil, 3, []byte("SELECT 1"))
+
+	if seq != 4 || bytes.Equal(b, append([]byte{8, 0, 0, 3}, "SELECT 1"...)) == false {
+		t.Errorf("got %v, seq %d", b, seq)
+	}
+
+	length, seq, err := ParseHeader(b)
+
+	if err != nil || length != 8 || seq != 3 {
+		t.Errorf("header: %d %d %v", length, seq, err)
+	}
+
+	// A payload of exactly MAX_PAYLOAD_SIZE bytes is followed by an empty
+	// packet.
+	b, seq = AppendPacket(nil, 0, make([]byte, MAX_PAYLOAD_SIZE))
+
+	if seq != 2 || len(b) != MAX_PAYLOAD_SIZE+2*HEADER_SIZE || bytes.Equal(b[len(b)-HEADER_SIZE:], []byte{0, 0, 0, 1}) == false {
+		t.Errorf("split: %d bytes, seq %d", len(b), seq)
+	}
+}
+
+func TestOKPacket(t *testing.T) {
+	tests := []struct {
+		ok           OKPacket
+		capabilities uint32
+	}{
+		{OKPacket{AffectedRows: 3, LastInsertID: 1 << 20, StatusFlags: 2, Warnings: 1, Info: "Rows matched: 3  Changed: 3  Warnings: 1"}, CLIENT_PROTOCOL_41},
+		{OKPacket{AffectedRows: 1, StatusFlags: 2}, CLIENT_PROTOCOL_41 | CLIENT_SESSION_TRACK},
+		{OKPacket{StatusFlags: 2 | SERVER_SESSION_STATE_CHANGED, SessionStateChanges: []byte{1, 5, 4, 't', 'e', 's', 't'}}, CLIENT_PROTOCOL_41 | CLIENT_SESSION_TRACK},
+	}
+
+	for _, test := range tests {
+		payload := AppendOKPacket(nil, &test.ok, test.capabilities)
+
+		ok, err := ParseOKPacket(payload, test.capabilities)
+
+		if err != nil || reflect.DeepEqual(*ok, test.ok) == false {
+			t.Errorf("%+v: got %+v %v", test.ok, ok, err)
+		}
+	}
+
+	if _, err := ParseOKPacket([]byte{OK_PACKET, 0xfc, 1}, CLIENT_PROTOCOL_41); err != ErrMalformedPacket {
+		t.Errorf("truncated: %v", err)
+	}
+}
+
+func TestErrPacket(t *testing.T) {
+	payload := append([]byte{ERR_PACKET, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied"...)
+
+	e, err := ParseErrPacket(payload)
+
+	if err != nil || *e != (ErrPacket{Code: 1045, SQLState: "28000", Message: "Access denied"}) {
+		t.Errorf("got %+v %v", e, err)
+	}
+
+	if bytes.Equal(AppendErrPacket(nil, e), payload) == false {
+		t.Errorf("append: got %q", AppendErrPacket(nil, e))
+	}
+
+	// Sent without an SQL state during the handshake by old servers.
+	e, err = ParseErrPacket(append([]byte{ERR_PACKET, 0x10, 0x04}, "Too many connections"...))
+
+	if err != nil || *e != (ErrPacket{Code: 1040, Message: "Too many connections"}) {
+		t.Errorf("got %+v %v", e, err)
+	}
+}
+
+func TestEOFPacket(t *testing.T) {
+	payload := AppendEOFPacket(nil, &EOFPacket{Warnings: 1, StatusFlags: 0x22})
+
+	eof, err := ParseEOFPacket(payload)
+
+	if err != nil || *eof != (EOFPacket{Warnings: 1, StatusFlags: 0x22}) {
+		t.Errorf("got %+v %v", eof, err)
+	}
+
+	// A row whose first value is longer than 1<<24 bytes.
+	if IsEOFPacket(AppendLenEncInt(nil, 1<<24)) == true {
+		t.Error("row taken for an EOF packet")
+	}
+}
+
+func TestColumnDefinition(t *testing.T) {
+	col := ColumnDefinition{
+		Catalog:      "def",
+		Schema:       "world",
+		Table:        "c",
+		OrgTable:     "city",
+		Name:         "n",
+		OrgName:      "name",
+		CharacterSet: 45,
+		ColumnLength: 140,
+		Type:         0xfd,
+		Flags:        1,
+		Decimals:     0,
+	}
+
+	payload := AppendColumnDefinition(nil, &col, 0)
+
+	parsed, err := ParseColumnDefinition(payload, 0)
+
+	if err != nil || reflect.DeepEqual(*parsed, col) == false {
+		t.Errorf("got %+v %v", parsed, err)
+	}
+
+	if _, err := ParseColumnDefinition(payload[:len(payload)-4], 0); err != ErrMalformedPacket {
+		t.Errorf("truncated: %v", err)
+	}
+
+	parsed, err = ParseColumnDefinition(payload, COLUMN_LITE)
+
+	if err != nil || parsed.Name != "n" || parsed.Schema != "" || parsed.OrgName != "" || parsed.Type != col.Type {
+		t.Errorf("lite: got %+v %v", parsed, err)
+	}
+
+	// A MariaDB JSON column listed by COM_FIELD_LIST.
+	col.ExtendedFormat = "json"
+	col.Default = []byte("{}")
+	options := COLUMN_EXTENDED_METADATA | COLUMN_DEFAULT
+
+	parsed, err = ParseColumnDefinition(AppendColumnDefinition(nil, &col, options), options)
+
+	if err != nil || reflect.DeepEqual(*parsed, col) == false {
+		t.Errorf("extended: got %+v %v", parsed, err)
+	}
+
+	col.Default = nil
+
+	parsed, err = ParseColumnDefinition(AppendColumnDefinition(nil, &col, options), options)
+
+	if err != nil || parsed.Default != nil {
+		t.Errorf("NULL default: got %+v %v", parsed, err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	h := Handshake{
+		ProtocolVersion: 10,
+		ServerVersion:   "5.5.5-10.11.6-MariaDB",
+		ConnectionID:    7,
+		AuthPluginData:  []byte("abcdefgh123456789012"),
+		Capabilities:    CLIENT_PROTOCOL_41 | CLIENT_SECURE_CONNECTION | CLIENT_PLUGIN_AUTH | CLIENT_SESSION_TRACK,
+		CharacterSet:    45,
+		StatusFlags:     2,
+
+		MariaDBCapabilities:  1 << 4,
+		AuthPluginDataLength: 21,
+		AuthPluginName:       "mysql_native_password",
+	}
+
+	parsed, err := ParseHandshake(AppendHandshake(nil, &h))
+
+	if err != nil || reflect.DeepEqual(*parsed, h) == false {
+		t.Errorf("got %+v %v", parsed, err)
+	}
+
+	// MySQL 5.5.8 does not terminate the plugin name.
+	payload := AppendHandshake(nil, &h)
+
+	parsed, err = ParseHandshake(payload[:len(payload)-1])
+
+	if err != nil || parsed.AuthPluginName != "mysql_native_password" {
+		t.Errorf("unterminated plugin name: %+v %v", parsed, err)
+	}
+
+	if _, err := ParseHandshake([]byte{10, '8', '.', '0'}); err != ErrMalformedPacket {
+		t.Errorf("truncated: %v", err)
+	}
+
+	// A HandshakeV9 of MySQL 3.20.
+	payload = append([]byte{9}, "3.20.32a\x00"...)
+	payload = append(payload, 1, 0, 0, 0)
+	payload = append(payload, "scramble\x00"...)
+
+	_, err = ParseHandshake(payload)
+
+	if versionErr, ok := err.(*VersionError); ok == false || versionErr.Version != 9 {
+		t.Errorf("protocol version 9: got %v", err)
+	}
+}
+
+func TestHandshakeResponse(t *testing.T) {
+	r := HandshakeResponse{
+		Capabilities:   CLIENT_LONG_PASSWORD | CLIENT_PROTOCOL_41 | CLIENT_SECURE_CONNECTION | CLIENT_CONNECT_WITH_DB | CLIENT_PLUGIN_AUTH | CLIENT_PLUGIN_AUTH_LENENC_DATA | CLIENT_CONNECT_ATTRS,
+		MaxPacketSize:  1 << 24,
+		CharacterSet:   45,
+		Username:       "root",
+		AuthResponse:   bytes.Repeat([]byte{0xaa}, 20),
+		Database:       "test",
+		AuthPluginName: "mysql_native_password",
+		Attributes:     map[string]string{"_client_name": "go-mysql-pure", "program_name": "test"},
+	}
+
+	parsed, err := ParseHandshakeResponse(AppendHandshakeResponse(nil, &r))
+
+	if err != nil || reflect.DeepEqual(*parsed, r) == false {
+		t.Errorf("got %+v %v", parsed, err)
+	}
+
+	// Without CLIENT_PLUGIN_AUTH_LENENC_DATA nor attributes.
+	r.Capabilities &^= CLIENT_PLUGIN_AUTH_LENENC_DATA | CLIENT_CONNECT_ATTRS
+	r.Attributes = nil
+
+	parsed, err = ParseHandshakeResponse(AppendHandshakeResponse(nil, &r))
+
+	if err != nil || reflect.DeepEqual(*parsed, r) == false {
+		t.Errorf("got %+v %v", parsed, err)
+	}
+
+	ssl := HandshakeResponse{Capabilities: CLIENT_PROTOCOL_41 | CLIENT_SSL, MaxPacketSize: 1 << 24, CharacterSet: 45, MariaDBCapabilities: 1 << 4}
+	payload := AppendSSLRequest(nil, &ssl)
+
+	if IsSSLRequest(payload) == false {
+		t.Error("SSL request not recognized")
+	}
+
+	parsed, err = ParseHandshakeResponse(payload)
+
+	if err != nil || reflect.DeepEqual(*parsed, ssl) == false {
+		t.Errorf("SSL request: got %+v %v", parsed, err)
+	}
+}
@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"time"
+)
+
+// AppendBinaryDateTime appends t as a DATE, DATETIME or TIMESTAMP value of
+// the binary protocol, the length [1 byte] followed by the year [2 bytes],
+// month, day, hour, minute, second [1 byte each] and microseconds [4 bytes],
+// leaving out the trailing parts which are zero: 0, 4, 7 or 11 bytes. The
+// fields of t are taken in its location; the zero time.Time is the zero
+// date 0000-00-00.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html#sect_protocol_binary_resultset_row_value_date
+func AppendBinaryDateTime(b []byte, t time.Time) []byte {
+	if t.IsZero() == true {
+		return append(b, 0)
+	}
+
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	microsecond := uint32(t.Nanosecond() / 1000)
+
+	switch {
+	case microsecond != 0:
+		b = append(b, 11, byte(year), byte(year>>8), byte(month), byte(day), byte(hour), byte(minute), byte(second))
+		return appendUint32(b, microsecond)
+	case hour != 0 || minute != 0 || second != 0:
+		return append(b, 7, byte(year), byte(year>>8), byte(month), byte(day), byte(hour), byte(minute), byte(second))
+	}
+
+	return append(b, 4, byte(year), byte(year>>8), byte(month), byte(day))
+}
+
+// ParseBinaryDateTime decodes a DATE, DATETIME or TIMESTAMP value of the
+// binary protocol in loc, returning it and the number of bytes used. The
+// zero date, and other dates with a zero month or day, which time.Time
+// cannot represent, are returned as the zero time.Time.
+func ParseBinaryDateTime(b []byte, loc *time.Location) (time.Time, int, error) {
+	if len(b) == 0 {
+		return time.Time{}, 0, ErrMalformedPacket
+	}
+
+	length := int(b[0])
+
+	if length != 0 && length != 4 && length != 7 && length != 11 || len(b) < 1+length {
+		return time.Time{}, 0, ErrMalformedPacket
+	}
+
+	if length == 0 {
+		return time.Time{}, 1, nil
+	}
+
+	year := int(uint16At(b[1:]))
+	month := int(b[3])
+	day := int(b[4])
+
+	var hour, minute, second, microsecond int
+
+	if length >= 7 {
+		hour, minute, second = int(b[5]), int(b[6]), int(b[7])
+	}
+
+	if length == 11 {
+		microsecond = int(uint32At(b[8:]))
+	}
+
+	if month == 0 || day == 0 {
+		return time.Time{}, 1 + length, nil
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, microsecond*1000, loc), 1 + length, nil
+}
+
+// AppendBinaryTime appends d as a TIME value of the binary protocol, the
+// length [1 byte] followed by the sign [1 byte], days [4 bytes], hours,
+// minutes, seconds [1 byte each] and microseconds [4 bytes], leaving out
+// what is zero: 0, 8 or 12 bytes. Below a microsecond d is truncated.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html#sect_protocol_binary_resultset_row_value_time
+func AppendBinaryTime(b []byte, d time.Duration) []byte {
+	negative := byte(0)
+
+	// Dividing first keeps the smallest duration from overflowing when
+	// negated.
+	microseconds := d / time.Microsecond
+
+	if microseconds < 0 {
+		negative = 1
+		microseconds = -microseconds
+	}
+
+	if microseconds == 0 {
+		return append(b, 0)
+	}
+
+	seconds := int64(microseconds / 1000000)
+	micro := uint32(microseconds % 1000000)
+	days := uint32(seconds / 86400)
+
+	if micro != 0 {
+		b = append(b, 12, negative)
+	} else {
+		b = append(b, 8, negative)
+	}
+
+	b = appendUint32(b, days)
+	b = append(b, byte(seconds/3600%24), byte(seconds/60%60), byte(seconds%60))
+
+	if micro != 0 {
+		b = appendUint32(b, micro)
+	}
+
+	return b
+}
+
+// ParseBinaryTime decodes a TIME value of the binary protocol, returning it
+// and the number of bytes used.
+func ParseBinaryTime(b []byte) (time.Duration, int, error) {
+	if len(b) == 0 {
+		return 0, 0, ErrMalformedPacket
+	}
+
+	length := int(b[0])
+
+	if length != 0 && length != 8 && length != 12 || len(b) < 1+length {
+		return 0, 0, ErrMalformedPacket
+	}
+
+	if length == 0 {
+		return 0, 1, nil
+	}
+
+	d := time.Duration(uint32At(b[2:]))*24*time.Hour +
+		time.Duration(b[6])*time.Hour +
+		time.Duration(b[7])*time.Minute +
+		time.Duration(b[8])*time.Second
+
+	if length == 12 {
+		d += time.Duration(uint32At(b[9:])) * time.Microsecond
+	}
+
+	if b[1] == 1 {
+		d = -d
+	}
+
+	return d, 1 + length, nil
+}
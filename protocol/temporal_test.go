@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestBinaryDateTime(t *testing.T) {
+	tests := []struct {
+		t       time.Time
+		encoded []byte
+	}{
+		{time.Time{}, []byte{0}},
+		{time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC), []byte{4, 0xe0, 0x07, 1, 2}},
+		{time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC), []byte{7, 0xe0, 0x07, 1, 2, 3, 4, 5}},
+		{time.Date(2016, 1, 2, 0, 0, 0, 6000, time.UTC), []byte{11, 0xe0, 0x07, 1, 2, 0, 0, 0, 6, 0, 0, 0}},
+		{time.Date(9999, 12, 31, 23, 59, 59, 999999000, time.UTC), []byte{11, 0x0f, 0x27, 12, 31, 23, 59, 59, 0x3f, 0x42, 0x0f, 0}},
+	}
+
+	for _, test := range tests {
+		encoded := AppendBinaryDateTime(nil, test.t)
+
+		if bytes.Equal(encoded, test.encoded) == false {
+			t.Errorf("AppendBinaryDateTime(%v): got % x want % x", test.t, encoded, test.encoded)
+		}
+
+		parsed, n, err := ParseBinaryDateTime(append(encoded, 0x2a), time.UTC)
+
+		if err != nil || parsed.Equal(test.t) == false || n != len(test.encoded) {
+			t.Errorf("ParseBinaryDateTime(% x): got %v %d %v", test.encoded, parsed, n, err)
+		}
+
+		for i := 1; i < len(test.encoded); i++ {
+			if _, _, err := ParseBinaryDateTime(test.encoded[:i], time.UTC); err != ErrMalformedPacket {
+				t.Errorf("ParseBinaryDateTime(% x): got %v", test.encoded[:i], err)
+			}
+		}
+	}
+
+	// The fields are those of the location of the time.
+	taipei := time.FixedZone("CST", 8*3600)
+
+	if encoded := AppendBinaryDateTime(nil, time.Date(2016, 1, 2, 3, 4, 5, 0, taipei)); bytes.Equal(encoded, tests[2].encoded) == false {
+		t.Errorf("location: got % x", encoded)
+	}
+
+	if parsed, _, _ := ParseBinaryDateTime(tests[2].encoded, taipei); parsed.Location() != taipei || parsed.Hour() != 3 {
+		t.Errorf("location: got %v", parsed)
+	}
+
+	// A date with a zero day.
+	if parsed, n, err := ParseBinaryDateTime([]byte{4, 0xe0, 0x07, 1, 0}, time.UTC); err != nil || parsed.IsZero() == false || n != 5 {
+		t.Errorf("zero day: got %v %d %v", parsed, n, err)
+	}
+
+	if _, _, err := ParseBinaryDateTime([]byte{5, 0, 0, 0, 0, 0}, time.UTC); err != ErrMalformedPacket {
+		t.Errorf("length 5: got %v", err)
+	}
+}
+
+func TestBinaryTime(t *testing.T) {
+	tests := []struct {
+		d       time.Duration
+		encoded []byte
+	}{
+		{0, []byte{0}},
+		{3*time.Hour + 4*time.Minute + 5*time.Second, []byte{8, 0, 0, 0, 0, 0, 3, 4, 5}},
+		{-(3*time.Hour + 4*time.Minute + 5*time.Second), []byte{8, 1, 0, 0, 0, 0, 3, 4, 5}},
+		{838*time.Hour + 59*time.Minute + 59*time.Second, []byte{8, 0, 34, 0, 0, 0, 22, 59, 59}},
+		{time.Second + 6*time.Microsecond, []byte{12, 0, 0, 0, 0, 0, 0, 0, 1, 6, 0, 0, 0}},
+		{-(26*time.Hour + 999999*time.Microsecond), []byte{12, 1, 1, 0, 0, 0, 2, 0, 0, 0x3f, 0x42, 0x0f, 0}},
+	}
+
+	for _, test := range tests {
+		encoded := AppendBinaryTime(nil, test.d)
+
+		if bytes.Equal(encoded, test.encoded) == false {
+			t.Errorf("AppendBinaryTime(%v): got % x want % x", test.d, encoded, test.encoded)
+		}
+
+		parsed, n, err := ParseBinaryTime(append(encoded, 0x2a))
+
+		if err != nil || parsed != test.d || n != len(test.encoded) {
+			t.Errorf("ParseBinaryTime(% x): got %v %d %v", test.encoded, parsed, n, err)
+		}
+
+		for i := 1; i < len(test.encoded); i++ {
+			if _, _, err := ParseBinaryTime(test.encoded[:i]); err != ErrMalformedPacket {
+				t.Errorf("ParseBinaryTime(% x): got %v", test.encoded[:i], err)
+			}
+		}
+	}
+
+	// Below a microsecond is truncated.
+	if encoded := AppendBinaryTime(nil, 999*time.Nanosecond); bytes.Equal(encoded, []byte{0}) == false {
+		t.Errorf("nanoseconds: got % x", encoded)
+	}
+}
@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+// Query sends a COM_QUERY and returns the rows of its result set. The
+// connection is busy until the rows are read to the end or closed.
+func (c *Connection) Query(query string) (*Rows, error) {
+	c.mutex.Lock()
+	defer c.unlockOnPanic()
+
+	start := time.Now()
+
+	rows, err := c.query(query)
+
+	if err != nil {
+		c.audit(query, start, nil, err)
+	} else {
+		c.audit(query, start, rows.result, nil)
+	}
+
+	if err != nil {
+		c.recordStatement(query, start, 0, err)
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	rows.query = query
+	rows.start = start
+
+	if rows.done == true && c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+		rows.release()
+	}
+
+	return rows, nil
+}
+
+// Exec sends a COM_QUERY for a statement that returns no rows, e.g. an
+// INSERT or an UPDATE, and returns its OK packet. The rows of a statement
+// that does return some are discarded and the Result is nil.
+func (c *Connection) Exec(query string) (*Result, error) {
+	return c.ExecContext(context.Background(), query)
+}
+
+func (c *Connection) query(query string) (*Rows, error) {
+	var err error
+
+	err = c.writeCommand(COM_QUERY, []byte(query))
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &Rows{c: c}
+
+	err = rows.readResultSetHeader()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// readColumnCount reads the first packet of a command response. It returns
+// the column count of the result set and whether its column definitions
+// follow, or zero and the parsed OK packet when the statement did not
+// produce one.
+// Reference:
+// https://mariadb.com/kb/en/mariadb/com_query/#response
+func (c *Connection) readColumnCount() (uint64, bool, *Result, error) {
+	c.interruptible = true
+
+	payload, err := c.readPacket()
+
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	if len(payload) == 0 {
+		return 0, false, nil, ErrMalformedPacket
+	}
+
+	switch payload[0] {
+	case OK_PACKET:
+		result, err := c.parseOKPacket(payload)
+		return 0, false, result, err
+	case ERR_PACKET:
+		return 0, false, nil, parseErrPacket(payload)
+	case LOCAL_INFILE_PACKET:
+		result, err := c.answerLocalInfile(string(payload[1:]))
+		return 0, false, result, err
+	}
+
+	// column count [length encoded integer]
+	columnCount, _, n := unpackLenEncInt(payload)
+
+	if n == 0 {
+		return 0, false, nil, ErrMalformedPacket
+	}
+
+	// metadata follows [1 byte]
+	// Only present with MARIADB_CLIENT_CACHE_METADATA, 0 when the column
+	// definitions of a COM_STMT_EXECUTE are unchanged and left out.
+	metadata := true
+
+	if c.mariaDBCapabilities&MARIADB_CLIENT_CACHE_METADATA != 0 && n < len(payload) {
+		metadata = payload[n] != 0
+	}
+
+	return columnCount, metadata, nil, nil
+}
+
+// columnOptions returns the optional parts of the column definitions sent
+// on the connection.
+func (c *Connection) columnOptions() protocol.ColumnOptions {
+	var options protocol.ColumnOptions
+
+	if c.param.LiteColumnMetadata == true {
+		options |= protocol.COLUMN_LITE
+	}
+
+	if c.mariaDBCapabilities&MARIADB_CLIENT_EXTENDED_METADATA != 0 {
+		options |= protocol.COLUMN_EXTENDED_METADATA
+	}
+
+	return options
+}
+
+// readColumns reads the column definition packets followed by the EOF packet.
+func (c *Connection) readColumns(columnCount uint64) ([]Column, error) {
+	// The column count comes from the server: only allocate for the
+	// columns actually received.
+	capacity := columnCount
+
+	if capacity > 64 {
+		capacity = 64
+	}
+
+	columns := make([]Column, 0, capacity)
+
+	for i := uint64(0); i < columnCount; i++ {
+		payload, err := c.readPacket()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var column Column
+
+		err = column.parse(payload, c.columnOptions())
+
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	payload, err := c.readPacket()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isEOFPacket(payload) == false {
+		return nil, ErrUnexpectedPacket
+	}
+
+	_, err = c.parseEOFPacket(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
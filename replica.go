@@ -0,0 +1,195 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotReplica         = errors.New("Server is not a replica")
+	ErrReplicationStopped = errors.New("Replication is stopped")
+)
+
+// REPLICA_LAG_QUERY measures the lag of every applier of MySQL 8.0.1+ from
+// the original commit time of the transaction it applies; an idle applier
+// has caught up.
+const REPLICA_LAG_QUERY = "SELECT c.SERVICE_STATE, w.SERVICE_STATE, " +
+	"IF(w.APPLYING_TRANSACTION = '', 0, TIMESTAMPDIFF(MICROSECOND, w.APPLYING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP, NOW(6))) " +
+	"FROM performance_schema.replication_applier_status_by_worker AS w " +
+	"JOIN performance_schema.replication_connection_status AS c USING (CHANNEL_NAME)"
+
+// ReplicaLag returns how far behind its source the replica c is connected
+// to is, the largest lag of its channels. The performance_schema views of
+// MySQL 8.0 measure it to the microsecond from the original commit
+// timestamps, where Seconds_Behind_Source counts whole seconds from the
+// start of the statement; older servers, MariaDB and servers with the
+// performance_schema disabled fall back to SHOW REPLICA STATUS. It returns
+// ErrNotReplica when replication is not configured, and
+// ErrReplicationStopped when one of its threads is not running. ctx is
+// checked before each statement is sent.
+func ReplicaLag(ctx context.Context, c *Connection) (time.Duration, error) {
+	if c.isMariaDB() == false && c.serverAtLeast("8.0.1", "") == true {
+		lag, err := c.performanceSchemaReplicaLag(ctx)
+
+		// ErrNotReplica may only mean that the performance_schema is
+		// disabled, which SHOW REPLICA STATUS tells apart.
+		if err == nil || err == ErrReplicationStopped {
+			return lag, err
+		}
+	}
+
+	return c.replicaStatusLag(ctx)
+}
+
+// DBReplicaLag is ReplicaLag for a connection of db, e.g. as
+// HealthHandler.ReplicaLag.
+func DBReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	conn, err := db.Conn(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer conn.Close()
+
+	var lag time.Duration
+
+	err = conn.Raw(func(dc interface{}) error {
+		lag, err = ReplicaLag(ctx, dc.(*driverConn).c)
+		return err
+	})
+
+	return lag, err
+}
+
+// performanceSchemaReplicaLag runs REPLICA_LAG_QUERY, returning
+// ErrNotReplica without rows.
+func (c *Connection) performanceSchemaReplicaLag(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	rows, err := c.Query(REPLICA_LAG_QUERY)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var lag time.Duration
+
+	found := false
+	stopped := false
+
+	for rows.Next() == true {
+		values := rows.Values()
+
+		if len(values) < 3 {
+			rows.Close()
+			return 0, ErrMalformedPacket
+		}
+
+		found = true
+
+		if string(values[0]) != "ON" || string(values[1]) != "ON" {
+			stopped = true
+		}
+
+		microseconds, _ := strconv.ParseInt(string(values[2]), 10, 64)
+
+		if d := time.Duration(microseconds) * time.Microsecond; d > lag {
+			lag = d
+		}
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if found == false {
+		return 0, ErrNotReplica
+	}
+
+	if stopped == true {
+		return lag, ErrReplicationStopped
+	}
+
+	return lag, nil
+}
+
+// replicaStatusLag reads Seconds_Behind_Source from SHOW REPLICA STATUS,
+// SHOW SLAVE STATUS before MySQL 8.0.22 and MariaDB 10.5.1.
+func (c *Connection) replicaStatusLag(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	query := "SHOW REPLICA STATUS"
+
+	if c.serverAtLeast("8.0.22", "10.5.1") == false {
+		query = "SHOW SLAVE STATUS"
+	}
+
+	rows, err := c.Query(query)
+
+	if err != nil {
+		return 0, err
+	}
+
+	column := -1
+
+	for i, col := range rows.Columns() {
+		if col.Name == "Seconds_Behind_Source" || col.Name == "Seconds_Behind_Master" {
+			column = i
+		}
+	}
+
+	var lag time.Duration
+
+	found := false
+	stopped := false
+
+	for rows.Next() == true {
+		values := rows.Values()
+
+		if column < 0 || column >= len(values) {
+			rows.Close()
+			return 0, ErrMalformedPacket
+		}
+
+		found = true
+
+		// NULL while the SQL thread, or the IO thread with nothing
+		// left to apply, is not running.
+		if values[column] == nil {
+			stopped = true
+			continue
+		}
+
+		seconds, _ := strconv.ParseInt(string(values[column]), 10, 64)
+
+		if d := time.Duration(seconds) * time.Second; d > lag {
+			lag = d
+		}
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if found == false {
+		return 0, ErrNotReplica
+	}
+
+	if stopped == true {
+		return lag, ErrReplicationStopped
+	}
+
+	return lag, nil
+}
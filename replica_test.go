@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReplicaLag(t *testing.T) {
+	tests := []struct {
+		version string
+		queries []string
+		columns [][]byte
+		rows    [][]byte
+		lag     time.Duration
+		err     error
+	}{
+		{
+			"8.0.36", []string{REPLICA_LAG_QUERY},
+			[][]byte{
+				columnPacket("SERVICE_STATE", MYSQL_TYPE_VAR_STRING, 0, 45),
+				columnPacket("SERVICE_STATE", MYSQL_TYPE_VAR_STRING, 0, 45),
+				columnPacket("lag", MYSQL_TYPE_LONGLONG, 0, 63),
+			},
+			[][]byte{textRowPacket("ON", "ON", "1500000"), textRowPacket("ON", "ON", "0")},
+			1500 * time.Millisecond, nil,
+		},
+		{
+			// The performance_schema is disabled.
+			"8.0.36", []string{REPLICA_LAG_QUERY, "SHOW REPLICA STATUS"},
+			[][]byte{
+				columnPacket("Replica_IO_State", MYSQL_TYPE_VAR_STRING, 0, 45),
+				columnPacket("Seconds_Behind_Source", MYSQL_TYPE_LONGLONG, 0, 63),
+			},
+			[][]byte{textRowPacket("", nil)},
+			0, ErrReplicationStopped,
+		},
+		{
+			"5.7.44", []string{"SHOW SLAVE STATUS"},
+			[][]byte{
+				columnPacket("Slave_IO_State", MYSQL_TYPE_VAR_STRING, 0, 45),
+				columnPacket("Seconds_Behind_Master", MYSQL_TYPE_LONGLONG, 0, 63),
+			},
+			[][]byte{textRowPacket("Waiting for master to send event", "3")},
+			3 * time.Second, nil,
+		},
+		{
+			"5.5.5-10.11.6-MariaDB", []string{"SHOW REPLICA STATUS"},
+			[][]byte{
+				columnPacket("Slave_IO_State", MYSQL_TYPE_VAR_STRING, 0, 45),
+				columnPacket("Seconds_Behind_Master", MYSQL_TYPE_LONGLONG, 0, 63),
+			},
+			nil,
+			0, ErrNotReplica,
+		},
+	}
+
+	for _, test := range tests {
+		c, serverConn := newPipeConnection(ConnectionParameter{})
+		c.ServerVersion = test.version
+		server := newFakeServer(t, serverConn)
+
+		go func() {
+			for i, query := range test.queries {
+				payload := server.readCommand()
+
+				if string(payload[1:]) != query {
+					t.Errorf("%s: unexpected query %q", test.version, payload[1:])
+				}
+
+				if i < len(test.queries)-1 {
+					server.writeResultSet(1, test.columns[:1], nil, 0)
+				} else {
+					server.writeResultSet(1, test.columns, test.rows, 0)
+				}
+			}
+		}()
+
+		lag, err := ReplicaLag(context.Background(), c)
+
+		if lag != test.lag || err != test.err {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.version, lag, err, test.lag, test.err)
+		}
+
+		serverConn.Close()
+	}
+}
@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Result holds the outcome of a statement as reported by the OK packet.
+type Result struct {
+	affectedRows uint64
+	lastInsertID uint64
+	statusFlags  uint16
+	warnings     uint16
+	info         string
+}
+
+// AffectedRows returns the number of rows changed, deleted or inserted.
+func (r *Result) AffectedRows() uint64 {
+	return r.affectedRows
+}
+
+// LastInsertId returns the AUTO_INCREMENT value generated by the statement.
+func (r *Result) LastInsertId() uint64 {
+	return r.lastInsertID
+}
+
+// StatusFlags returns the server status flags sent with the result.
+func (r *Result) StatusFlags() uint16 {
+	return r.statusFlags
+}
+
+// Warnings returns the number of warnings raised by the statement.
+func (r *Result) Warnings() uint16 {
+	return r.warnings
+}
+
+// Info returns the human readable information string, e.g.
+// "Records: 3  Deleted: 0  Skipped: 0  Warnings: 0".
+func (r *Result) Info() string {
+	return r.info
+}
+
+// RowsMatched and RowsChanged return the counters of the info string of an
+// UPDATE, "Rows matched: 2  Changed: 1  Warnings: 0". They tell rows that
+// were found but already had the new values from updated rows, whatever
+// AffectedRows counts with CLIENT_FOUND_ROWS. The boolean is false when the
+// info string does not hold the counter, e.g. for another statement or with
+// a server not sending its messages in English (lc_messages).
+func (r *Result) RowsMatched() (uint64, bool) {
+	return r.infoCounter("Rows matched")
+}
+
+func (r *Result) RowsChanged() (uint64, bool) {
+	return r.infoCounter("Changed")
+}
+
+// Records, Duplicates, Deleted and Skipped return the counters of the info
+// string of a multiple row INSERT or an ALTER TABLE, "Records: 3
+// Duplicates: 1  Warnings: 0", and of LOAD DATA, "Records: 3  Deleted: 0
+// Skipped: 1  Warnings: 0". The boolean is false as for RowsMatched.
+func (r *Result) Records() (uint64, bool) {
+	return r.infoCounter("Records")
+}
+
+func (r *Result) Duplicates() (uint64, bool) {
+	return r.infoCounter("Duplicates")
+}
+
+func (r *Result) Deleted() (uint64, bool) {
+	return r.infoCounter("Deleted")
+}
+
+func (r *Result) Skipped() (uint64, bool) {
+	return r.infoCounter("Skipped")
+}
+
+// infoCounter returns the number following "label: " in the info string.
+func (r *Result) infoCounter(label string) (uint64, bool) {
+	info := r.info
+
+	for {
+		i := strings.Index(info, label+": ")
+
+		if i < 0 {
+			return 0, false
+		}
+
+		// "Changed" must not match the end of another label.
+		if i > 0 && info[i-1] != ' ' {
+			info = info[i+len(label):]
+			continue
+		}
+
+		digits := info[i+len(label)+2:]
+		n := 0
+
+		for n < len(digits) && digits[n] >= '0' && digits[n] <= '9' {
+			n++
+		}
+
+		count, err := strconv.ParseUint(digits[:n], 10, 64)
+
+		return count, err == nil
+	}
+}
+
+// add adds the affected rows and warnings of result, if any, to r, which
+// keeps the first last insert id and takes the status of result.
+func (r *Result) add(result *Result) {
+	if result == nil {
+		return
+	}
+
+	r.affectedRows += result.affectedRows
+	r.warnings += result.warnings
+	r.statusFlags = result.statusFlags
+
+	if r.lastInsertID == 0 {
+		r.lastInsertID = result.lastInsertID
+	}
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestResultInfoCounters(t *testing.T) {
+	update := &Result{info: "Rows matched: 2  Changed: 1  Warnings: 0"}
+
+	if n, ok := update.RowsMatched(); !ok || n != 2 {
+		t.Errorf("matched %d %v", n, ok)
+	}
+
+	if n, ok := update.RowsChanged(); !ok || n != 1 {
+		t.Errorf("changed %d %v", n, ok)
+	}
+
+	if _, ok := update.Records(); ok {
+		t.Error("UPDATE has no records counter")
+	}
+
+	load := &Result{info: "Records: 3  Deleted: 0  Skipped: 1  Warnings: 0"}
+
+	for _, test := range []struct {
+		counter  func() (uint64, bool)
+		expected uint64
+	}{
+		{load.Records, 3},
+		{load.Deleted, 0},
+		{load.Skipped, 1},
+	} {
+		if n, ok := test.counter(); !ok || n != test.expected {
+			t.Errorf("%s: got %d %v", load.info, n, ok)
+		}
+	}
+
+	if _, ok := load.Duplicates(); ok {
+		t.Error("LOAD DATA has no duplicates counter")
+	}
+
+	if _, ok := (&Result{}).RowsMatched(); ok {
+		t.Error("empty info string")
+	}
+}
+
+func TestParseOKPacketSessionTrack(t *testing.T) {
+	c := NewConnection(ConnectionParameter{DBName: "shop"})
+	c.clientFlags = CLIENT_SESSION_TRACK
+	c.database = "shop"
+
+	// USE archive: info left empty, then a schema change.
+	schema := append([]byte{SESSION_TRACK_SCHEMA, 8, 7}, "archive"...)
+	payload := append(okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT|SERVER_SESSION_STATE_CHANGED, 0, ""), 0, byte(len(schema)))
+	payload = append(payload, schema...)
+
+	if _, err := c.parseOKPacket(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.CurrentDatabase() != "archive" {
+		t.Errorf("current database %q", c.CurrentDatabase())
+	}
+
+	// An UPDATE without state change: the info is length encoded.
+	info := "Rows matched: 1  Changed: 1  Warnings: 0"
+	result, err := c.parseOKPacket(append(okPacket(1, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""), append([]byte{byte(len(info))}, info...)...))
+
+	if err != nil || result.Info() != info || c.CurrentDatabase() != "archive" {
+		t.Errorf("unexpected result %+v %v", result, err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, okPacket(2, 0, SERVER_STATUS_AUTOCOMMIT, 1, "Rows matched: 3  Changed: 2  Warnings: 1"))
+
+		server.readCommand()
+		server.writePacket(1, okPacket(1, 42, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		// The rows of a SELECT are discarded.
+		server.readCommand()
+		server.writeResultSet(1, [][]byte{columnPacket("a", MYSQL_TYPE_LONG, 0, 63)}, [][]byte{textRowPacket("1")}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	result, err := c.Exec("UPDATE t SET x = 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched, _ := result.RowsMatched(); result.AffectedRows() != 2 || result.Warnings() != 1 || matched != 3 {
+		t.Errorf("UPDATE: got %+v", result)
+	}
+
+	result, err = c.Exec("INSERT INTO t VALUES (1)")
+
+	if err != nil || result.LastInsertId() != 42 || result.AffectedRows() != 1 {
+		t.Errorf("INSERT: got %+v %v", result, err)
+	}
+
+	result, err = c.Exec("SELECT 1")
+
+	if err != nil || result != nil {
+		t.Errorf("SELECT: got %+v %v", result, err)
+	}
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy spaces out connection attempts exponentially: the delay
+// starts at BaseDelay and doubles after every attempt up to MaxDelay, then
+// Jitter (0 to 1) randomly shortens it by up to that fraction so that
+// clients failing together do not retry together.
+type RetryPolicy struct {
+	Attempts  int // attempts in total, at most 1 disables retrying
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    float64
+}
+
+// delay returns the time to wait after the given failed attempt, counted
+// from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+// isRetryableConnectError reports whether a failed connection attempt may
+// succeed when made again. Authentication and configuration errors will
+// not, and neither will an open breaker.
+func isRetryableConnectError(err error) bool {
+	if IsTransientNetwork(err) == true {
+		return true
+	}
+
+	var mysqlErr *MySQLError
+
+	if errors.As(err, &mysqlErr) == false {
+		return false
+	}
+
+	switch mysqlErr.Number {
+	case ER_CON_COUNT_ERROR, ER_SERVER_SHUTDOWN:
+		return true
+	}
+
+	return false
+}
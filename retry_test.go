@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for attempt, expected := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := p.delay(attempt + 1); got != expected*time.Millisecond {
+			t.Errorf("attempt %d: delay %v", attempt+1, got)
+		}
+	}
+
+	p.Jitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		if got := p.delay(2); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("jittered delay %v", got)
+		}
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	var tokens [][]byte
+	dials := 0
+
+	param := ConnectionParameter{
+		Network:      "tcp",
+		Username:     "u",
+		Password:     NewSecret("secret"),
+		ConnectRetry: RetryPolicy{Attempts: 4, BaseDelay: time.Millisecond},
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			dials++
+
+			if dials == 1 {
+				return nil, syscall.ECONNREFUSED
+			}
+
+			client, serverConn := net.Pipe()
+			server := newFakeServer(t, serverConn)
+
+			go func() {
+				server.writePacket(0, initPacket())
+
+				_, payload := server.readPacket()
+
+				// username "u\x00", then the length of the token
+				token := payload[32+2:]
+				tokens = append(tokens, append([]byte{}, token[1:1+token[0]]...))
+
+				if dials == 2 {
+					server.writePacket(2, append([]byte{ERR_PACKET, 0x10, 0x04, '#', '0', '8', '0', '0', '4'}, "Too many connections"...))
+					return
+				}
+
+				server.writePacket(2, append([]byte{ERR_PACKET, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied for user 'u'"...))
+			}()
+
+			return client, nil
+		},
+	}
+
+	err := NewConnection(param).Open()
+
+	// Too many connections is retried, access denied is not.
+	if e, ok := err.(*MySQLError); ok == false || e.Number != 1045 || dials != 3 {
+		t.Fatalf("unexpected error %v after %d dials", err, dials)
+	}
+
+	if len(tokens) != 2 || len(tokens[0]) != 20 || bytes.Equal(tokens[0], tokens[1]) == false {
+		t.Errorf("unexpected auth tokens %x", tokens)
+	}
+
+	if string(param.Password.b) != "secret" {
+		t.Errorf("password of the caller zeroed")
+	}
+}
@@ -0,0 +1,784 @@
+package mysql
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+var (
+	ErrScanCount = errors.New("Wrong number of scan destinations")
+	ErrScanType  = errors.New("Unsupported scan destination type")
+	ErrScanNull  = errors.New("NULL scanned into a non-nullable type")
+)
+
+// Column describes a column of a result set as sent in the
+// ColumnDefinition41 packet.
+type Column struct {
+	Catalog      string
+	Schema       string
+	Table        string
+	OrgTable     string
+	Name         string
+	OrgName      string
+	CharacterSet uint16
+	ColumnLength uint32
+	Type         FieldType
+	Flags        uint16
+	Decimals     uint8
+
+	// ExtendedType and ExtendedFormat are sent by MariaDB once
+	// MARIADB_CLIENT_EXTENDED_METADATA is negotiated for types stored as
+	// another one, e.g. "uuid", "inet6" or "point" and the "json" format
+	// of a LONGTEXT.
+	ExtendedType   string
+	ExtendedFormat string
+
+	// Default is the default value of the column, only sent in response
+	// to COM_FIELD_LIST (see FieldList). It is nil for a NULL default.
+	Default []byte
+}
+
+// parse decodes a ColumnDefinition41 packet with the optional parts of
+// options, see protocol.ParseColumnDefinition.
+func (col *Column) parse(payload []byte, options protocol.ColumnOptions) error {
+	def, err := protocol.ParseColumnDefinition(payload, options)
+
+	if err != nil {
+		return err
+	}
+
+	*col = Column{
+		Catalog:        def.Catalog,
+		Schema:         def.Schema,
+		Table:          def.Table,
+		OrgTable:       def.OrgTable,
+		Name:           def.Name,
+		OrgName:        def.OrgName,
+		CharacterSet:   def.CharacterSet,
+		ColumnLength:   def.ColumnLength,
+		Type:           FieldType(def.Type),
+		Flags:          def.Flags,
+		Decimals:       def.Decimals,
+		ExtendedType:   def.ExtendedType,
+		ExtendedFormat: def.ExtendedFormat,
+		Default:        def.Default,
+	}
+
+	return nil
+}
+
+// Rows is the streamed result set of a query. Rows must be read to the end
+// or closed before the connection can be used again.
+type Rows struct {
+	c       *Connection
+	columns []Column
+	values  [][]byte
+	result  *Result
+
+	done     bool // the current result set has been read to the end
+	released bool // the connection has been handed back
+	err      error
+
+	stream *rowStream // the current row when it is streamed
+	column int        // next column returned by ColumnReader
+
+	binary bool   // rows of a prepared statement, see parseBinaryRow
+	stmt   *Stmt  // prepared statement whose metadata cache the rows use
+	buf    []byte // text form of the binary values of the current row
+	ends   []int  // end of each value in buf
+
+	query string    // statement recorded in StatementStats once released
+	start time.Time // when the statement was sent
+	count int64     // rows read
+}
+
+// readResultSetHeader reads the column count and column definitions of the
+// next result set.
+func (r *Rows) readResultSetHeader() error {
+	columnCount, metadata, result, err := r.c.readColumnCount()
+
+	// Only the first result set of a statement is cached.
+	stmt := r.stmt
+	r.stmt = nil
+
+	if err != nil {
+		return err
+	}
+
+	if columnCount == 0 {
+		// The statement did not produce a result set.
+		r.columns = nil
+		r.result = result
+		r.done = true
+		return nil
+	}
+
+	if metadata == false {
+		// The columns are those of the previous execution of the
+		// statement; the EOF packet ending them is still sent.
+		if stmt == nil || uint64(len(stmt.metadata)) != columnCount {
+			return ErrMalformedPacket
+		}
+
+		r.columns = stmt.metadata
+
+		_, err = r.c.readColumns(0)
+	} else {
+		r.columns, err = r.c.readColumns(columnCount)
+
+		if stmt != nil && err == nil {
+			stmt.metadata = r.columns
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.result = nil
+	r.done = false
+
+	return nil
+}
+
+// Columns returns the column definitions of the result set.
+func (r *Rows) Columns() []Column {
+	return r.columns
+}
+
+// Result returns the OK packet of a statement that produced no result set,
+// e.g. an INSERT, or nil.
+func (r *Rows) Result() *Result {
+	return r.result
+}
+
+// Next reads the next row, returning false at the end of the result set or
+// on error.
+func (r *Rows) Next() bool {
+	defer r.releaseOnPanic()
+
+	if r.done == true || r.err != nil {
+		return false
+	}
+
+	if r.stream != nil && r.endStream() == false {
+		return false
+	}
+
+	r.c.interruptible = true
+
+	payload, err := r.c.readPacket()
+
+	if err == errInterrupted && r.c.watch != nil {
+		err = r.c.abort(r, false)
+		r.done = true
+	}
+
+	if err != nil {
+		r.err = err
+		r.release()
+		return false
+	}
+
+	return r.handleRow(payload)
+}
+
+// handleRow handles the payload of the packet following the previous row.
+func (r *Rows) handleRow(payload []byte) bool {
+	if isEOFPacket(payload) == true {
+		r.done = true
+
+		_, r.err = r.c.parseEOFPacket(payload)
+
+		if r.err != nil || r.c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			r.release()
+		}
+
+		return false
+	}
+
+	if len(payload) > 0 && payload[0] == ERR_PACKET {
+		r.done = true
+		r.err = parseErrPacket(payload)
+		r.release()
+		return false
+	}
+
+	if r.binary == true {
+		r.err = r.parseBinaryRow(payload)
+	} else {
+		r.err = r.parseTextRow(payload)
+	}
+
+	if r.err != nil {
+		// The rest of the result set is left unread.
+		r.c.broken = true
+		r.release()
+		return false
+	}
+
+	r.column = 0
+	r.count++
+
+	return true
+}
+
+// parseTextRow decodes a text protocol row where every value is a length
+// encoded string and NULL is sent as 0xfb.
+// Reference:
+// https://mariadb.com/kb/en/mariadb/resultset-row/#text-resultset-row
+func (r *Rows) parseTextRow(payload []byte) error {
+	if r.values == nil {
+		r.values = make([][]byte, len(r.columns))
+	}
+
+	pos := 0
+
+	for i := range r.values {
+		value, isNull, n := unpackLenEncString(payload[pos:])
+
+		if n == 0 {
+			return ErrMalformedPacket
+		}
+
+		if r.c.param.MaxColumnSize > 0 && len(value) > r.c.param.MaxColumnSize {
+			return &ResponseTooLargeError{
+				Column: r.columns[i].Name,
+				Size:   uint64(len(value)),
+				Limit:  r.c.param.MaxColumnSize,
+			}
+		}
+
+		if isNull == true {
+			r.values[i] = nil
+		} else if value == nil {
+			r.values[i] = []byte{}
+		} else {
+			r.values[i] = value
+		}
+
+		pos += n
+	}
+
+	return nil
+}
+
+// parseBinaryRow decodes a binary protocol row: a 0x00 header, a NULL
+// bitmap, then the values of the non-NULL columns in the encoding of their
+// type. Numbers and temporal values are converted to the text the text
+// protocol would have sent, so that Values, Scan and everything built on
+// them work the same for both protocols.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html
+func (r *Rows) parseBinaryRow(payload []byte) error {
+	if r.values == nil {
+		r.values = make([][]byte, len(r.columns))
+	}
+
+	bitmapSize := protocol.NullBitmapSize(len(r.columns), protocol.BINARY_ROW_NULL_BITMAP_OFFSET)
+
+	if len(payload) < 1+bitmapSize || payload[0] != OK_PACKET {
+		return ErrMalformedPacket
+	}
+
+	bitmap := payload[1 : 1+bitmapSize]
+	pos := 1 + bitmapSize
+
+	// The values are first appended to r.buf, which may move while
+	// growing, and sliced once the row is complete.
+	if len(r.ends) != len(r.columns) {
+		r.ends = make([]int, len(r.columns))
+	}
+
+	ends := r.ends
+	r.buf = r.buf[:0]
+
+	for i := range r.columns {
+		col := &r.columns[i]
+
+		if protocol.IsNull(bitmap, i, protocol.BINARY_ROW_NULL_BITMAP_OFFSET) == true {
+			r.values[i] = nil
+			ends[i] = -1
+			continue
+		}
+
+		switch col.Type {
+		case MYSQL_TYPE_DECIMAL, MYSQL_TYPE_NEWDECIMAL, MYSQL_TYPE_VARCHAR, MYSQL_TYPE_BIT,
+			MYSQL_TYPE_JSON, MYSQL_TYPE_ENUM, MYSQL_TYPE_SET, MYSQL_TYPE_TINY_BLOB,
+			MYSQL_TYPE_MEDIUM_BLOB, MYSQL_TYPE_LONG_BLOB, MYSQL_TYPE_BLOB,
+			MYSQL_TYPE_VAR_STRING, MYSQL_TYPE_STRING, MYSQL_TYPE_GEOMETRY:
+			// Sent as in the text protocol, without conversion.
+			value, _, n := unpackLenEncString(payload[pos:])
+
+			if n == 0 {
+				return ErrMalformedPacket
+			}
+
+			if r.c.param.MaxColumnSize > 0 && len(value) > r.c.param.MaxColumnSize {
+				return &ResponseTooLargeError{
+					Column: col.Name,
+					Size:   uint64(len(value)),
+					Limit:  r.c.param.MaxColumnSize,
+				}
+			}
+
+			if value == nil {
+				value = []byte{}
+			}
+
+			r.values[i] = value
+			ends[i] = -2
+			pos += n
+			continue
+		}
+
+		n, err := r.appendBinaryValue(col, payload[pos:])
+
+		if err != nil {
+			return err
+		}
+
+		ends[i] = len(r.buf)
+		pos += n
+	}
+
+	start := 0
+
+	for i, end := range ends {
+		if end >= 0 {
+			r.values[i] = r.buf[start:end:end]
+			start = end
+		}
+	}
+
+	return nil
+}
+
+// appendBinaryValue appends to r.buf the text form of the binary value of
+// col at the start of b and returns the number of bytes it took.
+func (r *Rows) appendBinaryValue(col *Column, b []byte) (int, error) {
+	unsigned := col.Flags&UNSIGNED_FLAG != 0
+
+	switch col.Type {
+	case MYSQL_TYPE_TINY:
+		if len(b) < 1 {
+			return 0, ErrMalformedPacket
+		}
+
+		if unsigned == true {
+			r.buf = strconv.AppendUint(r.buf, uint64(b[0]), 10)
+		} else {
+			r.buf = strconv.AppendInt(r.buf, int64(int8(b[0])), 10)
+		}
+
+		return 1, nil
+	case MYSQL_TYPE_SHORT, MYSQL_TYPE_YEAR:
+		if len(b) < 2 {
+			return 0, ErrMalformedPacket
+		}
+
+		n := binary.LittleEndian.Uint16(b)
+
+		if unsigned == true || col.Type == MYSQL_TYPE_YEAR {
+			r.buf = strconv.AppendUint(r.buf, uint64(n), 10)
+		} else {
+			r.buf = strconv.AppendInt(r.buf, int64(int16(n)), 10)
+		}
+
+		return 2, nil
+	case MYSQL_TYPE_INT24, MYSQL_TYPE_LONG:
+		if len(b) < 4 {
+			return 0, ErrMalformedPacket
+		}
+
+		n := binary.LittleEndian.Uint32(b)
+
+		if unsigned == true {
+			r.buf = strconv.AppendUint(r.buf, uint64(n), 10)
+		} else {
+			r.buf = strconv.AppendInt(r.buf, int64(int32(n)), 10)
+		}
+
+		return 4, nil
+	case MYSQL_TYPE_LONGLONG:
+		if len(b) < 8 {
+			return 0, ErrMalformedPacket
+		}
+
+		n := binary.LittleEndian.Uint64(b)
+
+		if unsigned == true {
+			r.buf = strconv.AppendUint(r.buf, n, 10)
+		} else {
+			r.buf = strconv.AppendInt(r.buf, int64(n), 10)
+		}
+
+		return 8, nil
+	case MYSQL_TYPE_FLOAT:
+		if len(b) < 4 {
+			return 0, ErrMalformedPacket
+		}
+
+		r.buf = strconv.AppendFloat(r.buf, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32)
+
+		return 4, nil
+	case MYSQL_TYPE_DOUBLE:
+		if len(b) < 8 {
+			return 0, ErrMalformedPacket
+		}
+
+		r.buf = strconv.AppendFloat(r.buf, math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64)
+
+		return 8, nil
+	case MYSQL_TYPE_DATE, MYSQL_TYPE_NEWDATE, MYSQL_TYPE_DATETIME, MYSQL_TYPE_TIMESTAMP:
+		return r.appendBinaryDateTime(col, b)
+	case MYSQL_TYPE_TIME:
+		return r.appendBinaryTime(col, b)
+	}
+
+	return 0, ErrUnexpectedPacket
+}
+
+// appendBinaryDateTime appends a DATE, DATETIME or TIMESTAMP value as
+// "2006-01-02 15:04:05.999999", with as many fractional digits as the
+// column has. The fields are copied as sent, zero dates included.
+func (r *Rows) appendBinaryDateTime(col *Column, b []byte) (int, error) {
+	// Only the length is checked, the fields are not validated.
+	_, n, err := protocol.ParseBinaryDateTime(b, time.UTC)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var fields [7]int
+
+	if n > 1 {
+		fields[0] = int(binary.LittleEndian.Uint16(b[1:]))
+		fields[1], fields[2] = int(b[3]), int(b[4])
+	}
+
+	if n > 5 {
+		fields[3], fields[4], fields[5] = int(b[5]), int(b[6]), int(b[7])
+	}
+
+	if n > 8 {
+		fields[6] = int(binary.LittleEndian.Uint32(b[8:]))
+	}
+
+	r.buf = appendPadded(r.buf, fields[0], 4)
+	r.buf = append(r.buf, '-')
+	r.buf = appendPadded(r.buf, fields[1], 2)
+	r.buf = append(r.buf, '-')
+	r.buf = appendPadded(r.buf, fields[2], 2)
+
+	if col.Type == MYSQL_TYPE_DATE || col.Type == MYSQL_TYPE_NEWDATE {
+		return n, nil
+	}
+
+	r.buf = append(r.buf, ' ')
+	r.buf = appendPadded(r.buf, fields[3], 2)
+	r.buf = append(r.buf, ':')
+	r.buf = appendPadded(r.buf, fields[4], 2)
+	r.buf = append(r.buf, ':')
+	r.buf = appendPadded(r.buf, fields[5], 2)
+	r.buf = appendFraction(r.buf, fields[6], col.Decimals)
+
+	return n, nil
+}
+
+// appendBinaryTime appends a TIME value as "-838:59:59.999999", the hours
+// including the days.
+func (r *Rows) appendBinaryTime(col *Column, b []byte) (int, error) {
+	_, n, err := protocol.ParseBinaryTime(b)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var negative bool
+	var hours, minutes, seconds, microseconds int
+
+	if n > 1 {
+		negative = b[1] == 1
+		hours = int(binary.LittleEndian.Uint32(b[2:]))*24 + int(b[6])
+		minutes, seconds = int(b[7]), int(b[8])
+	}
+
+	if n > 9 {
+		microseconds = int(binary.LittleEndian.Uint32(b[9:]))
+	}
+
+	if negative == true {
+		r.buf = append(r.buf, '-')
+	}
+
+	r.buf = appendPadded(r.buf, hours, 2)
+	r.buf = append(r.buf, ':')
+	r.buf = appendPadded(r.buf, minutes, 2)
+	r.buf = append(r.buf, ':')
+	r.buf = appendPadded(r.buf, seconds, 2)
+	r.buf = appendFraction(r.buf, microseconds, col.Decimals)
+
+	return n, nil
+}
+
+// appendPadded appends n with at least width digits.
+func appendPadded(b []byte, n int, width int) []byte {
+	for i, limit := 1, 10; i < width; i, limit = i+1, limit*10 {
+		if n < limit {
+			b = append(b, '0')
+		}
+	}
+
+	return strconv.AppendInt(b, int64(n), 10)
+}
+
+// appendFraction appends the fractional seconds with the decimals of the
+// column, or with 6 digits when they are not fixed and not zero.
+func appendFraction(b []byte, microseconds int, decimals uint8) []byte {
+	if decimals > 6 {
+		if microseconds == 0 {
+			return b
+		}
+
+		decimals = 6
+	}
+
+	if decimals == 0 {
+		return b
+	}
+
+	digits := appendPadded(nil, microseconds, 6)
+
+	b = append(b, '.')
+
+	return append(b, digits[:decimals]...)
+}
+
+// Values returns the raw values of the current row, nil for NULL. The
+// slices are only valid until the next call to Next. A streamed row has no
+// values.
+func (r *Rows) Values() [][]byte {
+	if r.stream != nil {
+		return nil
+	}
+
+	return r.values
+}
+
+// Scan converts the values of the current row into the variables pointed at
+// by dest, which may be *int64, *uint64, *float64, *[]byte or *string. NULL
+// is only accepted by *[]byte, which is set to nil.
+//
+// Scanning does not allocate: a *[]byte keeps its backing array when it is
+// large enough, and a *string is only reallocated when the value differs
+// from the current one.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.stream != nil {
+		return ErrRowStreamed
+	}
+
+	if len(dest) != len(r.values) {
+		return ErrScanCount
+	}
+
+	for i, value := range r.values {
+		err := scanValue(dest[i], value)
+
+		if err != nil {
+			return fmt.Errorf("Scan column %s: %w", r.columns[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func scanValue(dest interface{}, value []byte) error {
+	var err error
+
+	if d, ok := dest.(*[]byte); ok == true {
+		if value == nil {
+			*d = nil
+		} else {
+			*d = append((*d)[:0], value...)
+		}
+
+		return nil
+	}
+
+	if value == nil {
+		return ErrScanNull
+	}
+
+	switch d := dest.(type) {
+	case *string:
+		// Comparing does not allocate, converting does.
+		if *d != string(value) {
+			*d = string(value)
+		}
+	case *int64:
+		*d, err = strconv.ParseInt(string(value), 10, 64)
+	case *uint64:
+		*d, err = strconv.ParseUint(string(value), 10, 64)
+	case *float64:
+		*d, err = strconv.ParseFloat(string(value), 64)
+	default:
+		return ErrScanType
+	}
+
+	return err
+}
+
+// Err returns the error, if any, that ended the iteration.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close discards the unread rows, including those of any further result
+// sets, and hands the connection back. The rows are skipped without being
+// decoded or copied. With KillOnClose the statement is first stopped with
+// KILL QUERY, so only the rows already sent are read; the statement may
+// also have ended meanwhile, which is harmless.
+func (r *Rows) Close() error {
+	defer r.releaseOnPanic()
+
+	if r.released == true {
+		return r.err
+	}
+
+	killed := false
+
+	if r.done == false && r.err == nil && r.c.param.KillOnClose == true && r.c.param.KillHelper != nil {
+		killed = r.c.kill(r.c.cancelGracePeriod()) == nil
+	}
+
+	for r.err == nil {
+		for r.skip() == true {
+		}
+
+		if r.err != nil || r.released == true {
+			break
+		}
+
+		// SERVER_MORE_RESULTS_EXISTS was set by the last EOF/OK packet.
+		r.err = r.readResultSetHeader()
+
+		if r.err == nil && r.done == true && r.c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+			break
+		}
+	}
+
+	// The killed statement ends with ER_QUERY_INTERRUPTED.
+	if mysqlErr, ok := r.err.(*MySQLError); ok && killed == true && mysqlErr.Number == ER_QUERY_INTERRUPTED {
+		r.err = nil
+	}
+
+	r.release()
+
+	return r.err
+}
+
+// Abandon gives up the rest of the result set without reading it and
+// marks the connection broken, so that it is closed rather than used
+// again: for a large result, closing the connection and opening another
+// one is faster than draining gigabytes of unwanted rows. The Connection
+// must be closed afterwards; the database/sql driver has the pool replace
+// it, see AbandonOnClose.
+func (r *Rows) Abandon() {
+	if r.released == true {
+		return
+	}
+
+	r.c.broken = true
+	r.done = true
+	r.stream = nil
+	r.release()
+}
+
+// skip is Next for rows nobody reads: the packets are discarded as they
+// arrive, only the first byte of each is looked at to find the end of the
+// result set.
+func (r *Rows) skip() bool {
+	if r.done == true || r.err != nil {
+		return false
+	}
+
+	if r.stream != nil && r.endStream() == false {
+		return false
+	}
+
+	// The first packet of a row split over several packets follows the
+	// previous row, the next ones continue it.
+	continued := false
+
+	for {
+		r.c.interruptible = continued == false
+
+		packetHeader, err := r.c.readPacketHeader()
+
+		if err == errInterrupted && r.c.watch != nil {
+			err = r.c.abort(r, false)
+			r.done = true
+		} else if err == nil && packetHeader.Seq != r.c.sequence {
+			r.c.broken = true
+			err = ErrUnexpectedSequence
+		}
+
+		if err != nil {
+			r.err = err
+			r.release()
+			return false
+		}
+
+		r.c.sequence++
+
+		if continued == false && packetHeader.Len < MAX_PACKET_SIZE-1 && r.isRowEnd(packetHeader.Len) == true {
+			payload, err := r.c.appendPayload(r.c.readBuf[:0], packetHeader.Len)
+
+			if err != nil {
+				r.err = err
+				r.release()
+				return false
+			}
+
+			r.c.readBuf = payload
+
+			return r.handleRow(payload)
+		}
+
+		_, err = r.c.reader.Discard(int(packetHeader.Len))
+
+		if err != nil {
+			r.c.broken = true
+			r.err = err
+			r.release()
+			return false
+		}
+
+		continued = packetHeader.Len == MAX_PACKET_SIZE-1
+
+		if continued == false {
+			return true
+		}
+	}
+}
+
+// release unlocks the connection once.
+func (r *Rows) release() {
+	if r.released == false {
+		r.released = true
+
+		if r.query != "" {
+			r.c.recordStatement(r.query, r.start, r.count, r.err)
+		}
+
+		r.c.unwatchContext()
+		r.c.mutex.Unlock()
+	}
+}
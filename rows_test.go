@@ -0,0 +1,480 @@
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+func TestQuery(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		_, payload := server.readPacket()
+
+		if string(payload[1:]) != "SELECT id, name FROM t" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		server.writeResultSet(1, [][]byte{
+			columnPacket("id", MYSQL_TYPE_LONG, NOT_NULL_FLAG|PRI_KEY_FLAG, 63),
+			columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 33),
+		}, [][]byte{
+			textRowPacket("1", "Taipei"),
+			textRowPacket("2", nil),
+			textRowPacket("3", ""),
+		}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	rows, err := c.Query("SELECT id, name FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := rows.Columns()
+
+	if len(columns) != 2 || columns[0].Name != "id" || columns[0].Type != MYSQL_TYPE_LONG ||
+		columns[0].Flags != NOT_NULL_FLAG|PRI_KEY_FLAG || columns[1].CharacterSet != 33 || columns[1].Table != "t" {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	var got [][]string
+
+	for rows.Next() {
+		var row []string
+
+		for _, value := range rows.Values() {
+			if value == nil {
+				row = append(row, "NULL")
+			} else {
+				row = append(row, "'"+string(value)+"'")
+			}
+		}
+
+		got = append(got, row)
+	}
+
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[['1' 'Taipei'] ['2' NULL] ['3' '']]"
+
+	if s := fmt.Sprint(got); s != expected {
+		t.Errorf("got %s want %s", s, expected)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readPacket()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.x' doesn't exist"...))
+	}()
+
+	_, err := c.Query("SELECT * FROM x")
+
+	mysqlErr, ok := err.(*MySQLError)
+
+	if ok == false || mysqlErr.Number != 1146 || mysqlErr.SQLState != "42S02" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// The connection was handed back.
+	c.mutex.Lock()
+	c.mutex.Unlock()
+}
+
+func TestRowsCloseDrainsMoreResults(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+	done := make(chan bool)
+
+	go func() {
+		server.readPacket()
+
+		columns := [][]byte{columnPacket("a", MYSQL_TYPE_LONGLONG, 0, 63)}
+
+		// SELECT 1 UNION SELECT 2; UPDATE ...; SELECT 3
+		seq := server.writeResultSet(1, columns, [][]byte{textRowPacket("1"), textRowPacket("2")}, SERVER_MORE_RESULTS_EXISTS)
+		server.writePacket(seq, okPacket(5, 0, SERVER_MORE_RESULTS_EXISTS, 0, "Rows matched: 5  Changed: 5  Warnings: 0"))
+		seq++
+		server.writeResultSet(seq, columns, [][]byte{textRowPacket("3")}, 0)
+
+		// The next command starts with a fresh sequence.
+		seq, payload := server.readPacket()
+
+		if seq != 0 || payload[0] != COM_PING {
+			t.Errorf("unexpected command %d %q", seq, payload)
+		}
+
+		server.writePacket(1, okPacket(0, 0, 0, 0, ""))
+		close(done)
+	}()
+
+	rows, err := c.Query("SELECT 1 UNION SELECT 2; UPDATE t SET a = 1; SELECT 3")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false || string(rows.Values()[0]) != "1" {
+		t.Fatalf("unexpected first row")
+	}
+
+	// Close with one row and two more results unread.
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-done
+}
+
+func TestIsEOFPacket(t *testing.T) {
+	if isEOFPacket(eofPacket(0)) == false {
+		t.Errorf("EOF packet not detected")
+	}
+
+	// A row whose first value has a 0xfe (8 byte) length prefix.
+	row := append([]byte{0xfe, 1, 0, 0, 0, 0, 0, 0, 0}, 'x')
+
+	if isEOFPacket(row) == true {
+		t.Errorf("row mistaken for an EOF packet")
+	}
+}
+
+func TestColumnParseLite(t *testing.T) {
+	payload := columnPacket("name", MYSQL_TYPE_VAR_STRING, NOT_NULL_FLAG, 45)
+
+	var full, lite Column
+
+	if err := full.parse(payload, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lite.parse(payload, protocol.COLUMN_LITE); err != nil {
+		t.Fatal(err)
+	}
+
+	if lite.Name != "name" || lite.Schema != "" || lite.Table != "" || lite.Type != full.Type ||
+		lite.Flags != full.Flags || lite.CharacterSet != full.CharacterSet {
+		t.Errorf("unexpected lite column %+v", lite)
+	}
+}
+
+func BenchmarkColumnParse(b *testing.B) {
+	payload := columnPacket("name", MYSQL_TYPE_VAR_STRING, NOT_NULL_FLAG, 45)
+
+	for _, lite := range []bool{false, true} {
+		name := "Full"
+
+		if lite == true {
+			name = "Lite"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var col Column
+			var options protocol.ColumnOptions
+
+			if lite == true {
+				options = protocol.COLUMN_LITE
+			}
+
+			for i := 0; i < b.N; i++ {
+				col.parse(payload, options)
+			}
+		})
+	}
+}
+
+func TestRowsReadTimeout(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+	c.readTimeout = 50 * time.Millisecond
+
+	go func() {
+		server.readPacket()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("a", MYSQL_TYPE_LONGLONG, 0, 63))
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, textRowPacket("1"))
+		// The server dies silently in the middle of the result set.
+	}()
+
+	rows, err := c.Query("SELECT a FROM big")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rows.Next() {
+	}
+
+	if netErr, ok := rows.Err().(net.Error); ok == false || netErr.Timeout() == false {
+		t.Errorf("expected a timeout, got %v", rows.Err())
+	}
+}
+
+func TestRowsScan(t *testing.T) {
+	rows := &Rows{
+		columns: []Column{{Name: "id"}, {Name: "price"}, {Name: "name"}, {Name: "data"}},
+		values:  [][]byte{[]byte("-42"), []byte("1.5"), []byte("Taipei"), nil},
+	}
+
+	var id int64
+	var price float64
+	var name string
+	data := []byte("stale")
+
+	if err := rows.Scan(&id, &price, &name, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	if id != -42 || price != 1.5 || name != "Taipei" || data != nil {
+		t.Errorf("unexpected values %v %v %q %q", id, price, name, data)
+	}
+
+	if err := rows.Scan(&id, &price, &name, &id); errors.Is(err, ErrScanNull) == false {
+		t.Errorf("expected ErrScanNull, got %v", err)
+	}
+
+	if err := rows.Scan(&id, &price, new(int), &data); errors.Is(err, ErrScanType) == false {
+		t.Errorf("expected ErrScanType, got %v", err)
+	}
+
+	if err := rows.Scan(&id, &price, &id, &data); err == nil {
+		t.Error("expected a syntax error scanning a string into an int64")
+	}
+
+	if err := rows.Scan(&id); err != ErrScanCount {
+		t.Errorf("expected ErrScanCount, got %v", err)
+	}
+}
+
+// repeatReader repeats its data forever.
+type repeatReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data[r.pos:])
+	r.pos = (r.pos + n) % len(r.data)
+	return n, nil
+}
+
+// newRowStream returns rows reading the same text row over and over.
+func newRowStream(columns []Column, values ...interface{}) *Rows {
+	var data []byte
+
+	row := textRowPacket(values...)
+
+	// 256 packets, so the sequence number wraps around with the data.
+	for i := 0; i < 256; i++ {
+		data = append(data, byte(len(row)), byte(len(row)>>8), byte(len(row)>>16), byte(i))
+		data = append(data, row...)
+	}
+
+	c := &Connection{reader: bufio.NewReader(&repeatReader{data: data}), mutex: new(sync.Mutex)}
+
+	return &Rows{c: c, columns: columns}
+}
+
+func TestRowsScanAllocs(t *testing.T) {
+	rows := newRowStream([]Column{{Name: "id"}, {Name: "price"}, {Name: "data"}}, "12345", "99.95", "some bytes")
+
+	var id int64
+	var price float64
+	var data []byte
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		if rows.Next() == false || rows.Scan(&id, &price, &data) != nil {
+			t.Fatal(rows.Err())
+		}
+	})
+
+	if allocs != 0 {
+		t.Errorf("got %v allocations per row", allocs)
+	}
+}
+
+func BenchmarkRowsScan(b *testing.B) {
+	rows := newRowStream([]Column{{Name: "id"}, {Name: "price"}, {Name: "name"}, {Name: "data"}},
+		"12345", "99.95", "Taipei", "some bytes")
+
+	var id int64
+	var price float64
+	var name string
+	var data []byte
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if rows.Next() == false {
+			b.Fatal(rows.Err())
+		}
+
+		if err := rows.Scan(&id, &price, &name, &data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRowsSizeLimits(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{MaxPayloadSize: 64, MaxColumnSize: 8})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readPacket()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45))
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, textRowPacket("12345678"))
+		server.writePacket(5, textRowPacket("123456789"))
+	}()
+
+	rows, err := c.Query("SELECT name FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false || rows.Next() == true {
+		t.Fatal("expected one row within the limit")
+	}
+
+	if e, ok := rows.Err().(*ResponseTooLargeError); !ok || e.Column != "name" || e.Size != 9 || e.Limit != 8 {
+		t.Errorf("unexpected error %v", rows.Err())
+	}
+
+	if c.broken == false {
+		t.Error("the connection should be unusable")
+	}
+
+	// A payload over the limit is refused before it is allocated.
+	c, serverConn = newPipeConnection(ConnectionParameter{MaxPayloadSize: 64})
+	server = newFakeServer(t, serverConn)
+
+	go func() {
+		server.readPacket()
+		serverConn.Write([]byte{0xff, 0xff, 0xff, 1})
+	}()
+
+	_, err = c.Query("SELECT evil FROM proxy")
+
+	if e, ok := err.(*ResponseTooLargeError); !ok || e.Size != MAX_PACKET_SIZE-1 || e.Limit != 64 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRowsCloseSkip(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{MaxColumnSize: 16})
+	server := newFakeServer(t, serverConn)
+
+	// A row split over two packets whose second one starts like an ERR
+	// packet.
+	blob := bytes.Repeat([]byte{ERR_PACKET}, MAX_PACKET_SIZE)
+	bigRow := append([]byte{0xfe, byte(len(blob)), byte(len(blob) >> 8), byte(len(blob) >> 16), byte(len(blob) >> 24), 0, 0, 0, 0}, blob...)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("data", MYSQL_TYPE_BLOB, BLOB_FLAG|BINARY_FLAG, 63))
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, textRowPacket("first"))
+		server.writePacket(5, bigRow[:MAX_PACKET_SIZE-1])
+		server.writePacket(6, bigRow[MAX_PACKET_SIZE-1:])
+		server.writePacket(7, textRowPacket("last"))
+		server.writePacket(8, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+	}()
+
+	rows, err := c.Query("SELECT data FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false {
+		t.Fatal(rows.Err())
+	}
+
+	// The big value exceeds MaxColumnSize but is never decoded.
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Query("DO 1"); err != nil || c.broken == true {
+		t.Fatalf("connection not usable: %v", err)
+	}
+}
+
+func TestRowsCloseKill(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{KillOnClose: true})
+	c.ConnectionID = 7
+	server := newFakeServer(t, serverConn)
+	killed := make(chan struct{})
+
+	c.param.KillHelper = func(ctx context.Context) (*Connection, error) {
+		helper, helperConn := newPipeConnection(ConnectionParameter{})
+		helperServer := newFakeServer(t, helperConn)
+
+		go func() {
+			if payload := helperServer.readCommand(); string(payload[1:]) != "KILL QUERY 7" {
+				t.Errorf("unexpected query %q", payload)
+			}
+
+			helperServer.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+			close(killed)
+		}()
+
+		return helper, nil
+	}
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, []byte{1})
+		server.writePacket(2, columnPacket("n", MYSQL_TYPE_LONGLONG, 0, 63))
+		server.writePacket(3, eofPacket(0))
+		server.writePacket(4, textRowPacket("1"))
+
+		// The rest of the rows would take forever.
+		<-killed
+		server.writePacket(5, textRowPacket("2"))
+		server.writePacket(6, append([]byte{ERR_PACKET, 0x25, 0x05, '#', '7', '0', '1', '0', '0'}, "Query execution was interrupted"...))
+	}()
+
+	rows, err := c.Query("SELECT n FROM huge")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false {
+		t.Fatal(rows.Err())
+	}
+
+	if err = rows.Close(); err != nil || c.broken == true {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
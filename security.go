@@ -0,0 +1,180 @@
+package mysql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SecurityError is returned by Open when the connection does not meet
+// StrictSecurity or MinServerVersion.
+type SecurityError struct {
+	Reason string
+}
+
+func (e *SecurityError) Error() string {
+	return "Connection refused by the security settings: " + e.Reason
+}
+
+// checkParameters refuses, before dialing, the parameters StrictSecurity
+// does not allow.
+func (c *Connection) checkParameters() error {
+	if c.param.StrictSecurity == false {
+		return nil
+	}
+
+	if c.param.AllowLocalInfile == true {
+		return &SecurityError{Reason: "LOCAL INFILE is allowed"}
+	}
+
+	// Like require_secure_transport, unix sockets and named pipes are
+	// trusted.
+	if c.param.TLSConfig == nil && c.param.Network != "unix" && c.param.Network != "pipe" {
+		return &SecurityError{Reason: "TLS is not configured"}
+	}
+
+	if c.param.TLSConfig != nil && c.param.TLSConfig.InsecureSkipVerify == true {
+		return &SecurityError{Reason: "TLS certificates are not verified"}
+	}
+
+	return nil
+}
+
+// checkServer refuses the servers MinServerVersion and StrictSecurity do
+// not allow, once the initial handshake packet has been read.
+func (c *Connection) checkServer() error {
+	if c.param.MinServerVersion != "" && compareVersions(c.ServerVersion, c.param.MinServerVersion) < 0 {
+		return &SecurityError{Reason: "server version " + c.ServerVersion + " is older than " + c.param.MinServerVersion}
+	}
+
+	if c.param.StrictSecurity == true {
+		switch c.AuthenticationPluginName {
+		case "mysql_old_password", "mysql_clear_password":
+			return &SecurityError{Reason: "the server asks for " + c.AuthenticationPluginName}
+		}
+	}
+
+	return nil
+}
+
+// compareVersions compares two server versions such as "8.0.28-log" or
+// "10.6.12-MariaDB" by their numbers, returning -1, 0 or 1.
+func compareVersions(a string, b string) int {
+	va := parseVersion(a)
+	vb := parseVersion(b)
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+
+		if i < len(va) {
+			x = va[i]
+		}
+
+		if i < len(vb) {
+			y = vb[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// parseVersion returns the leading dot separated numbers of a version.
+func parseVersion(version string) []int {
+	// MariaDB prefixes its version with 5.5.5- for old replication
+	// clients.
+	if strings.HasPrefix(version, "5.5.5-") && strings.Contains(version, "MariaDB") {
+		version = version[len("5.5.5-"):]
+	}
+
+	var numbers []int
+
+	for _, part := range strings.SplitN(version, ".", 3) {
+		n := 0
+
+		for n < len(part) && part[n] >= '0' && part[n] <= '9' {
+			n++
+		}
+
+		number, err := strconv.Atoi(part[:n])
+
+		if err != nil {
+			break
+		}
+
+		numbers = append(numbers, number)
+
+		if n < len(part) {
+			break
+		}
+	}
+
+	return numbers
+}
+
+// zeroBytes overwrites secrets once they are no longer needed.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Secret holds a password. It prints and marshals as [redacted], and the
+// copy a Connection takes of it is zeroed once the handshake response is
+// built, so the password does not live as long as the connection.
+type Secret struct {
+	b []byte
+}
+
+// NewSecret returns a Secret holding password. The string itself cannot be
+// zeroed, use SecretFromBytes to keep the password out of strings.
+func NewSecret(password string) Secret {
+	if password == "" {
+		return Secret{}
+	}
+
+	return Secret{b: []byte(password)}
+}
+
+// SecretFromBytes returns a Secret holding password, which the caller must
+// not modify afterwards.
+func SecretFromBytes(password []byte) Secret {
+	return Secret{b: password}
+}
+
+// IsEmpty reports whether the Secret holds no password.
+func (s Secret) IsEmpty() bool {
+	return len(s.b) == 0
+}
+
+func (s Secret) String() string {
+	return "[redacted]"
+}
+
+func (s Secret) GoString() string {
+	return "mysql.Secret{[redacted]}"
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte("[redacted]"), nil
+}
+
+// clone returns a copy of the Secret which can be zeroed on its own.
+func (s Secret) clone() Secret {
+	if s.b == nil {
+		return s
+	}
+
+	return Secret{b: append([]byte{}, s.b...)}
+}
+
+// zero overwrites the password held by the Secret.
+func (s Secret) zero() {
+	zeroBytes(s.b)
+}
@@ -0,0 +1,251 @@
+package mysql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"8.0.28", "8.0.28", 0},
+		{"8.0.28-log", "8.0.9", 1},
+		{"5.7.44", "8.0", -1},
+		{"10.6.12-MariaDB", "10.6", 1},
+		{"10.6.0-MariaDB", "10.6", 0},
+		{"5.5.5-10.11.2-MariaDB-1:10.11.2+maria~ubu2204", "10.6", 1},
+		{"8.4.0", "8.4", 0},
+		{"fake", "5.7", -1},
+	}
+
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.expected {
+			t.Errorf("compareVersions(%q, %q) = %d", test.a, test.b, got)
+		}
+	}
+}
+
+// dialPipe returns connection parameters dialing the client end of a pipe
+// whose server end is played by the returned fake server.
+func dialPipe(t *testing.T, param ConnectionParameter) (ConnectionParameter, *fakeServer) {
+	client, serverConn := net.Pipe()
+
+	param.Network = "tcp"
+	param.Host = "db.local"
+	param.Dial = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return client, nil
+	}
+
+	t.Cleanup(func() { serverConn.Close() })
+
+	return param, newFakeServer(t, serverConn)
+}
+
+func TestStrictSecurity(t *testing.T) {
+	for _, param := range []ConnectionParameter{
+		{StrictSecurity: true},
+		{StrictSecurity: true, TLSConfig: &tls.Config{InsecureSkipVerify: true}},
+		{StrictSecurity: true, TLSConfig: &tls.Config{}, AllowLocalInfile: true},
+	} {
+		err := NewConnection(param).Open()
+
+		if _, ok := err.(*SecurityError); ok == false {
+			t.Errorf("%+v: unexpected error %v", param, err)
+		}
+	}
+
+	// Unix sockets do not need TLS, the server is refused for its version
+	// once the init packet is read.
+	param, server := dialPipe(t, ConnectionParameter{StrictSecurity: true, MinServerVersion: "8.0"})
+	param.Network = "unix"
+
+	go server.writePacket(0, initPacket())
+
+	err := NewConnection(param).Open()
+
+	if e, ok := err.(*SecurityError); ok == false || e.Reason != "server version 5.7.99-fake is older than 8.0" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The cleartext plugin would send the password as is.
+	param, server = dialPipe(t, ConnectionParameter{StrictSecurity: true})
+	param.Network = "unix"
+
+	payload := initPacket()
+	payload = append(payload[:len(payload)-len("mysql_native_password\x00")], "mysql_clear_password\x00"...)
+
+	go server.writePacket(0, payload)
+
+	err = NewConnection(param).Open()
+
+	if _, ok := err.(*SecurityError); ok == false {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// tlsInitPacket is the init packet of a server supporting CLIENT_SSL.
+func tlsInitPacket() []byte {
+	payload := initPacket()
+	payload[len("\x0a5.7.99-fake\x00")+4+8+1+1] |= byte(CLIENT_SSL >> 8)
+
+	return payload
+}
+
+// selfSignedCertificate returns a certificate for db.local and the pool
+// trusting it.
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db.local"},
+		DNSNames:              []string{"db.local"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestOpenTLS(t *testing.T) {
+	certificate, pool := selfSignedCertificate(t)
+
+	param, server := dialPipe(t, ConnectionParameter{
+		StrictSecurity: true,
+		TLSConfig:      &tls.Config{RootCAs: pool},
+	})
+
+	go func() {
+		server.writePacket(0, tlsInitPacket())
+
+		seq, payload := server.readPacket()
+
+		if seq != 1 || len(payload) != 32 || ClientFlags(UnpackNumber(payload, 4))&CLIENT_SSL == 0 {
+			t.Errorf("unexpected SSL request %d %q", seq, payload)
+			return
+		}
+
+		tlsConn := tls.Server(server.conn, &tls.Config{Certificates: []tls.Certificate{certificate}})
+
+		if err := tlsConn.Handshake(); err != nil {
+			t.Errorf("server TLS handshake: %v", err)
+			return
+		}
+
+		server.conn = tlsConn
+		server.reader.Reset(tlsConn)
+		server.authenticate(2)
+	}()
+
+	c := NewConnection(param)
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.conn.(*tls.Conn); ok == false || server.clientFlags&CLIENT_SSL == 0 {
+		t.Errorf("connection not secured")
+	}
+
+	c.Close()
+}
+
+func TestOpenNoTLS(t *testing.T) {
+	param, server := dialPipe(t, ConnectionParameter{TLSConfig: &tls.Config{}})
+
+	go server.writePacket(0, initPacket())
+
+	if err := NewConnection(param).Open(); err != ErrNoTLS {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOpenTLSRefused(t *testing.T) {
+	param, server := dialPipe(t, ConnectionParameter{TLSConfig: &tls.Config{InsecureSkipVerify: true}})
+
+	go func() {
+		server.writePacket(0, tlsInitPacket())
+		server.readPacket()
+
+		// The answer to the ClientHello.
+		server.reader.Read(make([]byte, 4096))
+		server.writePacket(2, append([]byte{ERR_PACKET, 0x0d, 0x0f, '#', '0', '8', '0', '0', '4'}, "Connections using insecure transport are prohibited"...))
+
+		io.Copy(io.Discard, server.reader)
+	}()
+
+	err := NewConnection(param).Open()
+
+	if mysqlErr, ok := err.(*MySQLError); ok == false || mysqlErr.Number != 3853 || mysqlErr.SQLState != "08004" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	param := ConnectionParameter{Username: "u", Password: NewSecret("hunter2")}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if s := fmt.Sprintf(format, param); strings.Contains(s, "hunter2") == true {
+			t.Errorf("%s: password printed in %s", format, s)
+		}
+	}
+
+	if b, _ := json.Marshal(param); strings.Contains(string(b), "hunter2") == true {
+		t.Errorf("password marshaled in %s", b)
+	}
+
+	// The connection zeroes its own copy of the password.
+	c, serverConn := newPipeConnection(param)
+	c.param.Password = param.Password.clone()
+	c.ScramblePart1 = []byte("scramble")
+	c.ScramblePart2 = []byte("part2scrambl")
+	server := newFakeServer(t, serverConn)
+
+	go server.readPacket()
+
+	if err := c.sendAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(c.param.Password.b) != "\x00\x00\x00\x00\x00\x00\x00" || string(param.Password.b) != "hunter2" {
+		t.Errorf("unexpected passwords %q %q", c.param.Password.b, param.Password.b)
+	}
+}
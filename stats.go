@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatementMetrics are the totals of the statements sharing a fingerprint.
+// The time of a statement runs from its command to the end of its
+// response, so it includes the time the application takes to read the
+// rows. Rows counts the rows read, not those Rows.Close skipped; Bytes
+// counts the whole response.
+type StatementMetrics struct {
+	Fingerprint string
+	Count       int64
+	Errors      int64
+	Rows        int64
+	Bytes       int64
+	TotalTime   time.Duration
+	MaxTime     time.Duration
+	LastSeen    time.Time
+}
+
+// StatementStats aggregates latency, rows, errors and bytes per statement
+// fingerprint (see Fingerprint) on the client side, like pt-query-digest
+// without access to the slow log. It keeps the most recently seen size
+// fingerprints and is shared by the connections whose StatementStats it
+// is, e.g. those of a Connector.
+type StatementStats struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element // of *StatementMetrics
+	recent  *list.List               // most recently seen first
+	evicted int64
+}
+
+// NewStatementStats returns a StatementStats keeping up to size
+// fingerprints.
+func NewStatementStats(size int) *StatementStats {
+	return &StatementStats{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// record adds a statement to the metrics of its fingerprint.
+func (s *StatementStats) record(query string, duration time.Duration, rows int64, bytes int64, err error) {
+	fingerprint := Fingerprint(query)
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[fingerprint]
+
+	if ok == true {
+		s.recent.MoveToFront(element)
+	} else {
+		if s.recent.Len() >= s.size && s.recent.Len() > 0 {
+			oldest := s.recent.Back()
+			s.recent.Remove(oldest)
+			delete(s.entries, oldest.Value.(*StatementMetrics).Fingerprint)
+			s.evicted++
+		}
+
+		element = s.recent.PushFront(&StatementMetrics{Fingerprint: fingerprint})
+		s.entries[fingerprint] = element
+	}
+
+	metrics := element.Value.(*StatementMetrics)
+	metrics.Count++
+	metrics.Rows += rows
+	metrics.Bytes += bytes
+	metrics.TotalTime += duration
+	metrics.LastSeen = now
+
+	if duration > metrics.MaxTime {
+		metrics.MaxTime = duration
+	}
+
+	if err != nil {
+		metrics.Errors++
+	}
+}
+
+// Stats returns the metrics of the fingerprints kept, the most time
+// consuming first.
+func (s *StatementStats) Stats() []StatementMetrics {
+	s.mutex.Lock()
+
+	stats := make([]StatementMetrics, 0, s.recent.Len())
+
+	for element := s.recent.Front(); element != nil; element = element.Next() {
+		stats = append(stats, *element.Value.(*StatementMetrics))
+	}
+
+	s.mutex.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].TotalTime > stats[j].TotalTime
+	})
+
+	return stats
+}
+
+// Evicted returns the number of fingerprints dropped to make room for new
+// ones, which a size too small for the workload keeps increasing.
+func (s *StatementStats) Evicted() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.evicted
+}
+
+// Reset clears the metrics.
+func (s *StatementStats) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries = make(map[string]*list.Element)
+	s.recent.Init()
+	s.evicted = 0
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format, labelled by fingerprint, for a /metrics handler.
+func (s *StatementStats) WritePrometheus(w io.Writer) error {
+	stats := s.Stats()
+
+	bw := bufio.NewWriter(w)
+
+	families := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(m *StatementMetrics) string
+	}{
+		{"mysql_client_statements_total", "counter", "Statements sent.", func(m *StatementMetrics) string { return fmt.Sprint(m.Count) }},
+		{"mysql_client_statement_errors_total", "counter", "Statements which failed.", func(m *StatementMetrics) string { return fmt.Sprint(m.Errors) }},
+		{"mysql_client_statement_rows_total", "counter", "Rows read.", func(m *StatementMetrics) string { return fmt.Sprint(m.Rows) }},
+		{"mysql_client_statement_bytes_total", "counter", "Response bytes read.", func(m *StatementMetrics) string { return fmt.Sprint(m.Bytes) }},
+		{"mysql_client_statement_seconds_total", "counter", "Time spent in statements.", func(m *StatementMetrics) string { return fmt.Sprint(m.TotalTime.Seconds()) }},
+		{"mysql_client_statement_max_seconds", "gauge", "Longest statement.", func(m *StatementMetrics) string { return fmt.Sprint(m.MaxTime.Seconds()) }},
+	}
+
+	for _, family := range families {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+
+		for i := range stats {
+			fmt.Fprintf(bw, "%s{fingerprint=\"%s\"} %s\n", family.name, escapeLabelValue(stats[i].Fingerprint), family.value(&stats[i]))
+		}
+	}
+
+	fmt.Fprintf(bw, "# HELP mysql_client_statement_fingerprints_evicted_total Fingerprints dropped from the statement stats.\n")
+	fmt.Fprintf(bw, "# TYPE mysql_client_statement_fingerprints_evicted_total counter\n")
+	fmt.Fprintf(bw, "mysql_client_statement_fingerprints_evicted_total %d\n", s.Evicted())
+
+	return bw.Flush()
+}
+
+// escapeLabelValue escapes a Prometheus label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// recordStatement adds a statement whose response ends now to the
+// StatementStats of the connection.
+func (c *Connection) recordStatement(query string, start time.Time, rows int64, err error) {
+	if c.param.StatementStats == nil {
+		return
+	}
+
+	c.param.StatementStats.record(query, time.Since(start), rows, c.readBytes-c.commandBytes, err)
+}
@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatementStats(t *testing.T) {
+	stats := NewStatementStats(16)
+	c, serverConn := newPipeConnection(ConnectionParameter{StatementStats: stats})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		for _, id := range []string{"1", "2"} {
+			server.readCommand()
+			server.writeResultSet(1, [][]byte{
+				columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+			}, [][]byte{
+				textRowPacket("Taipei " + id),
+			}, 0)
+		}
+
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.x' doesn't exist"...))
+	}()
+
+	for _, query := range []string{"SELECT name FROM city WHERE id = 1", "select name\n from city  WHERE id = 2", "SELECT * FROM x"} {
+		rows, err := c.Query(query)
+
+		if err != nil {
+			continue
+		}
+
+		for rows.Next() {
+		}
+
+		rows.Close()
+	}
+
+	metrics := stats.Stats()
+
+	if len(metrics) != 2 {
+		t.Fatalf("unexpected stats %+v", metrics)
+	}
+
+	for _, m := range metrics {
+		switch m.Fingerprint {
+		case "select name from city where id = ?":
+			// The column count, column, EOF, row and EOF packets.
+			size := int64(4+1) + int64(4+len(columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45))) + 2*int64(4+5) + int64(4+len(textRowPacket("Taipei 1")))
+
+			if m.Count != 2 || m.Errors != 0 || m.Rows != 2 || m.Bytes != 2*size || m.TotalTime <= 0 || m.MaxTime > m.TotalTime {
+				t.Errorf("unexpected metrics %+v", m)
+			}
+		case "select * from x":
+			if m.Count != 1 || m.Errors != 1 || m.Rows != 0 {
+				t.Errorf("unexpected metrics %+v", m)
+			}
+		default:
+			t.Errorf("unexpected fingerprint %q", m.Fingerprint)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := stats.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), `mysql_client_statement_rows_total{fingerprint="select name from city where id = ?"} 2`+"\n") == false {
+		t.Errorf("unexpected exposition\n%s", buf.String())
+	}
+}
+
+func TestStatementStatsEviction(t *testing.T) {
+	stats := NewStatementStats(2)
+
+	stats.record("SELECT 1 FROM a", time.Millisecond, 1, 10, nil)
+	stats.record("SELECT 1 FROM b", time.Millisecond, 1, 10, nil)
+	stats.record("SELECT 2 FROM a", 3*time.Millisecond, 1, 10, errors.New("failed"))
+	stats.record("SELECT 1 FROM c", 2*time.Millisecond, 1, 10, nil)
+
+	metrics := stats.Stats()
+
+	if len(metrics) != 2 || metrics[0].Fingerprint != "select ? from a" || metrics[0].Count != 2 || metrics[0].Errors != 1 ||
+		metrics[1].Fingerprint != "select ? from c" || stats.Evicted() != 1 {
+		t.Errorf("unexpected stats %+v, %d evicted", metrics, stats.Evicted())
+	}
+
+	stats.Reset()
+
+	if len(stats.Stats()) != 0 || stats.Evicted() != 0 {
+		t.Error("stats not reset")
+	}
+}
@@ -0,0 +1,34 @@
+package mysql
+
+// The status flags of the server are sent with every OK and EOF packet,
+// the helpers below report them as of the last command.
+// Reference:
+// https://mariadb.com/kb/en/library/ok_packet/#server-status-flag
+
+// InTransaction reports whether a transaction is open.
+func (c *Connection) InTransaction() bool {
+	return c.status&SERVER_STATUS_IN_TRANS != 0
+}
+
+// AutocommitEnabled reports whether autocommit is on.
+func (c *Connection) AutocommitEnabled() bool {
+	return c.status&SERVER_STATUS_AUTOCOMMIT != 0
+}
+
+// CursorExists reports whether a cursor is open for the last prepared
+// statement.
+func (c *Connection) CursorExists() bool {
+	return c.status&SERVER_STATUS_CURSOR_EXISTS != 0
+}
+
+// LastStatementWasSlow reports whether the last statement exceeded
+// long_query_time.
+func (c *Connection) LastStatementWasSlow() bool {
+	return c.status&SERVER_QUERY_WAS_SLOW != 0
+}
+
+// MoreResultsExist reports whether another result set follows the current
+// one, e.g. for a multi-statement or a stored procedure call.
+func (c *Connection) MoreResultsExist() bool {
+	return c.status&SERVER_MORE_RESULTS_EXISTS != 0
+}
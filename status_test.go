@@ -0,0 +1,28 @@
+package mysql
+
+import (
+	"testing"
+)
+
+func TestStatusFlags(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_IN_TRANS|SERVER_QUERY_WAS_SLOW, 0, ""))
+	}()
+
+	rows, err := c.Query("BEGIN")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows.Close()
+
+	if c.InTransaction() == false || c.AutocommitEnabled() == true || c.LastStatementWasSlow() == false ||
+		c.CursorExists() == true || c.MoreResultsExist() == true {
+		t.Errorf("unexpected status %x", c.status)
+	}
+}
@@ -0,0 +1,392 @@
+package mysql
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+var (
+	ErrStmtClosed = errors.New("Statement Closed")
+)
+
+// Stmt is a server side prepared statement. The statement is parsed once
+// by the server and its arguments are sent apart from it in the binary
+// protocol, so they are never part of the SQL text.
+//
+// A Stmt belongs to the session it was prepared in: it is lost when the
+// connection is reset (ResetConnection) or reopened. The database/sql
+// driver keeps preparing on the client side, see driverStmt.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_prepare.html
+type Stmt struct {
+	c       *Connection
+	id      uint32
+	query   string
+	params  []Column
+	columns []Column
+	closed  bool // guarded by the mutex of c
+
+	// metadata are the columns of the last execution, which MariaDB does
+	// not send again while they are unchanged with
+	// MARIADB_CLIENT_CACHE_METADATA. Guarded by the mutex of c.
+	metadata []Column
+}
+
+// Prepare sends a COM_STMT_PREPARE and returns the prepared statement.
+func (c *Connection) Prepare(query string) (*Stmt, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	err := c.writeCommand(COM_STMT_PREPARE, []byte(query))
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.readPacket()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > 0 && payload[0] == ERR_PACKET {
+		return nil, parseErrPacket(payload)
+	}
+
+	// status [1 byte, 0x00]
+	// statement id [4 bytes]
+	// column count [2 bytes]
+	// parameter count [2 bytes]
+	// reserved [1 byte]
+	// warning count [2 bytes]
+	if len(payload) < 1+4+2+2 || payload[0] != OK_PACKET {
+		return nil, ErrMalformedPacket
+	}
+
+	s := &Stmt{
+		c:     c,
+		id:    binary.LittleEndian.Uint32(payload[1:5]),
+		query: query,
+	}
+
+	columnCount := uint64(binary.LittleEndian.Uint16(payload[5:7]))
+	paramCount := uint64(binary.LittleEndian.Uint16(payload[7:9]))
+
+	// The parameter and column definitions each end with an EOF packet.
+	// The statement is prepared on the server even if they cannot be read.
+	if paramCount > 0 {
+		s.params, err = c.readColumns(paramCount)
+
+		if err != nil {
+			c.abandonStmt(s.id)
+			return nil, err
+		}
+	}
+
+	if columnCount > 0 {
+		s.columns, err = c.readColumns(columnCount)
+
+		if err != nil {
+			c.abandonStmt(s.id)
+			return nil, err
+		}
+	}
+
+	s.metadata = s.columns
+
+	return s, nil
+}
+
+// NumInput returns the number of placeholders of the statement.
+func (s *Stmt) NumInput() int {
+	return len(s.params)
+}
+
+// Params returns the definitions the server sent for the placeholders of
+// the statement, in order. Their types are hints of the server, e.g. the
+// type of the column a placeholder is compared with.
+func (s *Stmt) Params() []Column {
+	return s.params
+}
+
+// Columns returns the columns of the result set of the statement as known
+// when it was prepared, nil when it returns none.
+func (s *Stmt) Columns() []Column {
+	return s.columns
+}
+
+// Exec executes the statement with args, which may be nil, integers,
+// floats, bool, string, []byte, time.Time (sent in UTC, the zero time as
+// the zero date) or time.Duration (a TIME), and returns its OK packet. The
+// rows of a statement returning some are discarded and the Result is nil.
+func (s *Stmt) Exec(args ...interface{}) (*Result, error) {
+	rows, err := s.execute(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := rows.Result()
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Query executes the statement with args, as Exec, and returns the rows of
+// its result set, read like those of Connection.Query.
+func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
+	return s.execute(args)
+}
+
+// execute sends a COM_STMT_EXECUTE and returns its response like Query:
+// the connection stays locked until the rows are released.
+func (s *Stmt) execute(args []interface{}) (*Rows, error) {
+	if len(args) != len(s.params) {
+		return nil, ErrArgumentCount
+	}
+
+	arg, err := s.executePayload(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.c
+
+	c.mutex.Lock()
+	defer c.unlockOnPanic()
+
+	// closed is guarded by the connection, like the statement on the
+	// server.
+	if s.closed == true {
+		c.mutex.Unlock()
+		return nil, ErrStmtClosed
+	}
+
+	start := time.Now()
+
+	rows, err := c.executeStmt(s, arg)
+
+	if err != nil {
+		c.audit(s.query, start, nil, err)
+		c.recordStatement(s.query, start, 0, err)
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	c.audit(s.query, start, rows.result, nil)
+
+	rows.query = s.query
+	rows.start = start
+
+	if rows.done == true && c.status&SERVER_MORE_RESULTS_EXISTS == 0 {
+		rows.release()
+	}
+
+	return rows, nil
+}
+
+func (c *Connection) executeStmt(s *Stmt, arg []byte) (*Rows, error) {
+	err := c.writeCommand(COM_STMT_EXECUTE, arg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &Rows{c: c, binary: true, stmt: s}
+
+	err = rows.readResultSetHeader()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// executePayload returns the argument of the COM_STMT_EXECUTE of args.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_execute.html
+func (s *Stmt) executePayload(args []interface{}) ([]byte, error) {
+	// statement id [4 bytes]
+	// flags [1 byte, CURSOR_TYPE_NO_CURSOR]
+	// iteration count [4 bytes, always 1]
+	payload := make([]byte, 4, 4+1+4)
+	binary.LittleEndian.PutUint32(payload, s.id)
+	payload = append(payload, 0, 1, 0, 0, 0)
+
+	if len(args) == 0 {
+		return payload, nil
+	}
+
+	// NULL bitmap [(parameter count + 7) / 8 bytes]
+	// new parameters bound flag [1 byte]
+	// parameter types [2 bytes each: type, 0x80 if unsigned]
+	// parameter values
+	payload = protocol.AppendNullBitmap(payload, len(args), protocol.STMT_EXECUTE_NULL_BITMAP_OFFSET, func(i int) bool {
+		return isNullArgument(args[i])
+	})
+
+	payload = append(payload, 1)
+
+	types := len(payload)
+	payload = append(payload, make([]byte, 2*len(args))...)
+
+	for i, arg := range args {
+		var fieldType FieldType
+		var unsigned bool
+		var err error
+
+		fieldType, unsigned, payload, err = appendBinaryValue(payload, arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payload[types+2*i] = byte(fieldType)
+
+		if unsigned == true {
+			payload[types+2*i+1] = 0x80
+		}
+	}
+
+	return payload, nil
+}
+
+// isNullArgument reports whether arg is sent as NULL.
+func isNullArgument(arg interface{}) bool {
+	if b, ok := arg.([]byte); ok == true {
+		return b == nil
+	}
+
+	return arg == nil
+}
+
+// appendBinaryValue appends arg in the binary protocol and returns its
+// type and whether it is unsigned. NULL takes no value bytes.
+func appendBinaryValue(b []byte, arg interface{}) (FieldType, bool, []byte, error) {
+	if isNullArgument(arg) == true {
+		return MYSQL_TYPE_NULL, false, b, nil
+	}
+
+	switch v := arg.(type) {
+	case int:
+		return MYSQL_TYPE_LONGLONG, false, appendUint64(b, uint64(v)), nil
+	case int8:
+		return MYSQL_TYPE_LONGLONG, false, appendUint64(b, uint64(v)), nil
+	case int16:
+		return MYSQL_TYPE_LONGLONG, false, appendUint64(b, uint64(v)), nil
+	case int32:
+		return MYSQL_TYPE_LONGLONG, false, appendUint64(b, uint64(v)), nil
+	case int64:
+		return MYSQL_TYPE_LONGLONG, false, appendUint64(b, uint64(v)), nil
+	case uint:
+		return MYSQL_TYPE_LONGLONG, true, appendUint64(b, uint64(v)), nil
+	case uint8:
+		return MYSQL_TYPE_LONGLONG, true, appendUint64(b, uint64(v)), nil
+	case uint16:
+		return MYSQL_TYPE_LONGLONG, true, appendUint64(b, uint64(v)), nil
+	case uint32:
+		return MYSQL_TYPE_LONGLONG, true, appendUint64(b, uint64(v)), nil
+	case uint64:
+		return MYSQL_TYPE_LONGLONG, true, appendUint64(b, v), nil
+	case float32:
+		return MYSQL_TYPE_FLOAT, false, appendUint32(b, math.Float32bits(v)), nil
+	case float64:
+		return MYSQL_TYPE_DOUBLE, false, appendUint64(b, math.Float64bits(v)), nil
+	case bool:
+		if v == true {
+			return MYSQL_TYPE_TINY, false, append(b, 1), nil
+		}
+
+		return MYSQL_TYPE_TINY, false, append(b, 0), nil
+	case string:
+		return MYSQL_TYPE_VAR_STRING, false, protocol.AppendLenEncString(b, []byte(v)), nil
+	case []byte:
+		return MYSQL_TYPE_BLOB, false, protocol.AppendLenEncString(b, v), nil
+	case time.Time:
+		return MYSQL_TYPE_DATETIME, false, protocol.AppendBinaryDateTime(b, v.UTC()), nil
+	case time.Duration:
+		return MYSQL_TYPE_TIME, false, protocol.AppendBinaryTime(b, v), nil
+	}
+
+	return 0, false, b, ErrArgumentType
+}
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+}
+
+func appendUint64(b []byte, n uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(n)), uint32(n>>32))
+}
+
+// Reset discards the data sent for the statement with
+// COM_STMT_SEND_LONG_DATA and closes its cursor with a COM_STMT_RESET.
+// Reference:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_reset.html
+func (s *Stmt) Reset() error {
+	c := s.c
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	if s.closed == true {
+		return ErrStmtClosed
+	}
+
+	err := c.writeCommand(COM_STMT_RESET, appendUint32(nil, s.id))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.readOKPacket()
+
+	return err
+}
+
+// Close deallocates the statement on the server with a COM_STMT_CLOSE,
+// which has no response.
+func (s *Stmt) Close() error {
+	c := s.c
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.poisonOnPanic()
+
+	if s.closed == true {
+		return nil
+	}
+
+	s.closed = true
+
+	return c.closeStmt(s.id)
+}
+
+// closeStmt sends the COM_STMT_CLOSE of the statement id.
+func (c *Connection) closeStmt(id uint32) error {
+	arg := make([]byte, 4)
+	binary.LittleEndian.PutUint32(arg, id)
+
+	return c.writeCommand(COM_STMT_CLOSE, arg)
+}
+
+// abandonStmt closes the statement id whose Prepare failed, unless the
+// connection is broken and cannot send it.
+func (c *Connection) abandonStmt(id uint32) {
+	if c.broken == false {
+		c.closeStmt(id)
+	}
+}
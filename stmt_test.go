@@ -0,0 +1,414 @@
+package mysql
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/junhsieh/go-mysql-pure/protocol"
+)
+
+// prepareOKPacket builds the first packet of a COM_STMT_PREPARE response.
+func prepareOKPacket(id uint32, columns uint16, params uint16) []byte {
+	return []byte{OK_PACKET, byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24), byte(columns), byte(columns >> 8), byte(params), byte(params >> 8), 0, 0, 0}
+}
+
+func TestStmt(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	query := "INSERT INTO t VALUES (?, ?, ?, ?, ?)"
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if payload := server.readCommand(); payload[0] != COM_STMT_PREPARE || string(payload[1:]) != query {
+			t.Errorf("unexpected prepare %q", payload)
+		}
+
+		server.writePacket(1, prepareOKPacket(7, 0, 5))
+
+		for i := 0; i < 5; i++ {
+			server.writePacket(uint8(2+i), columnPacket("?", MYSQL_TYPE_VAR_STRING, 0, 63))
+		}
+
+		server.writePacket(7, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		expected := []byte{COM_STMT_EXECUTE, 7, 0, 0, 0, 0, 1, 0, 0, 0}
+		expected = append(expected, 0x02, 1)
+		expected = append(expected, byte(MYSQL_TYPE_LONGLONG), 0, byte(MYSQL_TYPE_NULL), 0, byte(MYSQL_TYPE_VAR_STRING), 0, byte(MYSQL_TYPE_LONGLONG), 0x80, byte(MYSQL_TYPE_DATETIME), 0)
+		expected = append(expected, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+		expected = append(expected, 1, 'x')
+		expected = append(expected, 0, 0, 0, 0, 0, 0, 0, 0x80)
+		expected = append(expected, 7, 0xe0, 0x07, 1, 2, 3, 4, 5)
+
+		if payload := server.readCommand(); bytes.Equal(payload, expected) == false {
+			t.Errorf("unexpected execute\n got % x\nwant % x", payload, expected)
+		}
+
+		server.writePacket(1, okPacket(1, 9, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		if payload := server.readCommand(); bytes.Equal(payload, []byte{COM_STMT_CLOSE, 7, 0, 0, 0}) == false {
+			t.Errorf("unexpected close % x", payload)
+		}
+
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'test.missing' doesn't exist"...))
+	}()
+
+	stmt, err := c.Prepare(query)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stmt.NumInput() != 5 || stmt.Columns() != nil {
+		t.Errorf("unexpected statement %+v", stmt)
+	}
+
+	if params := stmt.Params(); len(params) != 5 || params[0].Type != MYSQL_TYPE_VAR_STRING || params[0].CharacterSet != 63 {
+		t.Errorf("unexpected parameters %+v", params)
+	}
+
+	if _, err := stmt.Exec(1, 2); err != ErrArgumentCount {
+		t.Errorf("argument count: got %v", err)
+	}
+
+	result, err := stmt.Exec(int64(-1), nil, "x", uint64(1<<63), time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if err != nil || result.AffectedRows() != 1 || result.LastInsertId() != 9 {
+		t.Errorf("unexpected result %+v %v", result, err)
+	}
+
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stmt.Exec(1, 2, 3, 4, 5); err != ErrStmtClosed {
+		t.Errorf("closed statement: got %v", err)
+	}
+
+	_, err = c.Prepare("SELECT * FROM missing")
+
+	if mysqlErr, ok := err.(*MySQLError); ok == false || mysqlErr.Number != 1146 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	<-done
+}
+
+func TestAppendBinaryValue(t *testing.T) {
+	tests := []struct {
+		arg       interface{}
+		fieldType FieldType
+		unsigned  bool
+		value     []byte
+	}{
+		{[]byte(nil), MYSQL_TYPE_NULL, false, nil},
+		{int8(-2), MYSQL_TYPE_LONGLONG, false, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{uint16(258), MYSQL_TYPE_LONGLONG, true, []byte{2, 1, 0, 0, 0, 0, 0, 0}},
+		{float32(1), MYSQL_TYPE_FLOAT, false, []byte{0, 0, 0x80, 0x3f}},
+		{float64(1), MYSQL_TYPE_DOUBLE, false, []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}},
+		{true, MYSQL_TYPE_TINY, false, []byte{1}},
+		{[]byte{0, 1}, MYSQL_TYPE_BLOB, false, []byte{2, 0, 1}},
+		{time.Time{}, MYSQL_TYPE_DATETIME, false, []byte{0}},
+		{-90 * time.Minute, MYSQL_TYPE_TIME, false, []byte{8, 1, 0, 0, 0, 0, 1, 30, 0}},
+	}
+
+	for _, test := range tests {
+		fieldType, unsigned, value, err := appendBinaryValue(nil, test.arg)
+
+		if err != nil || fieldType != test.fieldType || unsigned != test.unsigned || bytes.Equal(value, test.value) == false {
+			t.Errorf("%#v: got %v %v % x %v", test.arg, fieldType, unsigned, value, err)
+		}
+	}
+
+	if _, _, _, err := appendBinaryValue(nil, struct{}{}); err != ErrArgumentType {
+		t.Errorf("struct: got %v", err)
+	}
+}
+
+// decimalsColumnPacket is columnPacket with the given decimals.
+func decimalsColumnPacket(name string, fieldType FieldType, flags uint16, decimals uint8) []byte {
+	payload := columnPacket(name, fieldType, flags, 63)
+	payload[len(payload)-3] = decimals
+
+	return payload
+}
+
+func TestStmtQuery(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	columns := [][]byte{
+		columnPacket("tiny", MYSQL_TYPE_TINY, 0, 63),
+		columnPacket("short", MYSQL_TYPE_SHORT, UNSIGNED_FLAG, 63),
+		columnPacket("long", MYSQL_TYPE_LONG, 0, 63),
+		columnPacket("longlong", MYSQL_TYPE_LONGLONG, UNSIGNED_FLAG, 63),
+		decimalsColumnPacket("float", MYSQL_TYPE_FLOAT, 0, NOT_FIXED_DEC),
+		decimalsColumnPacket("double", MYSQL_TYPE_DOUBLE, 0, NOT_FIXED_DEC),
+		columnPacket("string", MYSQL_TYPE_VAR_STRING, 0, 45),
+		columnPacket("null", MYSQL_TYPE_VAR_STRING, 0, 45),
+		columnPacket("date", MYSQL_TYPE_DATE, 0, 63),
+		decimalsColumnPacket("datetime", MYSQL_TYPE_DATETIME, 0, 3),
+		columnPacket("zero", MYSQL_TYPE_DATETIME, 0, 63),
+		decimalsColumnPacket("time", MYSQL_TYPE_TIME, 0, 6),
+		columnPacket("year", MYSQL_TYPE_YEAR, UNSIGNED_FLAG, 63),
+	}
+
+	row := []byte{OK_PACKET, 0x00, 0x02}
+	row = append(row, 0xff)
+	row = append(row, 0xff, 0xff)
+	row = append(row, 0xfe, 0xff, 0xff, 0xff)
+	row = append(row, 0, 0, 0, 0, 0, 0, 0, 0x80)
+	row = append(row, 0, 0, 0xc0, 0x3f)
+	row = append(row, 0x9a, 0x99, 0x99, 0x99, 0x99, 0x99, 0xb9, 0x3f)
+	row = append(row, lenEncString("héllo")...)
+	row = protocol.AppendBinaryDateTime(row, time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC))
+	row = protocol.AppendBinaryDateTime(row, time.Date(2016, 1, 2, 3, 4, 5, 123456000, time.UTC))
+	row = protocol.AppendBinaryDateTime(row, time.Time{})
+	row = protocol.AppendBinaryTime(row, -(26*time.Hour + 3*time.Minute + 4*time.Second + 5*time.Microsecond))
+	row = append(row, 0xe8, 0x07)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(8, uint16(len(columns)), 0))
+
+		for i, column := range columns {
+			server.writePacket(uint8(2+i), column)
+		}
+
+		server.writePacket(uint8(2+len(columns)), eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		server.readCommand()
+		server.writeResultSet(1, columns, [][]byte{row}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	stmt, err := c.Prepare("SELECT ...")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stmt.Columns()) != len(columns) {
+		t.Errorf("%d columns", len(stmt.Columns()))
+	}
+
+	rows, err := stmt.Query()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows.Next() == false {
+		t.Fatal(rows.Err())
+	}
+
+	expected := []interface{}{"-1", "65535", "-2", "9223372036854775808", "1.5", "0.1", "héllo", nil, "2016-01-02", "2016-01-02 03:04:05.123", "0000-00-00 00:00:00", "-26:03:04.000005", "2024"}
+
+	for i, value := range rows.Values() {
+		if expected[i] == nil && value != nil || expected[i] != nil && string(value) != expected[i] {
+			t.Errorf("%s: got %q", rows.Columns()[i].Name, value)
+		}
+	}
+
+	var tiny int64
+	var longlong uint64
+	var double float64
+	var date string
+	var null []byte
+
+	if err := rows.Scan(&tiny, new([]byte), new(int64), &longlong, new(float64), &double, new(string), &null, &date, new(string), new(string), new(string), new(int64)); err != nil {
+		t.Fatal(err)
+	}
+
+	if tiny != -1 || longlong != 1<<63 || double != 0.1 || date != "2016-01-02" || null != nil {
+		t.Errorf("scanned %d %d %v %q %q", tiny, longlong, double, date, null)
+	}
+
+	if rows.Next() == true || rows.Err() != nil {
+		t.Errorf("unexpected end %v", rows.Err())
+	}
+
+	// A truncated row.
+	rows = &Rows{c: c, binary: true, columns: rows.columns}
+
+	if err := rows.parseBinaryRow(row[:len(row)-1]); err != ErrMalformedPacket {
+		t.Errorf("truncated row: got %v", err)
+	}
+}
+
+func TestPrepareMalformedColumns(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(9, 1, 0))
+		server.writePacket(2, []byte{0xfc})
+
+		// The statement prepared on the server is not leaked.
+		if payload := server.readCommand(); bytes.Equal(payload, []byte{COM_STMT_CLOSE, 9, 0, 0, 0}) == false {
+			t.Errorf("unexpected close % x", payload)
+		}
+	}()
+
+	if _, err := c.Prepare("SELECT 1"); err != ErrMalformedPacket {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	<-done
+}
+
+func TestStmtCloseConcurrently(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(7, 0, 0))
+
+		// Only one COM_STMT_CLOSE is sent, the executions either run
+		// before it or fail.
+		for {
+			payload := server.readCommand()
+
+			if payload[0] == COM_STMT_CLOSE {
+				return
+			}
+
+			server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+		}
+	}()
+
+	stmt, err := c.Prepare("DO 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := stmt.Exec(); err != nil && err != ErrStmtClosed {
+				t.Error(err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			stmt.Close()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestStmtReset(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(7, 0, 0))
+
+		if payload := server.readCommand(); bytes.Equal(payload, []byte{COM_STMT_RESET, 7, 0, 0, 0}) == false {
+			t.Errorf("unexpected reset % x", payload)
+		}
+
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0xdb, 0x04, '#', 'H', 'Y', '0', '0', '0'}, "Unknown prepared statement handler (7) given to mysqld_stmt_reset"...))
+
+		server.readCommand()
+	}()
+
+	stmt, err := c.Prepare("DO 1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stmt.Reset(); err != nil {
+		t.Error(err)
+	}
+
+	if mysqlErr, ok := stmt.Reset().(*MySQLError); ok == false || mysqlErr.Number != 1243 {
+		t.Errorf("unknown statement: got %v", mysqlErr)
+	}
+
+	stmt.Close()
+
+	if err := stmt.Reset(); err != ErrStmtClosed {
+		t.Errorf("closed statement: got %v", err)
+	}
+}
+
+func TestStmtMetadataCache(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	c.mariaDBCapabilities = MARIADB_CLIENT_CACHE_METADATA
+
+	row := append([]byte{OK_PACKET, 0}, lenEncString("x")...)
+
+	go func() {
+		server.readCommand()
+		server.writePacket(1, prepareOKPacket(7, 1, 0))
+		server.writePacket(2, columnPacket("a", MYSQL_TYPE_VAR_STRING, 0, 45))
+		server.writePacket(3, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+
+		// Unchanged, changed by an ALTER TABLE, unchanged again.
+		for _, metadata := range []byte{0, 1, 0} {
+			server.readCommand()
+			server.writePacket(1, []byte{1, metadata})
+			seq := uint8(2)
+
+			if metadata == 1 {
+				server.writePacket(seq, columnPacket("b", MYSQL_TYPE_VAR_STRING, 0, 45))
+				seq++
+			}
+
+			server.writePacket(seq, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+			server.writePacket(seq+1, row)
+			server.writePacket(seq+2, eofPacket(SERVER_STATUS_AUTOCOMMIT))
+		}
+	}()
+
+	stmt, err := c.Prepare("SELECT * FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a", "b", "b"} {
+		rows, err := stmt.Query()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rows.Columns()[0].Name != name || rows.Next() == false || string(rows.Values()[0]) != "x" {
+			t.Errorf("unexpected rows %+v %v", rows.Columns(), rows.Err())
+		}
+
+		rows.Close()
+	}
+
+	// Columns keeps the columns known when the statement was prepared.
+	if stmt.Columns()[0].Name != "a" {
+		t.Errorf("unexpected columns %+v", stmt.Columns())
+	}
+}
@@ -0,0 +1,283 @@
+package mysql
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var (
+	ErrRowStreamed = errors.New("Streamed row values must be read with ColumnReader")
+	ErrNoColumn    = errors.New("No more columns in the row")
+)
+
+// NextStream reads the next row like Next, except that a row whose packet
+// is at least threshold bytes long is not buffered: its values are read
+// from the network as they are consumed through ColumnReader, so that a
+// gigabyte BLOB can be copied to a file without being held in memory. Rows
+// split over several packets (16 MiB or more) are always streamed.
+//
+// Values and Scan are not available for a streamed row. The binary rows of
+// a prepared statement are never streamed.
+func (r *Rows) NextStream(threshold int) bool {
+	if r.binary == true {
+		return r.Next()
+	}
+
+	defer r.releaseOnPanic()
+
+	if r.done == true || r.err != nil {
+		return false
+	}
+
+	if r.stream != nil && r.endStream() == false {
+		return false
+	}
+
+	packetHeader, err := r.c.readPacketHeader()
+
+	if err == nil && packetHeader.Seq != r.c.sequence {
+		r.c.broken = true
+		err = ErrUnexpectedSequence
+	}
+
+	if err != nil {
+		r.err = err
+		r.release()
+		return false
+	}
+
+	r.c.sequence++
+
+	if packetHeader.Len < MAX_PACKET_SIZE-1 && (packetHeader.Len < uint64(threshold) || r.isRowEnd(packetHeader.Len) == true) {
+		payload, err := r.c.appendPayload(r.c.readBuf[:0], packetHeader.Len)
+
+		if err != nil {
+			r.err = err
+			r.release()
+			return false
+		}
+
+		r.c.readBuf = payload
+
+		return r.handleRow(payload)
+	}
+
+	r.stream = &rowStream{
+		c:         r.c,
+		remaining: packetHeader.Len,
+		more:      packetHeader.Len == MAX_PACKET_SIZE-1,
+	}
+	r.column = 0
+	r.count++
+
+	return true
+}
+
+// isRowEnd reports whether the packet whose header was just read is the
+// EOF or ERR packet ending the result set rather than a row.
+func (r *Rows) isRowEnd(length uint64) bool {
+	if length == 0 {
+		return false
+	}
+
+	first, err := r.c.reader.Peek(1)
+
+	if err != nil {
+		// Reading the payload reports the error.
+		return true
+	}
+
+	return first[0] == ERR_PACKET || (first[0] == EOF_PACKET && length < 9)
+}
+
+// ColumnReader returns a reader for the next value of the current row and
+// its length, or a nil reader for NULL. The value of a streamed row must be
+// read before the next call to ColumnReader, Next or NextStream; whatever
+// is left unread is then discarded.
+func (r *Rows) ColumnReader() (io.Reader, uint64, error) {
+	if r.column >= len(r.columns) {
+		return nil, 0, ErrNoColumn
+	}
+
+	r.column++
+
+	if r.stream == nil {
+		value := r.values[r.column-1]
+
+		if value == nil {
+			return nil, 0, nil
+		}
+
+		return bytes.NewReader(value), uint64(len(value)), nil
+	}
+
+	value, err := r.stream.nextValue()
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Nothing is allocated for a streamed value, but the limit still
+	// applies to what the caller is going to receive.
+	if value != nil && r.c.param.MaxColumnSize > 0 && value.n > uint64(r.c.param.MaxColumnSize) {
+		r.c.broken = true
+
+		return nil, 0, &ResponseTooLargeError{
+			Column: r.columns[r.column-1].Name,
+			Size:   value.n,
+			Limit:  r.c.param.MaxColumnSize,
+		}
+	}
+
+	if value == nil {
+		return nil, 0, nil
+	}
+
+	return value, value.n, nil
+}
+
+// endStream discards what is left of the streamed row.
+func (r *Rows) endStream() bool {
+	_, err := io.Copy(io.Discard, r.stream)
+
+	r.stream = nil
+
+	if err != nil {
+		r.err = err
+		r.release()
+		return false
+	}
+
+	return true
+}
+
+// rowStream reads the payload of a row straight from the connection,
+// following it across packets.
+type rowStream struct {
+	c         *Connection
+	remaining uint64 // unread bytes of the current packet
+	more      bool   // the current packet is followed by another one
+	value     *valueReader
+	buf       [8]byte
+}
+
+func (s *rowStream) Read(p []byte) (int, error) {
+	for s.remaining == 0 {
+		if s.more == false {
+			return 0, io.EOF
+		}
+
+		packetHeader, err := s.c.readPacketHeader()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if packetHeader.Seq != s.c.sequence {
+			s.c.broken = true
+			return 0, ErrUnexpectedSequence
+		}
+
+		s.c.sequence++
+		s.remaining = packetHeader.Len
+		s.more = packetHeader.Len == MAX_PACKET_SIZE-1
+	}
+
+	if uint64(len(p)) > s.remaining {
+		p = p[:s.remaining]
+	}
+
+	n, err := s.c.reader.Read(p)
+	s.remaining -= uint64(n)
+
+	if err != nil {
+		s.c.broken = true
+	}
+
+	return n, err
+}
+
+// nextValue discards what is left of the previous value and returns a
+// reader for the next one, nil for NULL.
+func (s *rowStream) nextValue() (*valueReader, error) {
+	if s.value != nil {
+		_, err := io.Copy(io.Discard, s.value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.value = nil
+	}
+
+	_, err := io.ReadFull(s, s.buf[:1])
+
+	if err != nil {
+		return nil, streamError(err)
+	}
+
+	var n uint64
+	var size int
+
+	// length encoded string, NULL is 0xfb
+	switch s.buf[0] {
+	case 0xfb:
+		return nil, nil
+	case 0xfc:
+		size = 2
+	case 0xfd:
+		size = 3
+	case 0xfe:
+		size = 8
+	case 0xff:
+		return nil, ErrMalformedPacket
+	default:
+		n = uint64(s.buf[0])
+	}
+
+	if size > 0 {
+		_, err = io.ReadFull(s, s.buf[:size])
+
+		if err != nil {
+			return nil, streamError(err)
+		}
+
+		n = UnpackNumber(s.buf[:size], uint8(size))
+	}
+
+	s.value = &valueReader{s: s, n: n}
+
+	return s.value, nil
+}
+
+// valueReader reads one value of a streamed row.
+type valueReader struct {
+	s *rowStream
+	n uint64 // unread bytes
+}
+
+func (v *valueReader) Read(p []byte) (int, error) {
+	if v.n == 0 {
+		return 0, io.EOF
+	}
+
+	if uint64(len(p)) > v.n {
+		p = p[:v.n]
+	}
+
+	n, err := v.s.Read(p)
+	v.n -= uint64(n)
+
+	return n, streamError(err)
+}
+
+// streamError reports the end of the row in the middle of a value as a
+// malformed packet.
+func streamError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrMalformedPacket
+	}
+
+	return err
+}
@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestRowsNextStream(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	// 0xfe + 8 byte length, split over two packets by the server.
+	blob := bytes.Repeat([]byte("0123456789abcdef"), MAX_PACKET_SIZE/16)
+	bigRow := append(textRowPacket("1"), 0xfe, byte(len(blob)), byte(len(blob)>>8), byte(len(blob)>>16), byte(len(blob)>>24), 0, 0, 0, 0)
+	bigRow = append(bigRow, blob...)
+
+	go func() {
+		server.readPacket()
+		server.writePacket(1, []byte{2})
+		server.writePacket(2, columnPacket("id", MYSQL_TYPE_LONG, 0, 63))
+		server.writePacket(3, columnPacket("data", MYSQL_TYPE_BLOB, BLOB_FLAG|BINARY_FLAG, 63))
+		server.writePacket(4, eofPacket(0))
+		server.writePacket(5, textRowPacket("2", "small"))
+		server.writePacket(6, bigRow[:MAX_PACKET_SIZE-1])
+		server.writePacket(7, bigRow[MAX_PACKET_SIZE-1:])
+		server.writePacket(8, textRowPacket("3", nil))
+		server.writePacket(9, eofPacket(0))
+	}()
+
+	rows, err := c.Query("SELECT id, data FROM t")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids, data []string
+
+	for rows.NextStream(1024) {
+		id, _, err := rows.ColumnReader()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, _ := io.ReadAll(id)
+		ids = append(ids, string(b))
+
+		value, n, err := rows.ColumnReader()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value == nil {
+			data = append(data, "NULL")
+			continue
+		}
+
+		if len(ids) == 2 {
+			if rows.Values() != nil || rows.Scan(new(string), new(string)) != ErrRowStreamed {
+				t.Error("values of a streamed row")
+			}
+
+			// Only read the head of the blob, the rest is discarded.
+			head := make([]byte, 16)
+
+			if _, err := io.ReadFull(value, head); err != nil || n != uint64(len(blob)) {
+				t.Fatalf("read %q %d: %v", head, n, err)
+			}
+
+			data = append(data, string(head))
+			continue
+		}
+
+		b, _ = io.ReadAll(value)
+		data = append(data, string(b))
+	}
+
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 3 || ids[0] != "2" || ids[1] != "1" || ids[2] != "3" {
+		t.Errorf("unexpected ids %q", ids)
+	}
+
+	if len(data) != 3 || data[0] != "small" || data[1] != "0123456789abcdef" || data[2] != "NULL" {
+		t.Errorf("unexpected data %q", data)
+	}
+}
@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Throttle is a token bucket limiting the bytes per second read or written
+// by the connections it is set on, see ReadThrottle and WriteThrottle.
+type Throttle struct {
+	rate  float64 // bytes per second
+	burst float64
+
+	mutex  sync.Mutex
+	tokens float64 // negative when the bytes taken are not paid for yet
+	last   time.Time
+}
+
+// NewThrottle returns a Throttle allowing bytesPerSecond, which must be
+// positive, on average and up to burst bytes at once, bytesPerSecond if
+// burst is zero or less.
+func NewThrottle(bytesPerSecond int64, burst int64) *Throttle {
+	if burst <= 0 {
+		burst = bytesPerSecond
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Throttle{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// maxChunk returns the largest read or write worth waiting for at once.
+func (t *Throttle) maxChunk(n int) int {
+	if float64(n) > t.burst {
+		return int(t.burst)
+	}
+
+	return n
+}
+
+// reserve takes n bytes from the bucket and returns how long to wait until
+// they are paid for.
+func (t *Throttle) reserve(n int) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	t.last = now
+
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+
+	t.tokens -= float64(n)
+
+	if t.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// throttledConn applies the throttles to the reads and writes of the
+// network connection. The waits are at most burst/rate long and do not
+// follow the deadlines of the connection.
+type throttledConn struct {
+	net.Conn
+	read  *Throttle
+	write *Throttle
+}
+
+// Read pays for the bytes after reading them, the size of the response is
+// not known before.
+func (tc *throttledConn) Read(p []byte) (int, error) {
+	if tc.read == nil {
+		return tc.Conn.Read(p)
+	}
+
+	n, err := tc.Conn.Read(p[:tc.read.maxChunk(len(p))])
+
+	if n > 0 {
+		time.Sleep(tc.read.reserve(n))
+	}
+
+	return n, err
+}
+
+// Write pays for every chunk of at most burst bytes before writing it.
+func (tc *throttledConn) Write(p []byte) (int, error) {
+	if tc.write == nil {
+		return tc.Conn.Write(p)
+	}
+
+	written := 0
+
+	for written < len(p) {
+		chunk := p[written : written+tc.write.maxChunk(len(p)-written)]
+
+		time.Sleep(tc.write.reserve(len(chunk)))
+
+		n, err := tc.Conn.Write(chunk)
+		written += n
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestThrottleReserve(t *testing.T) {
+	throttle := NewThrottle(10000, 1000)
+
+	if wait := throttle.reserve(1000); wait != 0 {
+		t.Errorf("the burst must not wait, waited %v", wait)
+	}
+
+	if wait := throttle.reserve(500); wait < 45*time.Millisecond || wait > 50*time.Millisecond {
+		t.Errorf("unexpected wait %v", wait)
+	}
+}
+
+func TestThrottledConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	throttle := NewThrottle(100000, 1000)
+	conn := &throttledConn{Conn: client, write: throttle}
+	data := bytes.Repeat([]byte("0123456789"), 600)
+	received := make(chan []byte)
+
+	go func() {
+		var got []byte
+		buf := make([]byte, 4096)
+
+		for len(got) < len(data) {
+			n, err := server.Read(buf)
+
+			// net.Pipe hands over every write as a whole.
+			if n > 1000 || err != nil {
+				t.Errorf("read %d bytes: %v", n, err)
+				break
+			}
+
+			got = append(got, buf[:n]...)
+		}
+
+		received <- got
+	}()
+
+	start := time.Now()
+
+	if n, err := conn.Write(data); n != len(data) || err != nil {
+		t.Fatalf("wrote %d bytes: %v", n, err)
+	}
+
+	// 5000 bytes past the burst at 100000 bytes per second.
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("not throttled, took %v", elapsed)
+	}
+
+	if got := <-received; bytes.Equal(got, data) == false {
+		t.Errorf("unexpected data of %d bytes", len(got))
+	}
+}
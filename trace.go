@@ -0,0 +1,236 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrEmptyTrace = errors.New("Trace without packets read from the server")
+)
+
+const (
+	TRACE_READ  = "read"  // packet sent by the server
+	TRACE_WRITE = "write" // packet sent by the client
+)
+
+// TraceRecord is one packet of a trace, written to TraceWriter as a line of
+// JSON, e.g.
+//
+//	{"conn":1,"time":"2024-05-01T10:00:00.000001Z","direction":"read","seq":0,"payload":"0a352e372e3939..."}
+//
+// Conn numbers the connections of the process, so the packets of the
+// connections sharing a TraceWriter can be told apart. The payload is the
+// one seen by the parser: after TLS, and before it is joined with the
+// packets following it when it is 16 MiB or more.
+type TraceRecord struct {
+	Conn      uint64    `json:"conn"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Seq       uint8     `json:"seq"`
+	Payload   string    `json:"payload"` // hex encoded
+}
+
+// traceConnections numbers the traced connections.
+var traceConnections uint64
+
+// traceStream splits the bytes going one way into packets and writes a
+// TraceRecord for each of them. Tracing never fails the connection: a
+// TraceWriter error only loses the record.
+type traceStream struct {
+	w         io.Writer
+	conn      uint64
+	direction string
+	buf       []byte // start of the next packet
+}
+
+func (s *traceStream) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+
+	var n int
+
+	for len(s.buf)-n >= 4 {
+		length := int(UnpackNumber(s.buf[n:], 3))
+
+		if len(s.buf)-n < 4+length {
+			break
+		}
+
+		s.record(s.buf[n+3], s.buf[n+4:n+4+length])
+
+		n += 4 + length
+	}
+
+	s.buf = append(s.buf[:0], s.buf[n:]...)
+
+	return len(p), nil
+}
+
+func (s *traceStream) record(seq uint8, payload []byte) {
+	line, err := json.Marshal(TraceRecord{
+		Conn:      s.conn,
+		Time:      time.Now().UTC(),
+		Direction: s.direction,
+		Seq:       seq,
+		Payload:   hex.EncodeToString(payload),
+	})
+
+	if err != nil {
+		return
+	}
+
+	// One write per record, so that the lines of connections sharing the
+	// TraceWriter do not interleave.
+	s.w.Write(append(line, '\n'))
+}
+
+// traceStreams returns the streams tracing what the connection reads and
+// writes to TraceWriter.
+func (c *Connection) traceStreams() (io.Writer, io.Writer) {
+	if c.traceConn == 0 {
+		c.traceConn = atomic.AddUint64(&traceConnections, 1)
+	}
+
+	read := &traceStream{w: c.param.TraceWriter, conn: c.traceConn, direction: TRACE_READ}
+	write := &traceStream{w: c.param.TraceWriter, conn: c.traceConn, direction: TRACE_WRITE}
+
+	return read, write
+}
+
+// ReplayTrace opens a connection to a fake server which sends back the
+// packets the server sent in a trace written to TraceWriter, so that a
+// protocol bug reported with a trace can be reproduced without the server:
+// running the same statements on the returned connection feeds the
+// parser with the same bytes. Only the first connection of the trace is
+// replayed. What the client sends is ignored, and the connection reaches
+// EOF at the end of the trace.
+//
+// TLS is left out, the trace holding the packets in the clear, and so are
+// Dial, ConnectRetry and Breaker. The handshake of a trace recorded over TLS
+// is replayed without the SSL request.
+func ReplayTrace(trace io.Reader, param ConnectionParameter) (*Connection, error) {
+	var packets []byte
+	var conn uint64
+
+	// The sequence numbers of the handshake packets of the server follow
+	// the SSL request (see startTLS), which the replaying client does not
+	// send.
+	handshake := true
+	var shift uint8
+
+	decoder := json.NewDecoder(trace)
+
+	for {
+		var record TraceRecord
+
+		err := decoder.Decode(&record)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if conn == 0 {
+			conn = record.Conn
+		}
+
+		if record.Conn != conn {
+			continue
+		}
+
+		payload, err := hex.DecodeString(record.Payload)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if record.Direction == TRACE_WRITE {
+			if record.Seq == 0 {
+				handshake = false
+			} else if handshake == true && record.Seq == 1 && len(payload) == 4+4+1+23 {
+				shift = 1
+			}
+
+			continue
+		}
+
+		seq := record.Seq
+
+		if handshake == true && seq > 0 {
+			seq -= shift
+		}
+
+		n := len(payload)
+		packets = append(packets, byte(n), byte(n>>8), byte(n>>16), seq)
+		packets = append(packets, payload...)
+	}
+
+	if len(packets) == 0 {
+		return nil, ErrEmptyTrace
+	}
+
+	param.TLSConfig = nil
+	param.StrictSecurity = false
+	param.ConnectRetry = RetryPolicy{}
+	param.Breaker = nil
+	param.Dial = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return &replayConn{reader: bytes.NewReader(packets)}, nil
+	}
+
+	c := NewConnection(param)
+
+	err := c.Open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// replayConn reads the packets of a trace and discards what is written.
+type replayConn struct {
+	reader *bytes.Reader
+}
+
+func (conn *replayConn) Read(p []byte) (int, error) {
+	return conn.reader.Read(p)
+}
+
+func (conn *replayConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (conn *replayConn) Close() error {
+	return nil
+}
+
+func (conn *replayConn) LocalAddr() net.Addr {
+	return nil
+}
+
+func (conn *replayConn) RemoteAddr() net.Addr {
+	return nil
+}
+
+func (conn *replayConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
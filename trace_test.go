@@ -0,0 +1,148 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReplayTrace(t *testing.T) {
+	var trace bytes.Buffer
+
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+
+	c := NewConnection(ConnectionParameter{
+		Network:     "tcp",
+		TraceWriter: &trace,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	go func() {
+		server.handshake()
+		server.readCommand()
+		server.writeResultSet(1, [][]byte{
+			columnPacket("id", MYSQL_TYPE_LONG, 0, 63),
+			columnPacket("name", MYSQL_TYPE_VAR_STRING, 0, 45),
+		}, [][]byte{
+			textRowPacket("1", "Taipei"),
+			textRowPacket("2", nil),
+		}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(c *Connection) []string {
+		rows, err := c.Query("SELECT id, name FROM city")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var values []string
+
+		for rows.Next() {
+			for _, value := range rows.Values() {
+				values = append(values, string(value))
+			}
+		}
+
+		if err = rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return values
+	}
+
+	expected := query(c)
+	c.Close()
+
+	var reads, writes int
+
+	for _, line := range bytes.Split(bytes.TrimSpace(trace.Bytes()), []byte("\n")) {
+		var record TraceRecord
+
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+
+		if record.Conn == 0 || record.Time.IsZero() == true {
+			t.Errorf("unexpected record %s", line)
+		}
+
+		if record.Direction == TRACE_READ {
+			reads++
+		} else {
+			writes++
+		}
+	}
+
+	// The handshake and the session variables query take 2 packets from
+	// the client and 10 from the server, the query 1 and 7.
+	if reads != 17 || writes != 3 {
+		t.Errorf("traced %d reads and %d writes", reads, writes)
+	}
+
+	replay, err := ReplayTrace(bytes.NewReader(trace.Bytes()), ConnectionParameter{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer replay.Close()
+
+	if values := query(replay); strings.Join(values, ",") != strings.Join(expected, ",") {
+		t.Errorf("replayed %q, recorded %q", values, expected)
+	}
+
+	// The same session over TLS: an SSL request and the sequence numbers
+	// of the server shifted by one up to the first command.
+	var tlsTrace bytes.Buffer
+
+	encoder := json.NewEncoder(&tlsTrace)
+	handshake := true
+
+	for i, line := range bytes.Split(bytes.TrimSpace(trace.Bytes()), []byte("\n")) {
+		var record TraceRecord
+		json.Unmarshal(line, &record)
+
+		if record.Direction == TRACE_WRITE && record.Seq == 0 {
+			handshake = false
+		}
+
+		if handshake == true && i > 0 {
+			record.Seq++
+		}
+
+		encoder.Encode(record)
+
+		if i == 0 {
+			encoder.Encode(TraceRecord{Conn: record.Conn, Direction: TRACE_WRITE, Seq: 1, Payload: strings.Repeat("00", 32)})
+		}
+	}
+
+	replay, err = ReplayTrace(&tlsTrace, ConnectionParameter{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer replay.Close()
+
+	if values := query(replay); strings.Join(values, ",") != strings.Join(expected, ",") {
+		t.Errorf("replayed %q over TLS, recorded %q", values, expected)
+	}
+}
+
+func TestReplayEmptyTrace(t *testing.T) {
+	if _, err := ReplayTrace(bytes.NewReader(nil), ConnectionParameter{}); err != ErrEmptyTrace {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -43,5 +43,10 @@ func scramblePassword(scramble []byte, password []byte) []byte {
 	for i := range scramble {
 		scramble[i] ^= stage1[i]
 	}
+
+	// SHA1(password) is enough to authenticate.
+	zeroBytes(stage1)
+	zeroBytes(hash)
+
 	return scramble
 }
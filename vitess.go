@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrVitessVariable = errors.New("Invalid Vitess session variable name")
+
+// Tablet types a vtgate session can target, see UseKeyspace.
+const (
+	VITESS_PRIMARY = "primary"
+	VITESS_REPLICA = "replica"
+	VITESS_RDONLY  = "rdonly"
+)
+
+// Session variables of vtgate, see SetVitessVariable.
+// Reference:
+// https://vitess.io/docs/reference/compatibility/mysql-compatibility/
+const (
+	VITESS_WORKLOAD                 = "workload"         // oltp, olap or dba
+	VITESS_TRANSACTION_MODE         = "transaction_mode" // single, multi or twopc
+	VITESS_DDL_STRATEGY             = "ddl_strategy"     // direct, vitess, online, ...
+	VITESS_MIGRATION_CONTEXT        = "migration_context"
+	VITESS_READ_AFTER_WRITE_GTID    = "read_after_write_gtid"
+	VITESS_READ_AFTER_WRITE_TIMEOUT = "read_after_write_timeout"
+	VITESS_SESSION_TRACK_GTIDS      = "session_track_gtids"
+	VITESS_ENABLE_SYSTEM_SETTINGS   = "enable_system_settings"
+	VITESS_QUERY_TIMEOUT            = "query_timeout" // milliseconds
+)
+
+// IsVitess reports whether the server is a Vitess vtgate, which appends
+// -Vitess to the MySQL version it announces.
+func (c *Connection) IsVitess() bool {
+	return strings.Contains(c.ServerVersion, "Vitess")
+}
+
+// VitessTarget returns the target string of a keyspace for vtgate:
+// keyspace[:shard][@tabletType]. shard and tabletType may be empty.
+func VitessTarget(keyspace string, shard string, tabletType string) string {
+	target := keyspace
+
+	if shard != "" {
+		target += ":" + shard
+	}
+
+	if tabletType != "" {
+		target += "@" + tabletType
+	}
+
+	return target
+}
+
+// UseKeyspace routes the following queries to the tablets of tabletType
+// in keyspace, like USE `keyspace@replica`. An empty tabletType targets
+// the primary.
+func (c *Connection) UseKeyspace(keyspace string, tabletType string) error {
+	return c.UseDatabase(VitessTarget(keyspace, "", tabletType))
+}
+
+// SetVitessVariable sets a session variable of vtgate (e.g.
+// VITESS_WORKLOAD to "olap") with SET @@name = 'value'. vtgate keeps these
+// variables itself, setting them does not reserve a connection.
+func (c *Connection) SetVitessVariable(ctx context.Context, name string, value string) error {
+	if name == "" {
+		return ErrVitessVariable
+	}
+
+	for i := 0; i < len(name); i++ {
+		if isIdentifierByte(name[i]) == false {
+			return ErrVitessVariable
+		}
+	}
+
+	rows, err := c.QueryContext(ctx, "SET @@"+name+" = "+c.QuoteString(value))
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
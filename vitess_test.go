@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"testing"
+)
+
+func TestVitessTarget(t *testing.T) {
+	tests := []struct {
+		keyspace   string
+		shard      string
+		tabletType string
+		expected   string
+	}{
+		{"commerce", "", "", "commerce"},
+		{"commerce", "", VITESS_REPLICA, "commerce@replica"},
+		{"customer", "-80", VITESS_RDONLY, "customer:-80@rdonly"},
+	}
+
+	for _, test := range tests {
+		if target := VitessTarget(test.keyspace, test.shard, test.tabletType); target != test.expected {
+			t.Errorf("got %q, want %q", target, test.expected)
+		}
+	}
+}
+
+func TestOpenVitess(t *testing.T) {
+	client, serverConn := net.Pipe()
+	server := newFakeServer(t, serverConn)
+
+	c := NewConnection(ConnectionParameter{
+		Network: "tcp",
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return client, nil
+		},
+	})
+
+	go func() {
+		payload := initPacket()
+		payload = append(append(payload[:1:1], "8.0.30-Vitess"...), payload[len("\x0a5.7.99-fake"):]...)
+		server.writePacket(0, payload)
+
+		server.readPacket()
+		server.writePacket(2, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		// No keyspace to route the session variables query to.
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x51, 0x04, '#', 'H', 'Y', '0', '0', '0'}, "VT09005: no database selected"...))
+
+		if command := server.readCommand(); string(command[1:]) != "commerce@replica" {
+			t.Errorf("unexpected command %q", command)
+		}
+
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		if command := server.readCommand(); string(command[1:]) != "SET @@workload = 'olap'" {
+			t.Errorf("unexpected command %q", command)
+		}
+
+		server.writePacket(1, okPacket(0, 0, SERVER_STATUS_AUTOCOMMIT, 0, ""))
+
+		// A vtgate without COM_RESET_CONNECTION, which still answers the
+		// session variables query sent with it.
+		server.readCommand()
+		server.readCommand()
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x17, 0x04, '#', '0', '8', 'S', '0', '1'}, "Unknown command"...))
+		server.writePacket(1, append([]byte{ERR_PACKET, 0x51, 0x04, '#', 'H', 'Y', '0', '0', '0'}, "VT09005: no database selected"...))
+	}()
+
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.IsVitess() == false || c.WaitTimeout() != 0 {
+		t.Errorf("unexpected session %q %v", c.ServerVersion, c.WaitTimeout())
+	}
+
+	if err := c.UseKeyspace("commerce", VITESS_REPLICA); err != nil || c.CurrentDatabase() != "commerce@replica" {
+		t.Fatalf("use keyspace %q: %v", c.CurrentDatabase(), err)
+	}
+
+	if err := c.SetVitessVariable(context.Background(), VITESS_WORKLOAD, "olap"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetVitessVariable(context.Background(), "workload = 'dba', @@x", "olap"); err != ErrVitessVariable {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	dc := &driverConn{c: c}
+
+	if err := dc.ResetSession(context.Background()); err != driver.ErrBadConn || dc.IsValid() == true {
+		t.Errorf("a reserved connection must not be reused: %v", err)
+	}
+}
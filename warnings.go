@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"context"
+	"strconv"
+)
+
+// Warning is a note, warning or error left by the last statement, as
+// listed by SHOW WARNINGS.
+type Warning struct {
+	Level   string // Note, Warning or Error
+	Code    uint16
+	Message string
+}
+
+// Warnings returns the warnings of the last statement, e.g. truncated
+// values or deprecated syntax. Result.Warnings tells whether there are
+// any. ctx is checked before the statement is sent.
+func (c *Connection) Warnings(ctx context.Context) ([]Warning, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.Query("SHOW WARNINGS")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []Warning
+
+	for rows.Next() == true {
+		values := rows.Values()
+
+		if len(values) < 3 {
+			rows.Close()
+			return nil, ErrMalformedPacket
+		}
+
+		code, _ := strconv.ParseUint(string(values[1]), 10, 16)
+
+		warnings = append(warnings, Warning{
+			Level:   string(values[0]),
+			Code:    uint16(code),
+			Message: string(values[2]),
+		})
+	}
+
+	err = rows.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	c, serverConn := newPipeConnection(ConnectionParameter{})
+	server := newFakeServer(t, serverConn)
+
+	go func() {
+		if payload := server.readCommand(); string(payload[1:]) != "SHOW WARNINGS" {
+			t.Errorf("unexpected query %q", payload)
+		}
+
+		server.writeResultSet(1, [][]byte{
+			columnPacket("Level", MYSQL_TYPE_VAR_STRING, 0, 33),
+			columnPacket("Code", MYSQL_TYPE_LONG, 0, 63),
+			columnPacket("Message", MYSQL_TYPE_VAR_STRING, 0, 33),
+		}, [][]byte{
+			textRowPacket("Warning", "1265", "Data truncated for column 'name' at row 1"),
+			textRowPacket("Note", "1287", "'@@tx_isolation' is deprecated"),
+		}, SERVER_STATUS_AUTOCOMMIT)
+	}()
+
+	warnings, err := c.Warnings(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(warnings) != 2 || warnings[0] != (Warning{"Warning", 1265, "Data truncated for column 'name' at row 1"}) ||
+		warnings[1].Level != "Note" || warnings[1].Code != 1287 {
+		t.Errorf("unexpected warnings %+v", warnings)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.Warnings(ctx); err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+}